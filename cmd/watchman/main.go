@@ -10,10 +10,9 @@ import (
 	"github.com/adrianpk/watchman/internal/cli"
 	"github.com/adrianpk/watchman/internal/config"
 	"github.com/adrianpk/watchman/internal/hook"
+	"github.com/adrianpk/watchman/internal/server"
 )
 
-const logFile = "/tmp/watchman.log"
-
 func main() {
 	// Handle CLI commands
 	if len(os.Args) > 1 {
@@ -35,44 +34,46 @@ func runCommand(cmd string) error {
 		local := len(os.Args) > 2 && os.Args[2] == "--local"
 		return cli.RunInit(local)
 	case "setup":
-		return cli.RunSetup()
+		return cli.RunSetup(os.Args[2:])
+	case "uninstall":
+		return cli.RunUninstall(os.Args[2:])
+	case "check":
+		return cli.RunCheck(os.Args[2:])
+	case "test":
+		return cli.RunTest(os.Args[2:])
+	case "replay":
+		return cli.RunReplay(os.Args[2:])
+	case "validate":
+		return cli.RunValidate(os.Args[2:])
+	case "config":
+		return cli.RunConfig(os.Args[2:])
+	case "log":
+		return cli.RunLog(os.Args[2:])
+	case "audit-links":
+		return cli.RunAuditLinks(os.Args[2:])
+	case "serve":
+		return cli.RunServe(os.Args[2:])
 	default:
 		return fmt.Errorf("unknown command: %s", cmd)
 	}
 }
 
 func runHook() error {
-	cfg, err := config.Load()
-	if err != nil {
-		reason := "watchman config error: " + err.Error()
-		logDeny(hookInput{}, reason)
-		deny(reason)
-		return nil
-	}
-
-	evaluator := hook.NewEvaluator(cfg)
-
 	rawInput, _ := io.ReadAll(os.Stdin)
 
-	var input hookInput
-	if err := json.Unmarshal(rawInput, &input); err != nil {
-		reason := "watchman input error: " + err.Error()
-		logDeny(hookInput{}, reason)
-		deny(reason)
+	input, result := evaluate(rawInput)
+
+	event := input.Event()
+	if event != config.EventPreToolUse {
+		respond(event, result)
 		return nil
 	}
 
-	evalInput := hook.Input{
-		HookType:  input.HookType,
-		ToolName:  input.ToolName,
-		ToolInput: input.ToolInput,
-		CWD:       input.CWD,
+	if result.Ask {
+		ask(result.Reason, result.Warning)
+		return nil
 	}
-
-	result := evaluator.Evaluate(evalInput)
-
 	if !result.Allowed {
-		logDeny(input, result.Reason)
 		deny(result.Reason)
 		return nil
 	}
@@ -81,65 +82,41 @@ func runHook() error {
 	return nil
 }
 
-func logDeny(input hookInput, reason string) {
-	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return
+// evaluate asks a running daemon, started in the payload's working
+// directory or in the process one, and otherwise loads the policy and
+// evaluates in-process. Daemons load the policy of the payload's working
+// directory either way. WATCHMAN_MODE is read by the process that
+// evaluates, so calls made with it set are always evaluated in-process.
+func evaluate(payload []byte) (hook.Input, hook.Result) {
+	wd, _ := os.Getwd()
+	dir := server.PayloadDir(payload)
+	if dir == "" {
+		dir = wd
 	}
-	defer f.Close()
-
-	ts := time.Now().Format("2006-01-02 15:04:05")
-
-	fmt.Fprintf(f, "[%s] DENY\n", ts)
-	fmt.Fprintf(f, "  tool:   %s\n", input.ToolName)
-	fmt.Fprintf(f, "  cwd:    %s\n", input.CWD)
-	fmt.Fprintf(f, "  reason: %s\n", reason)
-
-	switch input.ToolName {
-	case "Bash":
-		if cmd, ok := input.ToolInput["command"].(string); ok {
-			if len(cmd) > 200 {
-				cmd = cmd[:200] + "..."
-			}
-			fmt.Fprintf(f, "  cmd:    %s\n", cmd)
-		}
-	case "Read", "Write", "Edit":
-		if fp, ok := input.ToolInput["file_path"].(string); ok {
-			fmt.Fprintf(f, "  path:   %s\n", fp)
-		}
-	case "Glob":
-		if p, ok := input.ToolInput["pattern"].(string); ok {
-			fmt.Fprintf(f, "  pattern: %s\n", p)
-		}
-		if p, ok := input.ToolInput["path"].(string); ok {
-			fmt.Fprintf(f, "  path:   %s\n", p)
-		}
-	case "Grep":
-		if p, ok := input.ToolInput["pattern"].(string); ok {
-			fmt.Fprintf(f, "  pattern: %s\n", p)
-		}
-		if p, ok := input.ToolInput["path"].(string); ok {
-			fmt.Fprintf(f, "  path:   %s\n", p)
+	if os.Getenv("WATCHMAN_MODE") != "" {
+		return server.LoadPolicy(dir).Decide(payload)
+	}
+	sockets := []string{server.SocketPath(dir)}
+	if wd != dir {
+		sockets = append(sockets, server.SocketPath(wd))
+	}
+	for _, socket := range sockets {
+		if input, result, err := server.Query(socket, payload); err == nil {
+			return input, result
 		}
 	}
-
-	fmt.Fprintln(f, "")
-}
-
-type hookInput struct {
-	HookType  string                 `json:"hook_type"`
-	ToolName  string                 `json:"tool_name"`
-	ToolInput map[string]interface{} `json:"tool_input"`
-	CWD       string                 `json:"cwd"`
+	return server.LoadPolicy(dir).Decide(payload)
 }
 
 type hookOutput struct {
+	Decision           string              `json:"decision,omitempty"`
+	Reason             string              `json:"reason,omitempty"`
 	HookSpecificOutput *hookSpecificOutput `json:"hookSpecificOutput,omitempty"`
 }
 
 type hookSpecificOutput struct {
 	HookEventName      string `json:"hookEventName"`
-	PermissionDecision string `json:"permissionDecision"`
+	PermissionDecision string `json:"permissionDecision,omitempty"`
 	AdditionalContext  string `json:"additionalContext,omitempty"`
 	Reason             string `json:"reason,omitempty"`
 }
@@ -171,6 +148,46 @@ func deny(reason string) {
 	os.Exit(2)
 }
 
+// ask leaves the decision to the user: Claude Code prompts for permission
+// with the reason instead of blocking the agent.
+func ask(reason, additionalContext string) {
+	out := hookOutput{
+		HookSpecificOutput: &hookSpecificOutput{
+			HookEventName:      "PreToolUse",
+			PermissionDecision: "ask",
+			Reason:             reason,
+			AdditionalContext:  additionalContext,
+		},
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+	os.Exit(0)
+}
+
+// respond answers events other than PreToolUse. These cannot deny a tool call:
+// a block on PostToolUse feeds the reason back to the agent, on UserPromptSubmit
+// it rejects the prompt and on Stop it keeps the agent working.
+// There is no one to ask on these events, so an ask blocks like a deny.
+// SessionStart cannot block and only adds context.
+func respond(event string, result hook.Result) {
+	out := hookOutput{}
+	if !result.Allowed && event != config.EventSessionStart {
+		out.Decision = "block"
+		out.Reason = result.Reason
+	}
+	if result.Warning != "" && event != config.EventStop {
+		out.HookSpecificOutput = &hookSpecificOutput{
+			HookEventName:     event,
+			AdditionalContext: result.Warning,
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+	if out.Decision == "block" {
+		ts := time.Now().Format("15:04:05")
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", ts, result.Reason)
+	}
+	os.Exit(0)
+}
+
 func fatal(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format+"\n", args...)
 	os.Exit(1)
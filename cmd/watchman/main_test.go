@@ -6,7 +6,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 var binaryPath string
@@ -36,6 +38,7 @@ func runWatchman(t *testing.T, input string) (stdout, stderr string, exitCode in
 
 	cmd := exec.Command(binaryPath)
 	cmd.Dir = tmpDir
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"))
 	cmd.Stdin = bytes.NewBufferString(input)
 
 	var outBuf, errBuf bytes.Buffer
@@ -271,3 +274,255 @@ func TestWatchmanInvalidJSON(t *testing.T) {
 		t.Error("expected error message for invalid JSON")
 	}
 }
+
+func TestWatchmanDeniesOnInvalidConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := "invariants:\n  content:\n    - name: broken\n      forbid: \"(\"\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".watchman.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binaryPath)
+	cmd.Dir = tmpDir
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"))
+	cmd.Stdin = bytes.NewBufferString(makeInput("ls"))
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+
+	err := cmd.Run()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("expected exit 2, got %v", err)
+	}
+
+	if !strings.Contains(errBuf.String(), ".watchman.yml:4") || !strings.Contains(errBuf.String(), "invalid regex") {
+		t.Errorf("expected file:line diagnostic, got: %s", errBuf.String())
+	}
+}
+
+func TestWatchmanWritesAuditLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+	config := "rules:\n  workspace: true\naudit:\n  path: " + logPath + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".watchman.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range []string{"ls", "cat /etc/passwd"} {
+		cmd := exec.Command(binaryPath)
+		cmd.Dir = tmpDir
+		input := `{"hook_type":"PreToolUse","session_id":"s-1","tool_name":"Bash","tool_input":{"command":"` + command + `"},"cwd":"` + tmpDir + `"}`
+		cmd.Stdin = bytes.NewBufferString(input)
+		cmd.Run()
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("audit log not written: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %d:\n%s", len(lines), data)
+	}
+
+	var allowRec, denyRec map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &allowRec)
+	json.Unmarshal([]byte(lines[1]), &denyRec)
+
+	if allowRec["decision"] != "allow" || allowRec["session_id"] != "s-1" {
+		t.Errorf("unexpected allow record: %v", allowRec)
+	}
+	if denyRec["decision"] != "deny" || denyRec["rule"] != "workspace" || denyRec["config_hash"] == nil {
+		t.Errorf("unexpected deny record: %v", denyRec)
+	}
+}
+
+func TestWatchmanAsksOnViolation(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.jsonl")
+	config := "rules:\n  workspace: true\nworkspace:\n  on_violation: ask\naudit:\n  path: " + logPath + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".watchman.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binaryPath)
+	cmd.Dir = tmpDir
+	cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"))
+	cmd.Stdin = bytes.NewBufferString(`{"hook_type":"PreToolUse","tool_name":"Bash","tool_input":{"command":"cat /etc/passwd"},"cwd":"` + tmpDir + `"}`)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected exit 0 when asking, got %v", err)
+	}
+
+	var output hookOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+	if output.HookSpecificOutput == nil || output.HookSpecificOutput.PermissionDecision != "ask" {
+		t.Fatalf("expected permissionDecision ask, got %s", out)
+	}
+	if !strings.Contains(output.HookSpecificOutput.Reason, "/etc/passwd") {
+		t.Errorf("reason should name the path: %q", output.HookSpecificOutput.Reason)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("audit log not written: %v", err)
+	}
+	var rec map[string]interface{}
+	json.Unmarshal(data, &rec)
+	if rec["decision"] != "ask" || rec["rule"] != "workspace" {
+		t.Errorf("unexpected audit record: %v", rec)
+	}
+}
+
+func TestWatchmanAuditsViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		env    string
+	}{
+		{name: "rule in audit mode", config: "workspace:\n  mode: audit\n"},
+		{name: "WATCHMAN_MODE", env: "WATCHMAN_MODE=audit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			logPath := filepath.Join(tmpDir, "audit.jsonl")
+			config := "rules:\n  workspace: true\n" + tt.config + "audit:\n  path: " + logPath + "\n"
+			if err := os.WriteFile(filepath.Join(tmpDir, ".watchman.yml"), []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(binaryPath)
+			cmd.Dir = tmpDir
+			cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"), tt.env)
+			cmd.Stdin = bytes.NewBufferString(`{"hook_type":"PreToolUse","tool_name":"Bash","tool_input":{"command":"cat /etc/passwd"},"cwd":"` + tmpDir + `"}`)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("expected exit 0 when auditing, got %v", err)
+			}
+
+			var output hookOutput
+			if err := json.Unmarshal(out, &output); err != nil {
+				t.Fatalf("invalid output %q: %v", out, err)
+			}
+			if output.HookSpecificOutput == nil || output.HookSpecificOutput.PermissionDecision != "allow" {
+				t.Fatalf("expected permissionDecision allow, got %s", out)
+			}
+
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("audit log not written: %v", err)
+			}
+			var rec map[string]interface{}
+			json.Unmarshal(data, &rec)
+			audited, _ := rec["audited"].([]interface{})
+			if rec["decision"] != "audit" || rec["rule"] != "workspace" || len(audited) != 1 {
+				t.Fatalf("unexpected audit record: %v", rec)
+			}
+			if reason, _ := audited[0].(string); !strings.HasPrefix(reason, "workspace: ") || !strings.Contains(reason, "/etc/passwd") {
+				t.Errorf("audited violation should name the rule and path: %q", reason)
+			}
+		})
+	}
+}
+
+func TestWatchmanRespondsToOtherEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := "rules:\n  workspace: true\n  invariants: true\ninvariants:\n  events: [PostToolUse]\n  content:\n    - name: no-todo\n      forbid: TODO\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".watchman.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("// TODO"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(input string) (hookOutput, int) {
+		cmd := exec.Command(binaryPath)
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"))
+		cmd.Stdin = bytes.NewBufferString(input)
+		out, err := cmd.Output()
+		exitCode := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		var output hookOutput
+		if err := json.Unmarshal(out, &output); err != nil {
+			t.Fatalf("cannot parse output %q: %v", out, err)
+		}
+		return output, exitCode
+	}
+
+	output, exitCode := run(`{"hook_event_name":"PostToolUse","tool_name":"Edit","tool_input":{"file_path":"main.go"},"cwd":"` + tmpDir + `"}`)
+	if exitCode != 0 || output.Decision != "block" || !strings.Contains(output.Reason, "no-todo") {
+		t.Errorf("expected PostToolUse block with exit 0, got %+v (exit %d)", output, exitCode)
+	}
+
+	output, exitCode = run(`{"hook_event_name":"SessionStart","cwd":"` + tmpDir + `"}`)
+	if exitCode != 0 || output.Decision != "" || output.HookSpecificOutput == nil {
+		t.Fatalf("expected SessionStart context, got %+v (exit %d)", output, exitCode)
+	}
+	if output.HookSpecificOutput.HookEventName != "SessionStart" || !strings.Contains(output.HookSpecificOutput.AdditionalContext, "workspace") {
+		t.Errorf("unexpected SessionStart output: %+v", output.HookSpecificOutput)
+	}
+}
+
+func TestWatchmanUsesDaemon(t *testing.T) {
+	tmpDir := t.TempDir()
+	env := append(os.Environ(),
+		"HOME="+tmpDir,
+		"XDG_STATE_HOME="+filepath.Join(tmpDir, ".state"),
+		"XDG_RUNTIME_DIR="+filepath.Join(tmpDir, ".run"),
+	)
+
+	serve := exec.Command(binaryPath, "serve")
+	serve.Dir = tmpDir
+	serve.Env = env
+	var serveOut bytes.Buffer
+	serve.Stdout = &serveOut
+	if err := serve.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		serve.Process.Signal(os.Interrupt)
+		serve.Wait()
+	}()
+
+	var socket string
+	for i := 0; i < 100 && socket == ""; i++ {
+		matches, _ := filepath.Glob(filepath.Join(tmpDir, ".run", "watchman", "*.sock"))
+		if len(matches) > 0 {
+			socket = matches[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if socket == "" {
+		t.Fatalf("daemon did not create a socket: %s", serveOut.String())
+	}
+
+	cmd := exec.Command(binaryPath)
+	cmd.Dir = tmpDir
+	cmd.Env = env
+	cmd.Stdin = bytes.NewBufferString(makeInput("cat /etc/passwd"))
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("expected exit 2 from daemon decision, got %v", err)
+	}
+	if !strings.Contains(errBuf.String(), "workspace") {
+		t.Errorf("expected workspace reason, got: %s", errBuf.String())
+	}
+
+	serve.Process.Signal(os.Interrupt)
+	serve.Wait()
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Error("daemon did not remove its socket on shutdown")
+	}
+}
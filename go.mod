@@ -1,5 +1,8 @@
 module github.com/adrianpk/watchman
 
-go 1.23
+go 1.23.0
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	mvdan.cc/sh/v3 v3.12.0
+)
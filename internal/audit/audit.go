@@ -0,0 +1,227 @@
+// Package audit writes and reads the structured decision log.
+// Each decision is stored as one JSON object per line.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+const (
+	defaultMaxSizeMB = 10
+	defaultMaxFiles  = 5
+
+	// maxInputValue bounds string values copied from the tool input,
+	// so that file contents written by the agent do not bloat the log.
+	maxInputValue = 512
+)
+
+// Record is a single logged decision.
+type Record struct {
+	Time       time.Time              `json:"time"`
+	SessionID  string                 `json:"session_id,omitempty"`
+	Event      string                 `json:"event,omitempty"`
+	Tool       string                 `json:"tool"`
+	CWD        string                 `json:"cwd,omitempty"`
+	Input      map[string]interface{} `json:"input,omitempty"`
+	Paths      []string               `json:"paths,omitempty"`
+	Decision   string                 `json:"decision"` // allow, deny, ask, audit or advise
+	Rule       string                 `json:"rule,omitempty"`
+	Reason     string                 `json:"reason,omitempty"`
+	Warning    string                 `json:"warning,omitempty"`
+	Audited    []string               `json:"audited,omitempty"` // violations allowed in audit mode, as rule: reason
+	LatencyMS  float64                `json:"latency_ms"`
+	ConfigHash string                 `json:"config_hash,omitempty"`
+}
+
+// Logger appends records to a size-rotated JSONL file.
+type Logger struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	maxAge   time.Duration
+	disabled bool
+}
+
+// NewLogger creates a logger from config, filling in defaults.
+func NewLogger(cfg *config.AuditConfig) *Logger {
+	l := &Logger{
+		path:     DefaultPath(),
+		maxSize:  defaultMaxSizeMB << 20,
+		maxFiles: defaultMaxFiles,
+	}
+	if cfg == nil {
+		return l
+	}
+	if cfg.Path != "" {
+		l.path = expandHome(cfg.Path)
+	}
+	if cfg.MaxSizeMB > 0 {
+		l.maxSize = int64(cfg.MaxSizeMB) << 20
+	}
+	if cfg.MaxFiles > 0 {
+		l.maxFiles = cfg.MaxFiles
+	}
+	if cfg.MaxAgeDays > 0 {
+		l.maxAge = time.Duration(cfg.MaxAgeDays) * 24 * time.Hour
+	}
+	l.disabled = cfg.Disabled
+	return l
+}
+
+// Path returns the location of the active log file.
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// RotationPaths returns the active log file and every file it rotates to,
+// whether they exist or not.
+func (l *Logger) RotationPaths() []string {
+	files := make([]string, 0, l.maxFiles+1)
+	for i := 0; i <= l.maxFiles; i++ {
+		files = append(files, l.rotatedPath(i))
+	}
+	return files
+}
+
+// DefaultPath returns $XDG_STATE_HOME/watchman/audit.jsonl,
+// falling back to ~/.local/state/watchman/audit.jsonl.
+func DefaultPath() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "watchman", "audit.jsonl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "watchman-audit.jsonl")
+	}
+	return filepath.Join(home, ".local", "state", "watchman", "audit.jsonl")
+}
+
+// Write appends a record, rotating the file first if it would grow past the size limit.
+func (l *Logger) Write(rec Record) error {
+	if l.disabled {
+		return nil
+	}
+
+	rec.Input = truncateInput(rec.Input)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(line)
+	return err
+}
+
+// rotate shifts audit.jsonl -> audit.jsonl.1 -> audit.jsonl.2 ...,
+// dropping files beyond the retention count or age.
+func (l *Logger) rotate() error {
+	os.Remove(l.rotatedPath(l.maxFiles))
+	for i := l.maxFiles - 1; i >= 0; i-- {
+		src := l.rotatedPath(i)
+		if _, err := os.Stat(src); err == nil {
+			if err := os.Rename(src, l.rotatedPath(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if l.maxAge > 0 {
+		cutoff := time.Now().Add(-l.maxAge)
+		for _, p := range l.rotatedFiles() {
+			if info, err := os.Stat(p); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(p)
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Logger) rotatedPath(n int) string {
+	if n == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(n)
+}
+
+// rotatedFiles returns existing rotated files, oldest first.
+func (l *Logger) rotatedFiles() []string {
+	matches, _ := filepath.Glob(l.path + ".*")
+
+	type numbered struct {
+		path string
+		n    int
+	}
+	var files []numbered
+	for _, m := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(m, l.path+"."))
+		if err != nil {
+			continue
+		}
+		files = append(files, numbered{m, n})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].n > files[j].n })
+
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		out = append(out, f.path)
+	}
+	return out
+}
+
+// Files returns the rotated files followed by the active file, oldest first.
+func (l *Logger) Files() []string {
+	files := l.rotatedFiles()
+	if _, err := os.Stat(l.path); err == nil {
+		files = append(files, l.path)
+	}
+	return files
+}
+
+func truncateInput(input map[string]interface{}) map[string]interface{} {
+	if len(input) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if s, ok := v.(string); ok && len(s) > maxInputValue {
+			v = s[:maxInputValue] + fmt.Sprintf("... (%d bytes)", len(s))
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func expandHome(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	return p
+}
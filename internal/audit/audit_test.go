@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestNewLoggerDefaults(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+
+	l := NewLogger(nil)
+	if l.Path() != "/state/watchman/audit.jsonl" {
+		t.Errorf("Path() = %q", l.Path())
+	}
+	if l.maxSize != 10<<20 || l.maxFiles != 5 {
+		t.Errorf("unexpected defaults: %+v", l)
+	}
+}
+
+func TestLoggerWriteAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLogger(&config.AuditConfig{Path: path})
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: base, Tool: "Bash", Decision: "allow"},
+		{Time: base.Add(time.Hour), Tool: "Read", Decision: "deny", Rule: "workspace"},
+		{Time: base.Add(2 * time.Hour), Tool: "Write", Decision: "deny", Rule: "hook sentinel"},
+		{Time: base.Add(3 * time.Hour), Tool: "Write", Decision: "advise", Rule: "incremental"},
+	}
+	for _, rec := range records {
+		if err := l.Write(rec); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 4},
+		{"by tool", Filter{Tool: "write"}, 2},
+		{"by decision", Filter{Decision: "deny"}, 2},
+		{"by rule prefix", Filter{Rule: "hook"}, 1},
+		{"since", Filter{Since: base.Add(90 * time.Minute)}, 2},
+		{"until", Filter{Until: base.Add(time.Hour)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d records, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerTruncatesInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLogger(&config.AuditConfig{Path: path})
+
+	content := strings.Repeat("x", 10000)
+	if err := l.Write(Record{Tool: "Write", Input: map[string]interface{}{"content": content}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if len(data) > 2000 {
+		t.Errorf("record not truncated: %d bytes", len(data))
+	}
+	if !strings.Contains(string(data), "(10000 bytes)") {
+		t.Errorf("expected truncation marker: %s", data)
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLogger(&config.AuditConfig{Path: path, MaxFiles: 2})
+	l.maxSize = 200 // a couple of records per file
+
+	for i := 0; i < 20; i++ {
+		if err := l.Write(Record{Tool: "Bash", Decision: "allow", Reason: strings.Repeat("r", 50)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := l.Files()
+	if len(files) != 3 {
+		t.Fatalf("expected active file plus 2 rotated, got %v", files)
+	}
+	if files[0] != path+".2" || files[1] != path+".1" || files[2] != path {
+		t.Errorf("unexpected order: %v", files)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("rotated files beyond max_files should be removed")
+	}
+
+	for _, f := range files {
+		info, _ := os.Stat(f)
+		if info.Size() > 200 {
+			t.Errorf("%s exceeds max size: %d", f, info.Size())
+		}
+	}
+}
+
+func TestLoggerRotationMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLogger(&config.AuditConfig{Path: path, MaxAgeDays: 1})
+	l.maxSize = 200
+
+	os.WriteFile(path+".1", []byte("{}\n"), 0600)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(path+".1", old, old)
+
+	for i := 0; i < 5; i++ {
+		l.Write(Record{Tool: "Bash", Reason: strings.Repeat("r", 80)})
+	}
+
+	for _, f := range l.Files() {
+		info, _ := os.Stat(f)
+		if info.ModTime().Before(time.Now().Add(-24 * time.Hour)) {
+			t.Errorf("expired file kept: %s", f)
+		}
+	}
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLogger(&config.AuditConfig{Path: path, Disabled: true})
+
+	if err := l.Write(Record{Tool: "Bash"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("disabled logger should not create the file")
+	}
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// Filter selects records from the log. Zero fields match everything.
+type Filter struct {
+	Tool     string
+	Decision string
+	Rule     string // Prefix match, so "hook" selects every external hook
+	Since    time.Time
+	Until    time.Time
+}
+
+// Match reports whether a record satisfies the filter.
+func (f Filter) Match(rec Record) bool {
+	if f.Tool != "" && !strings.EqualFold(f.Tool, rec.Tool) {
+		return false
+	}
+	if f.Decision != "" && f.Decision != rec.Decision {
+		return false
+	}
+	if f.Rule != "" && !strings.HasPrefix(rec.Rule, f.Rule) {
+		return false
+	}
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && rec.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Query reads every log file, oldest first, and returns the matching records.
+// Lines that cannot be decoded are skipped.
+func (l *Logger) Query(f Filter) ([]Record, error) {
+	var out []Record
+	for _, path := range l.Files() {
+		records, err := readFile(path, f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, records...)
+	}
+	return out, nil
+}
+
+func readFile(path string, f Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if f.Match(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out, scanner.Err()
+}
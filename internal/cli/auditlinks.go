@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/policy"
+)
+
+// RunAuditLinks lists the symbolic links in the workspace that lead outside
+// it or to a protected path. Reads and writes through such links escape the
+// workspace, so the command fails when it finds any.
+func RunAuditLinks(args []string) error {
+	return runAuditLinks(args, os.Stdout)
+}
+
+func runAuditLinks(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("audit-links", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman audit-links [dir]")
+		fmt.Fprintln(stdout, "Checks the links under dir, the current directory by default.")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	root := flags.Arg(0)
+	if root == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("cannot get working directory: %w", err)
+		}
+		root = dir
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %w", root, err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+	rule := policy.NewConfineToWorkspace(&cfg.Workspace)
+	rule.Roots = policy.ResolveRoots(cfg.Workspace.Root, root)
+	rule.Protected = policy.NewProtected(&cfg.Protected)
+
+	escaping := 0
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped, not fatal.
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" && path != root {
+			return filepath.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		if decision := rule.CheckLink(rel, target, root); !decision.Allowed {
+			fmt.Fprintln(stdout, decision.Reason)
+			escaping++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot walk %s: %w", root, err)
+	}
+
+	if escaping == 0 {
+		fmt.Fprintf(stdout, "%s: no links escape the workspace\n", root)
+		return nil
+	}
+	fmt.Fprintf(stdout, "\n%d links escape the workspace\n", escaping)
+	return fmt.Errorf("%d links escape the workspace", escaping)
+}
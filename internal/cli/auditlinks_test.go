@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAuditLinks(t *testing.T) {
+	dir := isolate(t)
+	outside := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "src", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"root":         "/",
+		"src/up":       "../..",
+		"src/alias":    "../README.md",
+		"shared":       outside,
+		"src/.git/out": "/etc",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	err := runAuditLinks(nil, &out)
+	if err == nil {
+		t.Fatal("expected error for escaping links")
+	}
+
+	got := out.String()
+	for _, want := range []string{"link root points to /", "link src/up points to " + filepath.Dir(dir), "link shared points to " + outside, "3 links escape"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"src/alias", ".git"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output should not mention %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestRunAuditLinksClean(t *testing.T) {
+	dir := isolate(t)
+	if err := os.Symlink("README.md", filepath.Join(dir, "readme")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runAuditLinks([]string{dir}, &out); err != nil {
+		t.Fatalf("runAuditLinks() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "no links escape") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
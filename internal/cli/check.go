@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+// RunCheck evaluates a single tool call against the current configuration
+// and prints the verdict of every rule that ran. Nothing is blocked or persisted.
+func RunCheck(args []string) error {
+	return runCheck(args, os.Stdin, os.Stdout)
+}
+
+func runCheck(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	tool := fs.String("tool", "", "tool name (Bash, Read, Write, Edit, Glob, Grep, ...)")
+	command := fs.String("command", "", "command for the Bash tool")
+	file := fs.String("file", "", "file_path for Read, Write and Edit")
+	content := fs.String("content", "", "content for Write")
+	pattern := fs.String("pattern", "", "pattern for Glob and Grep")
+	path := fs.String("path", "", "path for Glob and Grep")
+	cwd := fs.String("cwd", "", "working directory reported by the agent (default: current directory)")
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman check [flags] [payload.json]")
+		fmt.Fprintln(stdout, "Reads a hook payload from the file or stdin unless --tool is given.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var input hook.Input
+	if *tool != "" {
+		input = hook.Input{
+			HookType:  "PreToolUse",
+			ToolName:  *tool,
+			ToolInput: toolInputFromFlags(*command, *file, *content, *pattern, *path),
+		}
+	} else {
+		data, err := readPayload(fs.Arg(0), stdin)
+		if err != nil {
+			return err
+		}
+		input, err = hook.ParseInput(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse payload: %w", err)
+		}
+	}
+
+	if *cwd != "" {
+		input.CWD = *cwd
+	}
+	if input.CWD == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("cannot get working directory: %w", err)
+		}
+		input.CWD = dir
+	}
+
+	dir, err := filepath.Abs(input.CWD)
+	if err != nil {
+		return fmt.Errorf("cannot resolve working directory: %w", err)
+	}
+	cfg, err := config.LoadDir(dir)
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+
+	result := hook.NewDryRunEvaluator(cfg).Evaluate(input)
+	printTrace(stdout, input, result)
+	return nil
+}
+
+func toolInputFromFlags(command, file, content, pattern, path string) map[string]interface{} {
+	toolInput := make(map[string]interface{})
+	set := func(key, value string) {
+		if value != "" {
+			toolInput[key] = value
+		}
+	}
+	set("command", command)
+	set("file_path", file)
+	set("content", content)
+	set("pattern", pattern)
+	set("path", path)
+	return toolInput
+}
+
+func readPayload(path string, stdin io.Reader) ([]byte, error) {
+	if path == "" || path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read payload: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read payload: %w", err)
+	}
+	return data, nil
+}
+
+func printTrace(w io.Writer, input hook.Input, result hook.Result) {
+	toolInput, _ := json.Marshal(input.ToolInput)
+	fmt.Fprintf(w, "tool:     %s\n", input.ToolName)
+	fmt.Fprintf(w, "input:    %s\n", toolInput)
+	fmt.Fprintf(w, "cwd:      %s\n\n", input.CWD)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tVERDICT\tREASON")
+	for _, step := range result.Trace {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", step.Rule, step.Verdict, step.Reason)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	if result.Ask {
+		fmt.Fprintln(w, "decision: ask (the user decides; a later deny would win)")
+		fmt.Fprintf(w, "reason:   %s\n", result.Reason)
+		if result.Warning != "" {
+			fmt.Fprintf(w, "warning:  %s\n", result.Warning)
+		}
+		return
+	}
+	if !result.Allowed {
+		fmt.Fprintln(w, "decision: deny (evaluation stops at the first deny)")
+		fmt.Fprintf(w, "reason:   %s\n", result.Reason)
+		return
+	}
+	if len(result.Audited()) > 0 {
+		fmt.Fprintln(w, "decision: allow (audited violations are not enforced)")
+	} else {
+		fmt.Fprintln(w, "decision: allow")
+	}
+	if result.Warning != "" {
+		fmt.Fprintf(w, "warning:  %s\n", result.Warning)
+	}
+}
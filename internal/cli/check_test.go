@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// isolate points HOME and the working directory at fresh temp dirs
+// so that no global or local config leaks into the test.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	origHome := os.Getenv("HOME")
+	origWd, _ := os.Getwd()
+	t.Cleanup(func() {
+		os.Setenv("HOME", origHome)
+		os.Chdir(origWd)
+	})
+
+	os.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRunCheckFlags(t *testing.T) {
+	isolate(t)
+
+	var out bytes.Buffer
+	err := runCheck([]string{"--tool", "Bash", "--command", "cat /etc/passwd"}, strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatalf("runCheck() failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"tools", "commands", "protected", "workspace", "decision: deny", "outside project directory"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunCheckPayload(t *testing.T) {
+	dir := isolate(t)
+
+	payload := `{"hook_type":"PreToolUse","tool_name":"Read","tool_input":{"file_path":"main.go"},"cwd":"` + dir + `"}`
+
+	var out bytes.Buffer
+	if err := runCheck(nil, strings.NewReader(payload), &out); err != nil {
+		t.Fatalf("runCheck() failed: %v", err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, "decision: allow") {
+		t.Errorf("expected allow:\n%s", got)
+	}
+	if !strings.Contains(got, "scope") || !strings.Contains(got, "rule disabled") {
+		t.Errorf("expected disabled rules in trace:\n%s", got)
+	}
+}
+
+func TestRunCheckInvalidPayload(t *testing.T) {
+	isolate(t)
+
+	var out bytes.Buffer
+	if err := runCheck(nil, strings.NewReader("not json"), &out); err == nil {
+		t.Error("expected error for invalid payload")
+	}
+}
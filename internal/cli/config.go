@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+// RunConfig shows the config layers the hook loads. With --effective it
+// prints the merged config, each value annotated with the layer that set it.
+func RunConfig(args []string) error {
+	return runConfig(args, os.Stdout)
+}
+
+func runConfig(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	effective := fs.Bool("effective", false, "print the merged config and where each value came from")
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman config [--effective]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !*effective {
+		for _, l := range config.Layers() {
+			state := "loaded"
+			if _, err := os.Stat(l.Path); err != nil {
+				state = "not found"
+			}
+			fmt.Fprintf(stdout, "%-10s %s (%s)\n", l.Name, l.Path, state)
+		}
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	out, err := cfg.Effective()
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
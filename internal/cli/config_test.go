@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestRunConfigEffective(t *testing.T) {
+	dir := isolate(t)
+
+	global := config.GlobalConfigPath()
+	if err := os.MkdirAll(filepath.Dir(global), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte("rules:\n  workspace: true\nenforce:\n  minimum: [rules]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".watchman.yml"), []byte("rules:\n  workspace: false\n  scope: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runConfig([]string{"--effective"}, &out); err != nil {
+		t.Fatalf("runConfig() failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"workspace: true # global, minimum by global",
+		"scope: true # repository, minimum by global",
+		"rules.workspace from " + filepath.Join(dir, ".watchman.yml") + " ignored: minimum by global",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunConfigLayers(t *testing.T) {
+	dir := isolate(t)
+	if err := os.WriteFile(filepath.Join(dir, ".watchman.yml"), []byte("rules:\n  scope: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runConfig(nil, &out); err != nil {
+		t.Fatalf("runConfig() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "repository "+filepath.Join(dir, ".watchman.yml")+" (loaded)") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
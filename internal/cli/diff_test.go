@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\n"
+
+	var out bytes.Buffer
+	writeDiff(&out, before, after)
+
+	want := "  a\n- b\n+ B\n  c\n  d\n...\n  g\n  h\n+ i\n"
+	if out.String() != want {
+		t.Errorf("writeDiff() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
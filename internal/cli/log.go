@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/audit"
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+// RunLog prints decisions from the audit log, filtered by tool, decision,
+// rule or time range.
+func RunLog(args []string) error {
+	return runLog(args, os.Stdout, time.Now())
+}
+
+func runLog(args []string, stdout io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	tool := fs.String("tool", "", "only calls to this tool")
+	decision := fs.String("decision", "", "only this decision (allow, deny, ask, audit, advise)")
+	rule := fs.String("rule", "", "only decisions made by this rule (prefix match)")
+	since := fs.String("since", "", "start of range: duration (2h) or time (2006-01-02, RFC 3339)")
+	until := fs.String("until", "", "end of range: duration (30m) or time")
+	limit := fs.Int("limit", 50, "show only the last N matching records (0 = all)")
+	asJSON := fs.Bool("json", false, "print records as JSON lines")
+	fs.SetOutput(stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := audit.Filter{Tool: *tool, Decision: *decision, Rule: *rule}
+	var err error
+	if filter.Since, err = parseTimeArg(*since, now); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseTimeArg(*until, now); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	// Fall back to defaults so the log stays readable when the config is broken
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.Default()
+	}
+
+	records, err := audit.NewLogger(&cfg.Audit).Query(filter)
+	if err != nil {
+		return fmt.Errorf("cannot read audit log: %w", err)
+	}
+	if *limit > 0 && len(records) > *limit {
+		records = records[len(records)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tDECISION\tTOOL\tRULE\tDETAIL")
+	for _, rec := range records {
+		detail := summarizeToolInput(rec.Input)
+		var msgs []string
+		for _, msg := range []string{rec.Reason, rec.Warning} {
+			if msg != "" {
+				msgs = append(msgs, msg)
+			}
+		}
+		msgs = append(msgs, rec.Audited...)
+		if len(msgs) > 0 {
+			detail += " -> " + strings.Join(msgs, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", rec.Time.Local().Format("2006-01-02 15:04:05"), rec.Decision, rec.Tool, rec.Rule, detail)
+	}
+	return tw.Flush()
+}
+
+// parseTimeArg accepts a duration relative to now, a date or an RFC 3339 time.
+func parseTimeArg(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q", s)
+}
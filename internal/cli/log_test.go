@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/audit"
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestRunLog(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := audit.NewLogger(&config.AuditConfig{})
+	l.Write(audit.Record{Time: now.Add(-3 * time.Hour), Tool: "Bash", Decision: "allow", Input: map[string]interface{}{"command": "ls"}})
+	l.Write(audit.Record{Time: now.Add(-time.Hour), Tool: "Read", Decision: "deny", Rule: "workspace", Reason: "outside project", Input: map[string]interface{}{"file_path": "/etc/passwd"}})
+	l.Write(audit.Record{Time: now.Add(-4 * time.Hour), Tool: "Write", Decision: "deny", Rule: "scope", Reason: "out of scope", Warning: "unresolved path", Input: map[string]interface{}{"file_path": "vendor/x"}})
+
+	var out bytes.Buffer
+	if err := runLog([]string{"--decision", "deny"}, &out, now); err != nil {
+		t.Fatalf("runLog() failed: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "/etc/passwd -> outside project") || strings.Contains(got, "ls") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+	if !strings.Contains(got, "vendor/x -> out of scope; unresolved path") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+
+	out.Reset()
+	if err := runLog([]string{"--since", "2h", "--json"}, &out, now); err != nil {
+		t.Fatalf("runLog() failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"tool":"Read"`) {
+		t.Errorf("unexpected JSON output:\n%s", out.String())
+	}
+}
+
+func TestRunLogMissingFile(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+
+	var out bytes.Buffer
+	if err := runLog(nil, &out, time.Now()); err != nil {
+		t.Fatalf("runLog() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state")); !os.IsNotExist(err) {
+		t.Error("reading the log should not create it")
+	}
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseTimeArg("90m", now)
+	if err != nil || !got.Equal(now.Add(-90*time.Minute)) {
+		t.Errorf("duration: got %v, %v", got, err)
+	}
+	got, err = parseTimeArg("2025-02-01T10:00:00Z", now)
+	if err != nil || got.Hour() != 10 {
+		t.Errorf("rfc3339: got %v, %v", got, err)
+	}
+	if _, err := parseTimeArg("2025-02-01", now); err != nil {
+		t.Errorf("date: %v", err)
+	}
+	if _, err := parseTimeArg("yesterday", now); err == nil {
+		t.Error("expected error for unparseable time")
+	}
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+// defaultTestFile is the policy test suite looked up when no file is given.
+const defaultTestFile = ".watchman.test.yml"
+
+// Expected outcomes of a policy test case.
+const (
+	expectAllow  = "allow"
+	expectDeny   = "deny"
+	expectAsk    = "ask"
+	expectAdvise = "advise"
+)
+
+// TestSuite is a set of declarative policy cases.
+// Both YAML and JSON files are accepted.
+type TestSuite struct {
+	Cases []TestCase `yaml:"cases"`
+}
+
+// TestCase describes one tool call and the decision it must produce.
+type TestCase struct {
+	Name   string                 `yaml:"name"`
+	Tool   string                 `yaml:"tool"`
+	Input  map[string]interface{} `yaml:"input"`
+	CWD    string                 `yaml:"cwd,omitempty"`    // Relative to the suite file (default: its directory)
+	Expect string                 `yaml:"expect"`           // allow, deny, ask or advise
+	Reason string                 `yaml:"reason,omitempty"` // Substring of the deny or ask reason or advise warning
+}
+
+// RunTest runs a policy test suite against the project config.
+// Returns an error when any case fails.
+func RunTest(args []string) error {
+	return runTest(args, os.Stdout)
+}
+
+func runTest(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "print the decision trace of failing cases")
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: watchman test [-v] [cases.yml ...] (default: %s)\n", defaultTestFile)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{defaultTestFile}
+	}
+
+	evaluators := newEvaluators("")
+
+	total, failed := 0, 0
+	for _, file := range files {
+		suite, err := loadTestSuite(file)
+		if err != nil {
+			return err
+		}
+
+		baseDir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			return fmt.Errorf("cannot resolve %s: %w", file, err)
+		}
+
+		for i, tc := range suite.Cases {
+			total++
+			name := tc.Name
+			if name == "" {
+				name = fmt.Sprintf("%s#%d", file, i+1)
+			}
+
+			input := tc.input(baseDir)
+			evaluator, err := evaluators.forDir(input.CWD)
+			if err != nil {
+				return err
+			}
+			result := evaluator.Evaluate(input)
+			if msg := tc.check(result); msg != "" {
+				failed++
+				fmt.Fprintf(stdout, "FAIL  %s: %s\n", name, msg)
+				if *verbose {
+					for _, step := range result.Trace {
+						fmt.Fprintf(stdout, "        %-12s %-6s %s\n", step.Rule, step.Verdict, step.Reason)
+					}
+				}
+				continue
+			}
+			fmt.Fprintf(stdout, "PASS  %s\n", name)
+		}
+	}
+
+	fmt.Fprintf(stdout, "\n%d cases, %d passed, %d failed\n", total, total-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d policy cases failed", failed, total)
+	}
+	return nil
+}
+
+// evaluators keeps a dry-run evaluator for each working directory, loaded
+// from the config layers of that directory, or from a single config file
+// when one is given.
+type evaluators struct {
+	path  string
+	byDir map[string]*hook.Evaluator
+}
+
+func newEvaluators(path string) *evaluators {
+	return &evaluators{path: path, byDir: make(map[string]*hook.Evaluator)}
+}
+
+func (e *evaluators) forDir(dir string) (*hook.Evaluator, error) {
+	key := dir
+	if e.path != "" {
+		key = ""
+	}
+	if evaluator, ok := e.byDir[key]; ok {
+		return evaluator, nil
+	}
+
+	var cfg *config.Config
+	var err error
+	switch {
+	case e.path != "":
+		if cfg, err = config.LoadFile(e.path); err != nil {
+			return nil, fmt.Errorf("cannot load config %s: %w", e.path, err)
+		}
+	case dir == "":
+		if cfg, err = config.Load(); err != nil {
+			return nil, fmt.Errorf("cannot load config: %w", err)
+		}
+	default:
+		if cfg, err = config.LoadDir(dir); err != nil {
+			return nil, fmt.Errorf("cannot load config for %s: %w", dir, err)
+		}
+	}
+
+	evaluator := hook.NewDryRunEvaluator(cfg)
+	e.byDir[key] = evaluator
+	return evaluator, nil
+}
+
+func loadTestSuite(path string) (*TestSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read test suite: %w", err)
+	}
+
+	var suite TestSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	for i, tc := range suite.Cases {
+		switch tc.Expect {
+		case expectAllow, expectDeny, expectAsk, expectAdvise:
+		default:
+			return nil, fmt.Errorf("%s: case %d (%s): expect must be allow, deny, ask or advise, got %q", path, i+1, tc.Name, tc.Expect)
+		}
+		if tc.Tool == "" {
+			return nil, fmt.Errorf("%s: case %d (%s): tool is required", path, i+1, tc.Name)
+		}
+	}
+
+	return &suite, nil
+}
+
+// input builds the evaluator input, resolving cwd against the suite directory.
+func (tc TestCase) input(baseDir string) hook.Input {
+	cwd := baseDir
+	if tc.CWD != "" {
+		cwd = tc.CWD
+		if !filepath.IsAbs(cwd) {
+			cwd = filepath.Join(baseDir, cwd)
+		}
+	}
+
+	toolInput := tc.Input
+	if toolInput == nil {
+		toolInput = make(map[string]interface{})
+	}
+
+	return hook.Input{
+		HookType:  "PreToolUse",
+		ToolName:  tc.Tool,
+		ToolInput: toolInput,
+		CWD:       cwd,
+	}
+}
+
+// check compares a result with the expectation.
+// Returns a failure message, or empty string if the case passes.
+func (tc TestCase) check(result hook.Result) string {
+	got, detail := outcome(result)
+
+	switch {
+	case tc.Expect == expectAllow && !result.Allowed:
+		return "expected allow, got " + got + " (" + detail + ")"
+	case tc.Expect == expectDeny && got != expectDeny:
+		return "expected deny, got " + got
+	case tc.Expect == expectAsk && got != expectAsk:
+		return "expected ask, got " + got + describe(detail)
+	case tc.Expect == expectAdvise && got != expectAdvise:
+		return "expected advise, got " + got + describe(detail)
+	}
+
+	if tc.Reason != "" && tc.Expect != expectAllow && !strings.Contains(detail, tc.Reason) {
+		return fmt.Sprintf("expected reason containing %q, got %q", tc.Reason, detail)
+	}
+
+	return ""
+}
+
+// outcome classifies a result as allow, deny, ask or advise along with its message.
+func outcome(result hook.Result) (string, string) {
+	switch {
+	case result.Ask:
+		return expectAsk, result.Reason
+	case !result.Allowed:
+		return expectDeny, result.Reason
+	case result.Warning != "":
+		return expectAdvise, result.Warning
+	default:
+		return expectAllow, ""
+	}
+}
+
+func describe(detail string) string {
+	if detail == "" {
+		return ""
+	}
+	return " (" + detail + ")"
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+func TestRunTestPassing(t *testing.T) {
+	dir := isolate(t)
+
+	suite := `
+cases:
+  - name: reads inside the project
+    tool: Read
+    input:
+      file_path: main.go
+    expect: allow
+  - name: blocks system files
+    tool: Bash
+    input:
+      command: cat /etc/shadow
+    expect: deny
+    reason: outside project directory
+  - name: blocks parent traversal from subdir
+    tool: Read
+    cwd: sub
+    input:
+      file_path: ../../etc
+    expect: deny
+`
+	path := filepath.Join(dir, defaultTestFile)
+	if err := os.WriteFile(path, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runTest(nil, &out); err != nil {
+		t.Fatalf("runTest() failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "3 cases, 3 passed, 0 failed") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestRunTestDirectoryLayer(t *testing.T) {
+	dir := isolate(t)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", ".watchman.yml"), []byte("rules:\n  scope: true\nscope:\n  block: [\"**/*.lock\"]\n"), 0644)
+
+	suite := `
+cases:
+  - name: lock file from the top
+    tool: Write
+    input:
+      file_path: go.lock
+    expect: allow
+  - name: lock file from sub
+    tool: Write
+    cwd: sub
+    input:
+      file_path: go.lock
+    expect: deny
+`
+	path := filepath.Join(dir, defaultTestFile)
+	if err := os.WriteFile(path, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runTest(nil, &out); err != nil {
+		t.Fatalf("runTest() failed: %v\n%s", err, out.String())
+	}
+}
+
+func TestRunTestFailing(t *testing.T) {
+	dir := isolate(t)
+
+	suite := `{"cases": [
+  {"name": "wrong expectation", "tool": "Read", "input": {"file_path": "/etc/passwd"}, "expect": "allow"},
+  {"name": "wrong reason", "tool": "Read", "input": {"file_path": "/etc/passwd"}, "expect": "deny", "reason": "scope"}
+]}`
+	path := filepath.Join(dir, "cases.json")
+	if err := os.WriteFile(path, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runTest([]string{"-v", path}, &out)
+	if err == nil {
+		t.Fatal("expected error for failing cases")
+	}
+
+	got := out.String()
+	for _, want := range []string{"FAIL  wrong expectation: expected allow, got deny", "FAIL  wrong reason: expected reason containing", "workspace"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTestCaseCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		tc     TestCase
+		allow  bool
+		reason string
+		warn   string
+		pass   bool
+	}{
+		{"allow matches allow", TestCase{Expect: "allow"}, true, "", "", true},
+		{"allow matches advise", TestCase{Expect: "allow"}, true, "", "careful", true},
+		{"advise needs warning", TestCase{Expect: "advise"}, true, "", "", false},
+		{"advise with reason", TestCase{Expect: "advise", Reason: "care"}, true, "", "careful", true},
+		{"deny with reason", TestCase{Expect: "deny", Reason: "blocked"}, false, "tool is blocked", "", true},
+		{"deny got allow", TestCase{Expect: "deny"}, true, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.tc.check(resultFor(tt.allow, tt.reason, tt.warn))
+			if (msg == "") != tt.pass {
+				t.Errorf("check() = %q, want pass=%v", msg, tt.pass)
+			}
+		})
+	}
+}
+
+func TestTestCaseCheckAsk(t *testing.T) {
+	ask := hook.Result{Ask: true, Reason: "outside the workspace"}
+
+	if msg := (TestCase{Expect: "ask", Reason: "workspace"}).check(ask); msg != "" {
+		t.Errorf("ask should match ask: %s", msg)
+	}
+	if msg := (TestCase{Expect: "deny"}).check(ask); msg != "expected deny, got ask" {
+		t.Errorf("ask should not match deny, got %q", msg)
+	}
+	if msg := (TestCase{Expect: "ask"}).check(resultFor(false, "blocked", "")); msg == "" {
+		t.Error("deny should not match ask")
+	}
+}
+
+func TestLoadTestSuiteInvalidExpect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cases.yml")
+	os.WriteFile(path, []byte("cases:\n  - tool: Read\n    expect: maybe\n"), 0644)
+
+	if _, err := loadTestSuite(path); err == nil {
+		t.Error("expected error for invalid expect value")
+	}
+}
+
+func resultFor(allowed bool, reason, warning string) hook.Result {
+	return hook.Result{Allowed: allowed, Reason: reason, Warning: warning}
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adrianpk/watchman/internal/hook"
+	"github.com/adrianpk/watchman/internal/transcript"
+)
+
+// replayed is the decision one config produced for a recorded tool call.
+type replayed struct {
+	call     transcript.ToolCall
+	decision string // allow, deny, ask or advise
+	detail   string // deny or ask reason, or advise warning
+}
+
+// RunReplay feeds every tool call of a recorded session transcript through
+// the evaluator and summarizes the decisions. With --compare, it lists the
+// calls whose decision differs between the two configs.
+func RunReplay(args []string) error {
+	return runReplay(args, os.Stdout)
+}
+
+func runReplay(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config file to evaluate against (default: project config)")
+	comparePath := fs.String("compare", "", "second config file; prints calls whose decision changed")
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman replay <transcript.jsonl> [--config a.yml] [--compare b.yml]")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("replay requires exactly one transcript")
+	}
+
+	calls, err := transcript.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("cannot read transcript: %w", err)
+	}
+
+	baseline, err := replayCalls(newEvaluators(*configPath), calls)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "replayed %d tool calls from %s\n\n", len(calls), positional[0])
+
+	if *comparePath == "" {
+		printReplaySummary(stdout, baseline)
+		return nil
+	}
+
+	other, err := replayCalls(newEvaluators(*comparePath), calls)
+	if err != nil {
+		return err
+	}
+	printReplayDiff(stdout, describeConfig(*configPath), *comparePath, baseline, other)
+	return nil
+}
+
+// replayCalls evaluates each call with the config of its recorded working
+// directory, unless the evaluators read a single config file.
+func replayCalls(evaluators *evaluators, calls []transcript.ToolCall) ([]replayed, error) {
+	results := make([]replayed, 0, len(calls))
+	for _, call := range calls {
+		evaluator, err := evaluators.forDir(call.CWD)
+		if err != nil {
+			return nil, err
+		}
+		toolInput := call.Input
+		if toolInput == nil {
+			toolInput = make(map[string]interface{})
+		}
+		result := evaluator.Evaluate(hook.Input{
+			HookType:  "PreToolUse",
+			ToolName:  call.Name,
+			ToolInput: toolInput,
+			CWD:       call.CWD,
+		})
+		decision, detail := outcome(result)
+		results = append(results, replayed{call: call, decision: decision, detail: detail})
+	}
+	return results, nil
+}
+
+func printReplaySummary(w io.Writer, results []replayed) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.decision]++
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DECISION\tCOUNT")
+	for _, d := range []string{expectAllow, expectAdvise, expectAsk, expectDeny} {
+		fmt.Fprintf(tw, "%s\t%d\n", d, counts[d])
+	}
+	tw.Flush()
+
+	printReplayed(w, "asked", expectAsk, counts, results)
+	printReplayed(w, "denied", expectDeny, counts, results)
+}
+
+// printReplayed lists the calls that got the decision, with their reason.
+func printReplayed(w io.Writer, title, decision string, counts map[string]int, results []replayed) {
+	if counts[decision] == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, r := range results {
+		if r.decision != decision {
+			continue
+		}
+		fmt.Fprintf(w, "  line %d  %s  %s\n", r.call.Line, r.call.Name, summarizeToolInput(r.call.Input))
+		fmt.Fprintf(w, "      %s\n", r.detail)
+	}
+}
+
+func printReplayDiff(w io.Writer, nameA, nameB string, a, b []replayed) {
+	fmt.Fprintf(w, "A: %s\nB: %s\n\n", nameA, nameB)
+
+	changed := 0
+	for i := range a {
+		if a[i].decision == b[i].decision {
+			continue
+		}
+		changed++
+		call := a[i].call
+		fmt.Fprintf(w, "line %d  %s  %s\n", call.Line, call.Name, summarizeToolInput(call.Input))
+		fmt.Fprintf(w, "    A: %s%s\n", a[i].decision, describe(a[i].detail))
+		fmt.Fprintf(w, "    B: %s%s\n", b[i].decision, describe(b[i].detail))
+	}
+
+	if changed == 0 {
+		fmt.Fprintln(w, "no decisions changed")
+		return
+	}
+	fmt.Fprintf(w, "\n%d of %d decisions changed\n", changed, len(a))
+}
+
+func describeConfig(path string) string {
+	if path == "" {
+		return "project config"
+	}
+	return path
+}
+
+// summarizeToolInput returns the most telling field of a tool input on one line.
+func summarizeToolInput(input map[string]interface{}) string {
+	var parts []string
+	for _, key := range []string{"command", "file_path", "pattern", "path"} {
+		if v, ok := input[key].(string); ok && v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	s := strings.Join(parts, " ")
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > 100 {
+		s = s[:100] + "..."
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTranscript(t *testing.T, dir string) string {
+	t.Helper()
+
+	lines := []string{
+		`{"type":"assistant","cwd":"` + dir + `","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"assistant","cwd":"` + dir + `","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"vendor/lib.go","content":"x"}}]}}`,
+		`{"type":"assistant","cwd":"` + dir + `","message":{"content":[{"type":"tool_use","id":"t3","name":"Read","input":{"file_path":"/etc/passwd"}}]}}`,
+	}
+	path := filepath.Join(dir, "session.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunReplaySummary(t *testing.T) {
+	dir := isolate(t)
+	path := writeTranscript(t, dir)
+
+	var out bytes.Buffer
+	if err := runReplay([]string{path}, &out); err != nil {
+		t.Fatalf("runReplay() failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"replayed 3 tool calls", "line 3  Read  /etc/passwd", "outside project directory"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunReplayAsk(t *testing.T) {
+	dir := isolate(t)
+	path := writeTranscript(t, dir)
+
+	cfg := filepath.Join(dir, "ask.yml")
+	os.WriteFile(cfg, []byte("rules:\n  scope: true\nscope:\n  block:\n    - vendor/**\n  on_violation: ask\n"), 0644)
+
+	var out bytes.Buffer
+	if err := runReplay([]string{path, "--config", cfg}, &out); err != nil {
+		t.Fatalf("runReplay() failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"ask       1", "asked:", "line 2  Write  vendor/lib.go"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunReplayDirectoryLayer(t *testing.T) {
+	dir := isolate(t)
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	os.WriteFile(filepath.Join(sub, ".watchman.yml"), []byte("rules:\n  scope: true\nscope:\n  block: [\"vendor/**\"]\n"), 0644)
+
+	line := `{"type":"assistant","cwd":"` + sub + `","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"vendor/lib.go","content":"x"}}]}}`
+	path := filepath.Join(dir, "session.jsonl")
+	os.WriteFile(path, []byte(line+"\n"), 0644)
+
+	var out bytes.Buffer
+	if err := runReplay([]string{path}, &out); err != nil {
+		t.Fatalf("runReplay() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "line 1  Write  vendor/lib.go") {
+		t.Errorf("expected the config of the recorded directory to deny the call:\n%s", out.String())
+	}
+}
+
+func TestRunReplayCompare(t *testing.T) {
+	dir := isolate(t)
+	path := writeTranscript(t, dir)
+
+	loose := filepath.Join(dir, "loose.yml")
+	strict := filepath.Join(dir, "strict.yml")
+	os.WriteFile(loose, []byte("rules:\n  workspace: true\n"), 0644)
+	os.WriteFile(strict, []byte("rules:\n  workspace: true\n  scope: true\nscope:\n  block:\n    - vendor/**\n"), 0644)
+
+	var out bytes.Buffer
+	if err := runReplay([]string{path, "--config", loose, "--compare", strict}, &out); err != nil {
+		t.Fatalf("runReplay() failed: %v", err)
+	}
+
+	got := out.String()
+	if !strings.Contains(got, "line 2  Write  vendor/lib.go") {
+		t.Errorf("expected changed Write call:\n%s", got)
+	}
+	if !strings.Contains(got, "1 of 3 decisions changed") {
+		t.Errorf("unexpected diff summary:\n%s", got)
+	}
+}
+
+func TestRunReplayMissingTranscript(t *testing.T) {
+	isolate(t)
+
+	var out bytes.Buffer
+	if err := runReplay(nil, &out); err == nil {
+		t.Error("expected error without transcript")
+	}
+	if err := runReplay([]string{"missing.jsonl"}, &out); err == nil {
+		t.Error("expected error for missing transcript")
+	}
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/server"
+)
+
+// RunServe runs the policy daemon for the current directory until interrupted.
+// Hook invocations started in the same directory are answered by the daemon.
+func RunServe(args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runServe(ctx, args, os.Stdout, os.Stderr)
+}
+
+func runServe(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	socket := fs.String("socket", "", "socket path (default: derived from the current directory)")
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman serve [--socket path]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("cannot get working directory: %w", err)
+	}
+
+	path := *socket
+	if path == "" {
+		path = server.SocketPath(dir)
+	}
+
+	logf := func(format string, args ...interface{}) {
+		ts := time.Now().Format("15:04:05")
+		fmt.Fprintf(stderr, "[%s] "+format+"\n", append([]interface{}{ts}, args...)...)
+	}
+
+	fmt.Fprintf(stdout, "Serving %s on %s\n", dir, path)
+	return server.New(path, logf).Serve(ctx)
+}
@@ -1,101 +1,318 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/config"
 )
 
-// RunSetup configures the Claude Code hook.
-func RunSetup() error {
-	home, err := os.UserHomeDir()
+// RunSetup registers watchman in the Claude Code settings for every hook event it handles.
+// Existing hooks are kept; only watchman's own entries are added or updated.
+func RunSetup(args []string) error {
+	return runSetup(args, os.Stdout)
+}
+
+// RunUninstall removes watchman's entries from the Claude Code settings,
+// leaving every other hook in place.
+func RunUninstall(args []string) error {
+	return runUninstall(args, os.Stdout)
+}
+
+// settingsOptions are the flags shared by setup and uninstall.
+type settingsOptions struct {
+	project bool
+	dryRun  bool
+}
+
+func parseSettingsFlags(name string, args []string, stdout io.Writer) (settingsOptions, error) {
+	var opts settingsOptions
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.BoolVar(&opts.project, "project", false, "use .claude/settings.json in the current project instead of ~/.claude/settings.json")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "show the changes without writing them")
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(stdout, "Usage: watchman %s [--project] [--dry-run]\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	return opts, nil
+}
+
+func runSetup(args []string, stdout io.Writer) error {
+	opts, err := parseSettingsFlags("setup", args, stdout)
+	if err != nil {
+		return err
+	}
+
+	watchmanPath, err := findBinary()
 	if err != nil {
-		return fmt.Errorf("cannot get home directory: %w", err)
+		return err
 	}
 
-	claudeDir := filepath.Join(home, ".claude")
-	settingsPath := filepath.Join(claudeDir, "settings.json")
-	watchmanPath := filepath.Join(home, "go", "bin", "watchman")
+	return editSettings(opts, stdout, func(settings map[string]interface{}) {
+		hooks, ok := settings["hooks"].(map[string]interface{})
+		if !ok {
+			hooks = make(map[string]interface{})
+			settings["hooks"] = hooks
+		}
 
-	if err := os.MkdirAll(claudeDir, 0755); err != nil {
-		return fmt.Errorf("cannot create .claude directory: %w", err)
+		for _, event := range config.Events {
+			entries, _ := hooks[event].([]interface{})
+			if hasWatchmanCommand(entries, watchmanPath) {
+				continue
+			}
+			// Drop entries pointing at another watchman binary before adding the current one.
+			entries = removeWatchmanHooks(entries)
+			hooks[event] = append(entries, watchmanEntry(event, watchmanPath))
+		}
+	}, "Watchman hook already configured", "Run 'watchman init' to create watchman config")
+}
+
+func runUninstall(args []string, stdout io.Writer) error {
+	opts, err := parseSettingsFlags("uninstall", args, stdout)
+	if err != nil {
+		return err
 	}
 
-	settings := make(map[string]interface{})
+	return editSettings(opts, stdout, func(settings map[string]interface{}) {
+		hooks, ok := settings["hooks"].(map[string]interface{})
+		if !ok {
+			return
+		}
 
-	data, err := os.ReadFile(settingsPath)
-	if err == nil && len(data) > 0 {
-		if err := json.Unmarshal(data, &settings); err != nil {
-			return fmt.Errorf("cannot parse settings.json: %w", err)
+		for event, value := range hooks {
+			entries, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			entries = removeWatchmanHooks(entries)
+			if len(entries) == 0 {
+				delete(hooks, event)
+			} else {
+				hooks[event] = entries
+			}
 		}
+		if len(hooks) == 0 {
+			delete(settings, "hooks")
+		}
+	}, "Watchman hook not configured", "")
+}
+
+// editSettings loads the settings file, applies edit and, when anything changed,
+// prints a diff, backs up the previous file and writes the new one.
+func editSettings(opts settingsOptions, stdout io.Writer, edit func(map[string]interface{}), unchangedMsg, doneMsg string) error {
+	settingsPath, err := settingsPath(opts.project)
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(settingsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot read settings.json: %w", err)
 	}
 
-	hooks, ok := settings["hooks"].(map[string]interface{})
-	if !ok {
-		hooks = make(map[string]interface{})
-		settings["hooks"] = hooks
+	settings := make(map[string]interface{})
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := json.Unmarshal(original, &settings); err != nil {
+			return fmt.Errorf("cannot parse settings.json: %w", err)
+		}
 	}
 
-	preToolUse, ok := hooks["PreToolUse"].([]interface{})
-	if !ok {
-		preToolUse = []interface{}{}
+	before, err := marshalSettings(settings)
+	if err != nil {
+		return err
+	}
+	edit(settings)
+	after, err := marshalSettings(settings)
+	if err != nil {
+		return err
 	}
 
-	if hasWatchmanHook(preToolUse, watchmanPath) {
-		fmt.Println("Watchman hook already configured")
+	if bytes.Equal(before, after) {
+		fmt.Fprintln(stdout, unchangedMsg)
 		return nil
 	}
 
-	watchmanHook := map[string]interface{}{
-		"matcher": "*",
-		"hooks": []interface{}{
-			map[string]interface{}{
-				"type":    "command",
-				"command": watchmanPath,
-			},
-		},
+	fmt.Fprintf(stdout, "--- %s\n+++ %s\n", settingsPath, settingsPath)
+	writeDiff(stdout, string(before), string(after))
+
+	if opts.dryRun {
+		fmt.Fprintln(stdout, "\nDry run, nothing written")
+		return nil
 	}
 
-	hooks["PreToolUse"] = []interface{}{watchmanHook}
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		return fmt.Errorf("cannot create .claude directory: %w", err)
+	}
 
-	output, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("cannot marshal settings: %w", err)
+	if len(original) > 0 {
+		backup := settingsPath + "." + time.Now().Format("20060102-150405") + ".bak"
+		if err := os.WriteFile(backup, original, 0644); err != nil {
+			return fmt.Errorf("cannot back up settings.json: %w", err)
+		}
+		fmt.Fprintf(stdout, "\nBackup: %s\n", backup)
 	}
 
-	if err := os.WriteFile(settingsPath, output, 0644); err != nil {
+	if err := os.WriteFile(settingsPath, after, 0644); err != nil {
 		return fmt.Errorf("cannot write settings.json: %w", err)
 	}
 
-	fmt.Printf("Configured hook: %s\n", settingsPath)
-	fmt.Println("Run 'watchman init' to create watchman config")
+	fmt.Fprintf(stdout, "Updated: %s\n", settingsPath)
+	if doneMsg != "" {
+		fmt.Fprintln(stdout, doneMsg)
+	}
 	return nil
 }
 
-func hasWatchmanHook(preToolUse []interface{}, watchmanPath string) bool {
-	for _, entry := range preToolUse {
+// settingsPath returns ~/.claude/settings.json, or .claude/settings.json
+// in the current directory for project scope.
+func settingsPath(project bool) (string, error) {
+	if project {
+		dir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("cannot get working directory: %w", err)
+		}
+		return filepath.Join(dir, ".claude", "settings.json"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot get home directory: %w", err)
+	}
+	return filepath.Join(home, ".claude", "settings.json"), nil
+}
+
+func marshalSettings(settings map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal settings: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// findBinary locates the watchman executable to register: the running binary,
+// then $GOBIN, then $PATH. Binaries built by `go run` live in a temporary
+// directory and are never registered.
+func findBinary() (string, error) {
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		if filepath.Base(exe) == "watchman" && !isTempBuild(exe) {
+			return exe, nil
+		}
+	}
+
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		candidate := filepath.Join(gobin, "watchman")
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	if p, err := exec.LookPath("watchman"); err == nil {
+		if abs, err := filepath.Abs(p); err == nil {
+			return abs, nil
+		}
+		return p, nil
+	}
+
+	return "", errors.New("cannot find the watchman binary: install it with 'go install' or add it to PATH")
+}
+
+func isTempBuild(path string) bool {
+	return strings.HasPrefix(path, os.TempDir()) && strings.Contains(path, "go-build")
+}
+
+// watchmanEntry builds the settings entry that runs watchman for an event.
+// Only tool events take a matcher.
+func watchmanEntry(event, watchmanPath string) map[string]interface{} {
+	entry := map[string]interface{}{
+		"hooks": []interface{}{
+			map[string]interface{}{
+				"type":    "command",
+				"command": watchmanPath,
+			},
+		},
+	}
+	if event == config.EventPreToolUse || event == config.EventPostToolUse {
+		entry["matcher"] = "*"
+	}
+	return entry
+}
+
+// removeWatchmanHooks drops watchman handlers from the entries,
+// and entries left without handlers. Other handlers are kept as they are.
+func removeWatchmanHooks(entries []interface{}) []interface{} {
+	var kept []interface{}
+	for _, entry := range entries {
 		e, ok := entry.(map[string]interface{})
 		if !ok {
+			kept = append(kept, entry)
 			continue
 		}
 		hooksList, ok := e["hooks"].([]interface{})
 		if !ok {
+			kept = append(kept, entry)
 			continue
 		}
+
+		var others []interface{}
 		for _, h := range hooksList {
-			if h == "watchman" {
-				return true
+			if !isWatchmanHandler(h) {
+				others = append(others, h)
 			}
-			if hm, ok := h.(map[string]interface{}); ok {
-				if cmd, ok := hm["command"].(string); ok {
-					if strings.Contains(cmd, "watchman") {
-						return true
-					}
-				}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		e["hooks"] = others
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+// hasWatchmanCommand reports whether the entries already run exactly watchmanPath.
+func hasWatchmanCommand(entries []interface{}, watchmanPath string) bool {
+	for _, entry := range entries {
+		e, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		hooksList, _ := e["hooks"].([]interface{})
+		for _, h := range hooksList {
+			if hm, ok := h.(map[string]interface{}); ok && hm["command"] == watchmanPath {
+				return true
 			}
 		}
 	}
 	return false
 }
+
+func isWatchmanHandler(h interface{}) bool {
+	if h == "watchman" {
+		return true
+	}
+	if hm, ok := h.(map[string]interface{}); ok {
+		if cmd, ok := hm["command"].(string); ok {
+			fields := strings.Fields(cmd)
+			return len(fields) > 0 && filepath.Base(fields[0]) == "watchman"
+		}
+	}
+	return false
+}
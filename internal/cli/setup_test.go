@@ -1,197 +1,245 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
-func TestRunSetup(t *testing.T) {
-	// Save original HOME and restore after test
-	origHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", origHome)
-
-	tmpHome := t.TempDir()
-	os.Setenv("HOME", tmpHome)
-
-	// Create go/bin directory for watchman path
-	os.MkdirAll(filepath.Join(tmpHome, "go", "bin"), 0755)
-
-	// Run setup
-	err := RunSetup()
-	if err != nil {
-		t.Fatalf("RunSetup() failed: %v", err)
-	}
-
-	// Verify settings.json was created
-	settingsPath := filepath.Join(tmpHome, ".claude", "settings.json")
-	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
-		t.Error("settings.json was not created")
+// setupEnv isolates HOME and the working directory and installs a fake
+// watchman binary in GOBIN. Returns the binary path.
+func setupEnv(t *testing.T) string {
+	t.Helper()
+	isolate(t)
+
+	origGobin, hadGobin := os.LookupEnv("GOBIN")
+	t.Cleanup(func() {
+		if hadGobin {
+			os.Setenv("GOBIN", origGobin)
+		} else {
+			os.Unsetenv("GOBIN")
+		}
+	})
+
+	gobin := t.TempDir()
+	os.Setenv("GOBIN", gobin)
+	bin := filepath.Join(gobin, "watchman")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
 	}
+	return bin
+}
 
-	// Verify content
-	content, err := os.ReadFile(settingsPath)
+func readSettings(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	content, err := os.ReadFile(path)
 	if err != nil {
 		t.Fatalf("cannot read settings: %v", err)
 	}
-
 	var settings map[string]interface{}
 	if err := json.Unmarshal(content, &settings); err != nil {
 		t.Fatalf("cannot parse settings: %v", err)
 	}
+	return settings
+}
 
-	hooks, ok := settings["hooks"].(map[string]interface{})
+func writeSettings(t *testing.T, path string, settings map[string]interface{}) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, _ := json.Marshal(settings)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunSetup(t *testing.T) {
+	bin := setupEnv(t)
+
+	var out bytes.Buffer
+	if err := runSetup(nil, &out); err != nil {
+		t.Fatalf("runSetup() failed: %v", err)
+	}
+
+	settingsPath := filepath.Join(os.Getenv("HOME"), ".claude", "settings.json")
+	hooks, ok := readSettings(t, settingsPath)["hooks"].(map[string]interface{})
 	if !ok {
 		t.Fatal("settings missing hooks")
 	}
 
-	if _, ok := hooks["PreToolUse"]; !ok {
-		t.Error("settings missing PreToolUse hook")
+	for _, event := range []string{"PreToolUse", "PostToolUse", "SessionStart", "UserPromptSubmit", "Stop"} {
+		entries, ok := hooks[event].([]interface{})
+		if !ok || !hasWatchmanCommand(entries, bin) {
+			t.Errorf("settings missing %s hook for %s", event, bin)
+		}
+	}
+
+	stop := hooks["Stop"].([]interface{})[0].(map[string]interface{})
+	if _, ok := stop["matcher"]; ok {
+		t.Error("Stop entry should not have a matcher")
+	}
+
+	if !strings.Contains(out.String(), "+ ") {
+		t.Errorf("expected diff preview, got:\n%s", out.String())
 	}
 }
 
 func TestRunSetupAlreadyConfigured(t *testing.T) {
-	origHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", origHome)
+	setupEnv(t)
+
+	if err := runSetup(nil, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
 
-	tmpHome := t.TempDir()
-	os.Setenv("HOME", tmpHome)
+	var out bytes.Buffer
+	if err := runSetup(nil, &out); err != nil {
+		t.Fatalf("runSetup() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "already configured") {
+		t.Errorf("expected already configured, got:\n%s", out.String())
+	}
+}
 
-	// Create .claude dir and existing settings with watchman hook
-	claudeDir := filepath.Join(tmpHome, ".claude")
-	os.MkdirAll(claudeDir, 0755)
+func TestRunSetupExistingSettings(t *testing.T) {
+	bin := setupEnv(t)
 
-	existingSettings := map[string]interface{}{
+	settingsPath := filepath.Join(os.Getenv("HOME"), ".claude", "settings.json")
+	teamHook := map[string]interface{}{
+		"matcher": "Bash",
+		"hooks": []interface{}{
+			map[string]interface{}{"type": "command", "command": "/opt/team/lint-hook"},
+		},
+	}
+	staleHook := map[string]interface{}{
+		"matcher": "*",
+		"hooks": []interface{}{
+			map[string]interface{}{"type": "command", "command": "/old/bin/watchman"},
+		},
+	}
+	writeSettings(t, settingsPath, map[string]interface{}{
+		"other": "setting",
 		"hooks": map[string]interface{}{
-			"PreToolUse": []interface{}{
-				map[string]interface{}{
-					"matcher": "*",
-					"hooks": []interface{}{
-						map[string]interface{}{
-							"type":    "command",
-							"command": filepath.Join(tmpHome, "go", "bin", "watchman"),
-						},
-					},
-				},
-			},
+			"PreToolUse": []interface{}{teamHook, staleHook},
 		},
+	})
+
+	if err := runSetup(nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("runSetup() failed: %v", err)
 	}
-	data, _ := json.Marshal(existingSettings)
-	settingsPath := filepath.Join(claudeDir, "settings.json")
-	os.WriteFile(settingsPath, data, 0644)
 
-	// Run setup should not fail
-	err := RunSetup()
-	if err != nil {
-		t.Fatalf("RunSetup() failed: %v", err)
+	settings := readSettings(t, settingsPath)
+	if settings["other"] != "setting" {
+		t.Error("existing settings were not preserved")
 	}
-}
 
-func TestRunSetupExistingSettings(t *testing.T) {
-	origHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", origHome)
+	pre := settings["hooks"].(map[string]interface{})["PreToolUse"].([]interface{})
+	if len(pre) != 2 {
+		t.Fatalf("expected team hook and watchman, got %v", pre)
+	}
+	if cmd := pre[0].(map[string]interface{})["hooks"].([]interface{})[0].(map[string]interface{})["command"]; cmd != "/opt/team/lint-hook" {
+		t.Errorf("team hook not preserved: %v", pre[0])
+	}
+	if !hasWatchmanCommand(pre[1:], bin) {
+		t.Errorf("stale watchman entry not replaced: %v", pre[1])
+	}
 
-	tmpHome := t.TempDir()
-	os.Setenv("HOME", tmpHome)
+	backups, _ := filepath.Glob(settingsPath + ".*.bak")
+	if len(backups) != 1 {
+		t.Errorf("expected one backup, got %v", backups)
+	}
+}
 
-	// Create .claude dir with existing settings (no hooks)
-	claudeDir := filepath.Join(tmpHome, ".claude")
-	os.MkdirAll(claudeDir, 0755)
+func TestRunSetupProjectDryRun(t *testing.T) {
+	setupEnv(t)
 
-	existingSettings := map[string]interface{}{
-		"other": "setting",
+	var out bytes.Buffer
+	if err := runSetup([]string{"--project", "--dry-run"}, &out); err != nil {
+		t.Fatalf("runSetup() failed: %v", err)
 	}
-	data, _ := json.Marshal(existingSettings)
-	settingsPath := filepath.Join(claudeDir, "settings.json")
-	os.WriteFile(settingsPath, data, 0644)
 
-	// Run setup
-	err := RunSetup()
-	if err != nil {
-		t.Fatalf("RunSetup() failed: %v", err)
+	if !strings.Contains(out.String(), filepath.Join(".claude", "settings.json")) {
+		t.Errorf("expected project settings path in preview:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(".claude", "settings.json")); !os.IsNotExist(err) {
+		t.Error("dry run wrote the settings file")
 	}
 
-	// Verify existing settings preserved
-	content, _ := os.ReadFile(settingsPath)
-	var settings map[string]interface{}
-	json.Unmarshal(content, &settings)
-
-	if settings["other"] != "setting" {
-		t.Error("existing settings were not preserved")
+	if err := runSetup([]string{"--project"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(".claude", "settings.json")); err != nil {
+		t.Errorf("project settings not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".claude", "settings.json")); !os.IsNotExist(err) {
+		t.Error("project setup touched user settings")
 	}
 }
 
-func TestHasWatchmanHook(t *testing.T) {
-	tests := []struct {
-		name       string
-		preToolUse []interface{}
-		want       bool
-	}{
-		{
-			name:       "empty list",
-			preToolUse: []interface{}{},
-			want:       false,
-		},
-		{
-			name: "short form watchman",
-			preToolUse: []interface{}{
+func TestRunUninstall(t *testing.T) {
+	setupEnv(t)
+
+	settingsPath := filepath.Join(os.Getenv("HOME"), ".claude", "settings.json")
+	writeSettings(t, settingsPath, map[string]interface{}{
+		"hooks": map[string]interface{}{
+			"PreToolUse": []interface{}{
 				map[string]interface{}{
 					"matcher": "*",
-					"hooks":   []interface{}{"watchman"},
-				},
-			},
-			want: true,
-		},
-		{
-			name: "long form watchman",
-			preToolUse: []interface{}{
-				map[string]interface{}{
-					"matcher": "Bash",
 					"hooks": []interface{}{
-						map[string]interface{}{
-							"type":    "command",
-							"command": "/home/user/go/bin/watchman",
-						},
+						map[string]interface{}{"type": "command", "command": "/opt/team/lint-hook"},
+						map[string]interface{}{"type": "command", "command": "/home/me/go/bin/watchman"},
 					},
 				},
 			},
-			want: true,
-		},
-		{
-			name: "other hook",
-			preToolUse: []interface{}{
-				map[string]interface{}{
-					"matcher": "*",
-					"hooks":   []interface{}{"other-tool"},
-				},
-			},
-			want: false,
-		},
-		{
-			name: "invalid entry",
-			preToolUse: []interface{}{
-				"not a map",
-			},
-			want: false,
-		},
-		{
-			name: "invalid hooks list",
-			preToolUse: []interface{}{
+			"Stop": []interface{}{
 				map[string]interface{}{
-					"matcher": "*",
-					"hooks":   "not a list",
+					"hooks": []interface{}{
+						map[string]interface{}{"type": "command", "command": "/home/me/go/bin/watchman"},
+					},
 				},
 			},
-			want: false,
 		},
+	})
+
+	if err := runUninstall(nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("runUninstall() failed: %v", err)
+	}
+
+	hooks := readSettings(t, settingsPath)["hooks"].(map[string]interface{})
+	if _, ok := hooks["Stop"]; ok {
+		t.Error("empty Stop event should be removed")
+	}
+	pre := hooks["PreToolUse"].([]interface{})
+	if len(pre) != 1 || len(pre[0].(map[string]interface{})["hooks"].([]interface{})) != 1 {
+		t.Errorf("expected only the team hook to remain, got %v", pre)
+	}
+
+	var out bytes.Buffer
+	if err := runUninstall(nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "not configured") {
+		t.Errorf("expected not configured, got:\n%s", out.String())
+	}
+}
+
+func TestIsWatchmanHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler interface{}
+		want    bool
+	}{
+		{"short form", "watchman", true},
+		{"long form", map[string]interface{}{"type": "command", "command": "/home/user/go/bin/watchman"}, true},
+		{"name containing watchman", map[string]interface{}{"type": "command", "command": "/usr/bin/watchman-sentinel"}, false},
+		{"other hook", "other-tool", false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := hasWatchmanHook(tt.preToolUse, "/any/path")
-			if got != tt.want {
-				t.Errorf("hasWatchmanHook() = %v, want %v", got, tt.want)
+			if got := isWatchmanHandler(tt.handler); got != tt.want {
+				t.Errorf("isWatchmanHandler(%v) = %v, want %v", tt.handler, got, tt.want)
 			}
 		})
 	}
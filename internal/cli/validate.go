@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+// RunValidate lints config files strictly and prints file:line diagnostics.
+// Without arguments it checks the files the hook would load.
+func RunValidate(args []string) error {
+	return runValidate(args, os.Stdout)
+}
+
+func runValidate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "Usage: watchman validate [config.yml ...]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = config.Paths()
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(stdout, "no config files found, defaults apply")
+		return nil
+	}
+
+	errCount, warnCount := 0, 0
+	for _, file := range files {
+		diags, err := config.ValidateFile(file)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", file, err)
+		}
+		for _, d := range diags {
+			fmt.Fprintln(stdout, d.String())
+			if d.Severity == config.SeverityError {
+				errCount++
+			} else {
+				warnCount++
+			}
+		}
+	}
+
+	if errCount == 0 && warnCount == 0 {
+		for _, file := range files {
+			fmt.Fprintf(stdout, "%s: ok\n", file)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "\n%d errors, %d warnings\n", errCount, warnCount)
+	if errCount > 0 {
+		return fmt.Errorf("config has %d errors", errCount)
+	}
+	return nil
+}
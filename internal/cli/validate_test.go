@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	dir := isolate(t)
+
+	path := filepath.Join(dir, ".watchman.yml")
+	content := "rules:\n  workspace: true\n  scopes: true\nversioning:\n  commit:\n    no_period: true\n    require_period: true\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runValidate(nil, &out)
+	if err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+
+	got := out.String()
+	for _, want := range []string{".watchman.yml:3: error: unknown key", ".watchman.yml:7: error: versioning.commit.require_period", "2 errors, 0 warnings"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunValidateOK(t *testing.T) {
+	dir := isolate(t)
+
+	path := filepath.Join(dir, "strict.yml")
+	if err := os.WriteFile(path, []byte("rules:\n  workspace: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runValidate([]string{path}, &out); err != nil {
+		t.Fatalf("runValidate() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "strict.yml: ok") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunValidateNoConfig(t *testing.T) {
+	isolate(t)
+
+	var out bytes.Buffer
+	if err := runValidate(nil, &out); err != nil {
+		t.Fatalf("runValidate() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "no config files found") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
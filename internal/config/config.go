@@ -20,8 +20,45 @@ type Config struct {
 	Invariants  InvariantsConfig  `yaml:"invariants,omitempty"`
 	Commands    CommandsConfig    `yaml:"commands"`
 	Tools       ToolsConfig       `yaml:"tools"`
+	Protected   ProtectedConfig   `yaml:"protected,omitempty"`
 	Hooks       []HookConfig      `yaml:"hooks,omitempty"`
 	Reminders   []ReminderConfig  `yaml:"reminders,omitempty"`
+	Audit       AuditConfig       `yaml:"audit,omitempty"`
+	Enforce     EnforceConfig     `yaml:"enforce,omitempty"`
+
+	// Directories holds the scope and invariants of directory layers,
+	// which only apply to files under their directory.
+	Directories []DirectoryConfig `yaml:"-"`
+
+	hash      string     // digest of the loaded files, see Hash
+	layers    []Layer    // files loaded, in order
+	merged    *yaml.Node // the merged document, see Effective
+	origins   map[string]string
+	enforced  map[string]enforcement
+	overrides []Override
+}
+
+// EnforceConfig sets floors that configs loaded later cannot lower. Keys are
+// dotted paths such as rules, rules.workspace or workspace.block. Only the
+// system and global configs can enforce.
+type EnforceConfig struct {
+	// Locked keys keep the value they have after this config; later
+	// configs cannot change them.
+	Locked []string `yaml:"locked,omitempty"`
+
+	// Minimum keys can only be tightened by later configs: a boolean can
+	// be turned on but not off, a list can grow, except allow lists, and
+	// other values are locked.
+	Minimum []string `yaml:"minimum,omitempty"`
+}
+
+// DirectoryConfig is the scope and invariants a .watchman.yml below the
+// repository top sets. They only apply to files under Dir, with patterns
+// relative to Dir, on top of the repository-wide ones.
+type DirectoryConfig struct {
+	Dir        string           `yaml:"-"`
+	Scope      ScopeConfig      `yaml:"scope"`
+	Invariants InvariantsConfig `yaml:"invariants"`
 }
 
 // RulesConfig enables/disables semantic rules.
@@ -37,23 +74,103 @@ type RulesConfig struct {
 
 // WorkspaceConfig controls the workspace confinement rule.
 type WorkspaceConfig struct {
-	Allow []string `yaml:"allow"`
-	Block []string `yaml:"block"`
+	Allow      []string `yaml:"allow"`
+	ReadAllow  []string `yaml:"read_allow,omitempty"`  // outside paths the agent may only read
+	WriteAllow []string `yaml:"write_allow,omitempty"` // outside paths the agent may read and write
+	Block      []string `yaml:"block"`
+
+	// ToolchainCaches adds the Go module cache, GOROOT and the build cache
+	// to ReadAllow, so the agent can navigate dependencies without writing there.
+	ToolchainCaches bool `yaml:"toolchain_caches,omitempty"`
+
+	// OnUnresolved decides what happens to paths that depend on values only
+	// known when the command runs, such as $(pwd)/x or $1: deny, warn or allow.
+	// Empty means warn.
+	OnUnresolved string `yaml:"on_unresolved,omitempty"`
+
+	// Root sets where the workspace boundary lies: cwd, git, a path, or a
+	// list of them for setups that span several repositories. Empty means cwd.
+	Root Roots `yaml:"root,omitempty"`
+
+	Events      []string `yaml:"events,omitempty"`       // PreToolUse (default), PostToolUse
+	OnViolation string   `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string   `yaml:"mode,omitempty"`         // enforce (default) or audit
+}
+
+// ProtectedConfig adds paths no tool may access to the built-in ones.
+// Entries can only be added, never removed.
+type ProtectedConfig struct {
+	Paths     []string `yaml:"paths,omitempty"`     // files or directories (ending in /), absolute, ~ or relative to the project
+	Filenames []string `yaml:"filenames,omitempty"` // names protected in any directory
+	Globs     []string `yaml:"globs,omitempty"`     // gitignore-style patterns
+	Events    []string `yaml:"events,omitempty"`    // PostToolUse; PreToolUse always checks them
+}
+
+// Roots is a list of workspace roots, written as a single value or a list.
+type Roots []string
+
+// UnmarshalYAML accepts both root: git and root: [git, ../shared].
+func (r *Roots) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*r = Roots{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*r = list
+	return nil
 }
 
+// Special values for workspace.root; anything else is a path.
+const (
+	RootCwd = "cwd"
+	RootGit = "git"
+)
+
+// Outcomes for workspace.on_unresolved.
+const (
+	UnresolvedDeny  = "deny"
+	UnresolvedWarn  = "warn"
+	UnresolvedAllow = "allow"
+)
+
+// Outcomes for on_violation: what happens to a call a rule rejects. Deny
+// blocks it, ask lets the user decide and warn lets it run with a warning.
+const (
+	ViolationDeny = "deny"
+	ViolationAsk  = "ask"
+	ViolationWarn = "warn"
+)
+
+// Modes of a rule or invariant check. In audit mode a violation does not
+// reach on_violation: the call is allowed and the violation is recorded,
+// with the reason it would have had.
+const (
+	ModeEnforce = "enforce"
+	ModeAudit   = "audit"
+)
+
 // ScopeConfig controls which files can be modified.
 type ScopeConfig struct {
-	Allow []string `yaml:"allow"`
-	Block []string `yaml:"block"`
+	Allow       []string `yaml:"allow"`
+	Block       []string `yaml:"block"`
+	Events      []string `yaml:"events,omitempty"`       // PreToolUse (default), PostToolUse
+	OnViolation string   `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string   `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // VersioningConfig controls commit and branch rules.
 type VersioningConfig struct {
-	Commit     CommitConfig     `yaml:"commit"`
-	Branches   BranchesConfig   `yaml:"branches"`
-	Operations OperationsConfig `yaml:"operations"`
-	Workflow   string           `yaml:"workflow"`
-	Tool       string           `yaml:"tool"`
+	Commit      CommitConfig     `yaml:"commit"`
+	Branches    BranchesConfig   `yaml:"branches"`
+	Operations  OperationsConfig `yaml:"operations"`
+	Workflow    string           `yaml:"workflow"`
+	Tool        string           `yaml:"tool"`
+	Events      []string         `yaml:"events,omitempty"`       // PreToolUse (default), PostToolUse
+	OnViolation string           `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string           `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // CommitConfig controls commit message validation.
@@ -81,19 +198,27 @@ type BranchesConfig struct {
 
 // IncrementalConfig controls change size limits.
 type IncrementalConfig struct {
-	MaxFiles  int     `yaml:"max_files"`
-	WarnRatio float64 `yaml:"warn_ratio"`
+	MaxFiles    int      `yaml:"max_files"`
+	WarnRatio   float64  `yaml:"warn_ratio"`
+	Events      []string `yaml:"events,omitempty"`       // PreToolUse (default), Stop
+	OnViolation string   `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string   `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // CommandsConfig controls shell command filtering.
 type CommandsConfig struct {
-	Block []string `yaml:"block"`
+	Block       []string `yaml:"block"`
+	Events      []string `yaml:"events,omitempty"`       // PreToolUse (default), PostToolUse
+	OnViolation string   `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string   `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // ToolsConfig controls which tools are available.
 type ToolsConfig struct {
-	Allow []string `yaml:"allow"`
-	Block []string `yaml:"block"`
+	Allow       []string `yaml:"allow"`
+	Block       []string `yaml:"block"`
+	OnViolation string   `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string   `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // HookConfig defines an external hook executable.
@@ -107,14 +232,41 @@ type HookConfig struct {
 	Timeout        time.Duration `yaml:"timeout,omitempty"`
 	OnError        string        `yaml:"on_error,omitempty"`
 	ProtectedPaths []string      `yaml:"protected_paths,omitempty"`
+	Events         []string      `yaml:"events,omitempty"`       // Hook events the hook runs on (default: PreToolUse)
+	OnViolation    string        `yaml:"on_violation,omitempty"` // deny (default), ask or warn, for the hook's denials
+	Mode           string        `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
-// ReminderConfig defines a periodic reminder to show the agent.
+// ReminderConfig defines a reminder to show the agent, periodically or when
+// a condition is met.
 type ReminderConfig struct {
-	Name         string `yaml:"name"`
-	Message      string `yaml:"message"`
-	EveryTasks   int    `yaml:"every_tasks,omitempty"`   // Trigger every N tool invocations
-	EveryMinutes int    `yaml:"every_minutes,omitempty"` // Trigger every N minutes
+	Name         string   `yaml:"name"`
+	Message      string   `yaml:"message,omitempty"`       // Supports ${modified_files}, ${branch}, ${denied_reason}, ${file}, ${tool}, ${tasks}
+	MessageFile  string   `yaml:"message_file,omitempty"`  // Read the message from a file, relative to the repository
+	Section      string   `yaml:"section,omitempty"`       // Only the Markdown section of message_file under this heading
+	On           string   `yaml:"on,omitempty"`            // Condition: tool calls (default), session_start, commit or deny
+	Tools        []string `yaml:"tools,omitempty"`         // Only count calls of these tools
+	Paths        []string `yaml:"paths,omitempty"`         // Only count calls touching these paths
+	EveryTasks   int      `yaml:"every_tasks,omitempty"`   // Trigger every N occurrences of the condition
+	EveryMinutes int      `yaml:"every_minutes,omitempty"` // Trigger every N minutes
+	SameFile     int      `yaml:"same_file,omitempty"`     // Trigger when a file has been modified N times
+}
+
+// Reminder conditions, see ReminderConfig.On.
+const (
+	ReminderOnTool         = ""
+	ReminderOnSessionStart = "session_start"
+	ReminderOnCommit       = "commit"
+	ReminderOnDeny         = "deny"
+)
+
+// AuditConfig controls the structured decision log.
+type AuditConfig struct {
+	Path       string `yaml:"path,omitempty"`         // Default: $XDG_STATE_HOME/watchman/audit.jsonl
+	MaxSizeMB  int    `yaml:"max_size_mb,omitempty"`  // Rotate when the log exceeds this size (default 10)
+	MaxFiles   int    `yaml:"max_files,omitempty"`    // Rotated files to keep (default 5)
+	MaxAgeDays int    `yaml:"max_age_days,omitempty"` // Delete rotated files older than this (0 = keep)
+	Disabled   bool   `yaml:"disabled,omitempty"`
 }
 
 // InvariantsConfig defines declarative structural checks.
@@ -124,6 +276,9 @@ type InvariantsConfig struct {
 	Imports     []ImportCheck      `yaml:"imports,omitempty"`
 	Naming      []NamingCheck      `yaml:"naming,omitempty"`
 	Required    []RequiredCheck    `yaml:"required,omitempty"`
+	Events      []string           `yaml:"events,omitempty"`       // PreToolUse (default), PostToolUse, Stop
+	OnViolation string             `yaml:"on_violation,omitempty"` // deny (default), ask or warn
+	Mode        string             `yaml:"mode,omitempty"`         // enforce (default) or audit
 }
 
 // CoexistenceCheck ensures related files exist together.
@@ -132,6 +287,7 @@ type CoexistenceCheck struct {
 	If      string `yaml:"if"`      // Glob pattern that triggers the check
 	Require string `yaml:"require"` // Pattern that must exist (supports ${base}, ${name}, ${ext})
 	Message string `yaml:"message,omitempty"`
+	Mode    string `yaml:"mode,omitempty"` // enforce (default) or audit
 }
 
 // ContentCheck validates file content against patterns.
@@ -141,6 +297,7 @@ type ContentCheck struct {
 	Require string   `yaml:"require,omitempty"` // Regex that must match
 	Forbid  string   `yaml:"forbid,omitempty"`  // Regex that must not match
 	Message string   `yaml:"message,omitempty"`
+	Mode    string   `yaml:"mode,omitempty"` // enforce (default) or audit
 }
 
 // ImportCheck validates import statements (regex-based, not AST).
@@ -149,6 +306,7 @@ type ImportCheck struct {
 	Paths   []string `yaml:"paths"`  // Files to check
 	Forbid  string   `yaml:"forbid"` // Regex pattern for forbidden imports
 	Message string   `yaml:"message,omitempty"`
+	Mode    string   `yaml:"mode,omitempty"` // enforce (default) or audit
 }
 
 // NamingCheck validates file naming conventions.
@@ -157,6 +315,7 @@ type NamingCheck struct {
 	Paths   []string `yaml:"paths"`   // Directories/patterns to check
 	Pattern string   `yaml:"pattern"` // Regex pattern filenames must match
 	Message string   `yaml:"message,omitempty"`
+	Mode    string   `yaml:"mode,omitempty"` // enforce (default) or audit
 }
 
 // RequiredCheck ensures certain files exist in directories.
@@ -166,212 +325,43 @@ type RequiredCheck struct {
 	When    string `yaml:"when,omitempty"` // Only check when this pattern exists
 	Require string `yaml:"require"`        // File that must exist
 	Message string `yaml:"message,omitempty"`
+	Mode    string `yaml:"mode,omitempty"` // enforce (default) or audit
 }
 
-// Default returns the default configuration.
-func Default() *Config {
-	return &Config{
-		Version: 1,
-		Rules: RulesConfig{
-			Workspace: true,
-		},
-	}
-}
-
-// Load loads configuration. If local config exists, it is used exclusively.
-// Otherwise, global config is used. No merging occurs.
-func Load() (*Config, error) {
-	cfg := Default()
-
-	// Check for local config first - if exists, use only local
-	localPath := localConfigPath()
-	if localPath != "" {
-		if _, err := os.Stat(localPath); err == nil {
-			if err := cfg.loadFrom(localPath); err != nil {
-				return nil, err
-			}
-			return cfg, nil
-		}
-	}
-
-	// No local config - use global
-	globalPath := globalConfigPath()
-	if globalPath != "" {
-		if err := cfg.loadFrom(globalPath); err != nil && !os.IsNotExist(err) {
-			return nil, err
-		}
-	}
-
-	return cfg, nil
-}
-
-// loadFrom loads and merges a config file into the current config.
-func (c *Config) loadFrom(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-
-	var overlay Config
-	if err := yaml.Unmarshal(data, &overlay); err != nil {
-		return err
-	}
-
-	c.merge(&overlay)
-	return nil
-}
-
-// merge applies overlay config onto the current config.
-// Local values override global values.
-// Block lists are appended, not replaced.
-func (c *Config) merge(overlay *Config) {
-	if overlay.Version > 0 {
-		c.Version = overlay.Version
-	}
-	c.Rules = overlay.Rules
-	c.Workspace.Allow = appendUnique(c.Workspace.Allow, overlay.Workspace.Allow)
-	c.Workspace.Block = appendUnique(c.Workspace.Block, overlay.Workspace.Block)
-	c.Scope.Allow = appendUnique(c.Scope.Allow, overlay.Scope.Allow)
-	c.Scope.Block = appendUnique(c.Scope.Block, overlay.Scope.Block)
-	c.Versioning = overlay.Versioning
-	c.Versioning.Branches.Protected = appendUnique(c.Versioning.Branches.Protected, overlay.Versioning.Branches.Protected)
-	c.Incremental = overlay.Incremental
-	c.Invariants = mergeInvariants(c.Invariants, overlay.Invariants)
-	c.Commands.Block = appendUnique(c.Commands.Block, overlay.Commands.Block)
-	c.Tools.Allow = appendUnique(c.Tools.Allow, overlay.Tools.Allow)
-	c.Tools.Block = appendUnique(c.Tools.Block, overlay.Tools.Block)
-	c.Hooks = appendHooksUnique(c.Hooks, overlay.Hooks)
-	c.Reminders = appendRemindersUnique(c.Reminders, overlay.Reminders)
-}
-
-func mergeInvariants(base, overlay InvariantsConfig) InvariantsConfig {
-	return InvariantsConfig{
-		Coexistence: appendCoexistenceUnique(base.Coexistence, overlay.Coexistence),
-		Content:     appendContentUnique(base.Content, overlay.Content),
-		Imports:     appendImportsUnique(base.Imports, overlay.Imports),
-		Naming:      appendNamingUnique(base.Naming, overlay.Naming),
-		Required:    appendRequiredUnique(base.Required, overlay.Required),
-	}
-}
-
-func appendCoexistenceUnique(base, items []CoexistenceCheck) []CoexistenceCheck {
-	seen := make(map[string]bool)
-	for _, c := range base {
-		seen[c.Name] = true
-	}
-	result := base
-	for _, c := range items {
-		if !seen[c.Name] {
-			result = append(result, c)
-			seen[c.Name] = true
-		}
-	}
-	return result
-}
-
-func appendContentUnique(base, items []ContentCheck) []ContentCheck {
-	seen := make(map[string]bool)
-	for _, c := range base {
-		seen[c.Name] = true
-	}
-	result := base
-	for _, c := range items {
-		if !seen[c.Name] {
-			result = append(result, c)
-			seen[c.Name] = true
-		}
-	}
-	return result
-}
-
-func appendImportsUnique(base, items []ImportCheck) []ImportCheck {
-	seen := make(map[string]bool)
-	for _, c := range base {
-		seen[c.Name] = true
-	}
-	result := base
-	for _, c := range items {
-		if !seen[c.Name] {
-			result = append(result, c)
-			seen[c.Name] = true
-		}
-	}
-	return result
-}
-
-func appendNamingUnique(base, items []NamingCheck) []NamingCheck {
-	seen := make(map[string]bool)
-	for _, c := range base {
-		seen[c.Name] = true
-	}
-	result := base
-	for _, c := range items {
-		if !seen[c.Name] {
-			result = append(result, c)
-			seen[c.Name] = true
-		}
-	}
-	return result
-}
+// Claude Code hook events watchman can be registered for.
+const (
+	EventPreToolUse       = "PreToolUse"
+	EventPostToolUse      = "PostToolUse"
+	EventSessionStart     = "SessionStart"
+	EventUserPromptSubmit = "UserPromptSubmit"
+	EventStop             = "Stop"
+)
 
-func appendRequiredUnique(base, items []RequiredCheck) []RequiredCheck {
-	seen := make(map[string]bool)
-	for _, c := range base {
-		seen[c.Name] = true
-	}
-	result := base
-	for _, c := range items {
-		if !seen[c.Name] {
-			result = append(result, c)
-			seen[c.Name] = true
-		}
-	}
-	return result
-}
+// Events lists every supported hook event.
+var Events = []string{EventPreToolUse, EventPostToolUse, EventSessionStart, EventUserPromptSubmit, EventStop}
 
-func appendHooksUnique(base, items []HookConfig) []HookConfig {
-	seen := make(map[string]bool)
-	for _, h := range base {
-		seen[h.Name] = true
+// RunsOn reports whether a rule declaring the given events runs on event.
+// Rules that declare no events run on PreToolUse only.
+func RunsOn(events []string, event string) bool {
+	if len(events) == 0 {
+		return event == EventPreToolUse
 	}
-	result := base
-	for _, h := range items {
-		if !seen[h.Name] {
-			result = append(result, h)
-			seen[h.Name] = true
+	for _, e := range events {
+		if e == event {
+			return true
 		}
 	}
-	return result
+	return false
 }
 
-func appendRemindersUnique(base, items []ReminderConfig) []ReminderConfig {
-	seen := make(map[string]bool)
-	for _, r := range base {
-		seen[r.Name] = true
-	}
-	result := base
-	for _, r := range items {
-		if !seen[r.Name] {
-			result = append(result, r)
-			seen[r.Name] = true
-		}
-	}
-	return result
-}
-
-func appendUnique(base, items []string) []string {
-	seen := make(map[string]bool)
-	for _, s := range base {
-		seen[s] = true
-	}
-	result := base
-	for _, s := range items {
-		if !seen[s] {
-			result = append(result, s)
-			seen[s] = true
-		}
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		Version: 1,
+		Rules: RulesConfig{
+			Workspace: true,
+		},
 	}
-	return result
 }
 
 func globalConfigPath() string {
@@ -386,11 +376,3 @@ func globalConfigPath() string {
 func GlobalConfigPath() string {
 	return globalConfigPath()
 }
-
-func localConfigPath() string {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return ""
-	}
-	return filepath.Join(cwd, ".watchman.yml")
-}
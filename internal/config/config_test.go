@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -47,8 +50,8 @@ tools:
 		t.Fatal(err)
 	}
 
-	cfg := Default()
-	if err := cfg.loadFrom(configPath); err != nil {
+	cfg, err := LoadFile(configPath)
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -72,90 +75,389 @@ tools:
 	}
 }
 
-func TestMerge(t *testing.T) {
-	base := &Config{
-		Version: 1,
-		Rules:   RulesConfig{Workspace: true},
-		Workspace: WorkspaceConfig{
-			Allow: []string{"/tmp"},
-			Block: []string{".env"},
-		},
-		Scope: ScopeConfig{
-			Allow: []string{"src/**"},
-		},
+func TestLoadWorkspaceRoot(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Roots
+	}{
+		{"single value", "workspace:\n  root: git\n", Roots{RootGit}},
+		{"list", "workspace:\n  root: [git, ../shared]\n", Roots{RootGit, "../shared"}},
+		{"unset", "workspace:\n  allow: [/tmp]\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Workspace.Root, tt.want) {
+				t.Errorf("Workspace.Root = %q, want %q", cfg.Workspace.Root, tt.want)
+			}
+		})
 	}
+}
 
-	overlay := &Config{
-		Rules: RulesConfig{Workspace: true, Scope: true},
-		Workspace: WorkspaceConfig{
-			Allow: []string{"/var"},
-			Block: []string{"secrets/"},
-		},
-		Scope: ScopeConfig{
-			Allow: []string{"internal/**"},
-			Block: []string{"vendor/**"},
-		},
-		Commands: CommandsConfig{
-			Block: []string{"sudo"},
-		},
-	}
+// loadLayers writes each content to its own file and loads them in order,
+// the first as the global config and the rest as repository configs.
+func loadLayers(t *testing.T, contents ...string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	var layers []Layer
+	for i, content := range contents {
+		l := Layer{Name: LayerRepository, Path: filepath.Join(dir, fmt.Sprintf("%d.yml", i))}
+		if i == 0 {
+			l.Name = LayerGlobal
+		}
+		if err := os.WriteFile(l.Path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		layers = append(layers, l)
+	}
+	return load(layers)
+}
 
-	base.merge(overlay)
+func TestMerge(t *testing.T) {
+	base := `
+rules:
+  workspace: true
+workspace:
+  allow: [/tmp]
+  block: [.env]
+scope:
+  allow: [src/**]
+`
+	overlay := `
+rules:
+  scope: true
+workspace:
+  allow: [/var]
+  block: [secrets/]
+scope:
+  allow: [internal/**]
+  block: [vendor/**]
+commands:
+  block: [sudo]
+`
+	cfg, err := loadLayers(t, base, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if !base.Rules.Workspace {
+	if !cfg.Rules.Workspace {
 		t.Error("Rules.Workspace should be true")
 	}
-	if !base.Rules.Scope {
+	if !cfg.Rules.Scope {
 		t.Error("Rules.Scope should be true after merge")
 	}
-	if len(base.Workspace.Allow) != 2 {
-		t.Errorf("Workspace.Allow = %v, want 2 items", base.Workspace.Allow)
+	if !reflect.DeepEqual(cfg.Workspace.Allow, []string{"/tmp", "/var"}) {
+		t.Errorf("Workspace.Allow = %v, want [/tmp /var]", cfg.Workspace.Allow)
 	}
-	if len(base.Workspace.Block) != 2 {
-		t.Errorf("Workspace.Block = %v, want 2 items", base.Workspace.Block)
+	if !reflect.DeepEqual(cfg.Workspace.Block, []string{".env", "secrets/"}) {
+		t.Errorf("Workspace.Block = %v, want [.env secrets/]", cfg.Workspace.Block)
 	}
-	if len(base.Scope.Allow) != 2 {
-		t.Errorf("Scope.Allow = %v, want 2 items", base.Scope.Allow)
+	if len(cfg.Scope.Allow) != 2 {
+		t.Errorf("Scope.Allow = %v, want 2 items", cfg.Scope.Allow)
 	}
-	if len(base.Scope.Block) != 1 {
-		t.Errorf("Scope.Block = %v, want 1 item", base.Scope.Block)
+	if len(cfg.Scope.Block) != 1 {
+		t.Errorf("Scope.Block = %v, want 1 item", cfg.Scope.Block)
 	}
-	if len(base.Commands.Block) != 1 {
-		t.Errorf("Commands.Block = %v, want 1 item", base.Commands.Block)
+	if len(cfg.Commands.Block) != 1 {
+		t.Errorf("Commands.Block = %v, want 1 item", cfg.Commands.Block)
 	}
 }
 
 func TestMergeOverridesRules(t *testing.T) {
-	base := &Config{
-		Rules: RulesConfig{Workspace: true, Scope: true},
+	cfg, err := loadLayers(t,
+		"rules:\n  workspace: true\n  scope: true\n",
+		"rules:\n  workspace: false\n  scope: false\n")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	overlay := &Config{
-		Rules: RulesConfig{Workspace: false, Scope: false},
+	if cfg.Rules.Workspace {
+		t.Error("Rules.Workspace should be false after merge")
+	}
+	if cfg.Rules.Scope {
+		t.Error("Rules.Scope should be false after merge")
 	}
+}
 
-	base.merge(overlay)
+func TestMergeNamedItems(t *testing.T) {
+	base := `
+invariants:
+  content:
+    - name: no-todo
+      forbid: TODO
+`
+	overlay := `
+invariants:
+  content:
+    - name: no-todo
+      forbid: FIXME
+    - name: no-print
+      forbid: fmt\\.Print
+`
+	cfg, err := loadLayers(t, base, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if base.Rules.Workspace {
-		t.Error("Rules.Workspace should be false after merge")
+	content := cfg.Invariants.Content
+	if len(content) != 2 || content[0].Forbid != "TODO" || content[1].Name != "no-print" {
+		t.Errorf("Invariants.Content = %+v, want no-todo from base and no-print", content)
 	}
-	if base.Rules.Scope {
-		t.Error("Rules.Scope should be false after merge")
+}
+
+func TestMergeEnforced(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		local   string
+		check   func(*Config) bool
+		ignored []string
+	}{
+		{
+			name:    "minimum keeps rule on",
+			global:  "rules:\n  workspace: true\nenforce:\n  minimum: [rules]\n",
+			local:   "rules:\n  workspace: false\n  scope: true\n",
+			check:   func(c *Config) bool { return c.Rules.Workspace && c.Rules.Scope },
+			ignored: []string{"rules.workspace"},
+		},
+		{
+			name:   "minimum lets block lists grow",
+			global: "workspace:\n  block: [.env]\nenforce:\n  minimum: [workspace]\n",
+			local:  "workspace:\n  block: [secrets/]\n",
+			check: func(c *Config) bool {
+				return reflect.DeepEqual(c.Workspace.Block, []string{".env", "secrets/"})
+			},
+		},
+		{
+			name:    "minimum keeps allow lists",
+			global:  "workspace:\n  allow: [/tmp]\nenforce:\n  minimum: [workspace]\n",
+			local:   "workspace:\n  allow: [/tmp, /]\n  toolchain_caches: true\n",
+			check:   func(c *Config) bool { return len(c.Workspace.Allow) == 1 && !c.Workspace.ToolchainCaches },
+			ignored: []string{"workspace.allow", "workspace.toolchain_caches"},
+		},
+		{
+			name:    "minimum keeps workspace root",
+			global:  "enforce:\n  minimum: [workspace]\n",
+			local:   "workspace:\n  root: /\n",
+			check:   func(c *Config) bool { return len(c.Workspace.Root) == 0 },
+			ignored: []string{"workspace.root"},
+		},
+		{
+			name:    "minimum keeps enforce mode",
+			global:  "enforce:\n  minimum: [rules, workspace, commands]\n",
+			local:   "workspace:\n  mode: audit\ncommands:\n  mode: audit\n",
+			check:   func(c *Config) bool { return c.Workspace.Mode == "" && c.Commands.Mode == "" },
+			ignored: []string{"workspace.mode", "commands.mode"},
+		},
+		{
+			name:    "minimum keeps on_violation",
+			global:  "workspace:\n  on_violation: ask\nenforce:\n  minimum: [workspace, scope]\n",
+			local:   "workspace:\n  on_violation: warn\nscope:\n  on_violation: ask\n",
+			check:   func(c *Config) bool { return c.Workspace.OnViolation == ViolationAsk && c.Scope.OnViolation == "" },
+			ignored: []string{"workspace.on_violation", "scope.on_violation"},
+		},
+		{
+			name:    "minimum keeps on_unresolved",
+			global:  "enforce:\n  minimum: [workspace]\n",
+			local:   "workspace:\n  on_unresolved: allow\n",
+			check:   func(c *Config) bool { return c.Workspace.OnUnresolved == "" },
+			ignored: []string{"workspace.on_unresolved"},
+		},
+		{
+			name:   "minimum lets enforcement tighten",
+			global: "workspace:\n  on_violation: warn\nenforce:\n  minimum: [workspace]\n",
+			local:  "workspace:\n  on_violation: deny\n  on_unresolved: deny\n  mode: enforce\n",
+			check: func(c *Config) bool {
+				return c.Workspace.OnViolation == ViolationDeny && c.Workspace.OnUnresolved == UnresolvedDeny && c.Workspace.Mode == ModeEnforce
+			},
+		},
+		{
+			name:    "locked key",
+			global:  "commands:\n  block: [sudo]\nenforce:\n  locked: [commands.block]\n",
+			local:   "commands:\n  block: [curl]\n",
+			check:   func(c *Config) bool { return reflect.DeepEqual(c.Commands.Block, []string{"sudo"}) },
+			ignored: []string{"commands.block"},
+		},
+		{
+			name:   "enforce in repository ignored",
+			global: "rules:\n  scope: true\n",
+			local:  "enforce:\n  locked: [rules]\nrules:\n  scope: false\n",
+			check:  func(c *Config) bool { return !c.Rules.Scope && len(c.Enforce.Locked) == 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadLayers(t, tt.global, tt.local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config: %+v", cfg)
+			}
+			var ignored []string
+			for _, o := range cfg.Overrides() {
+				ignored = append(ignored, o.Key)
+			}
+			if !reflect.DeepEqual(ignored, tt.ignored) {
+				t.Errorf("ignored = %q, want %q", ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestLayersFor(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "services", "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, l := range LayersFor(sub) {
+		got = append(got, l.Name+" "+l.Path)
+	}
+	want := []string{
+		LayerSystem + " " + systemConfigPath,
+		LayerGlobal + " " + globalConfigPath(),
+		LayerRepository + " " + filepath.Join(repo, ".watchman.yml"),
+		LayerDirectory + " " + filepath.Join(repo, "services", ".watchman.yml"),
+		LayerDirectory + " " + filepath.Join(sub, ".watchman.yml"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LayersFor(%s) = %q, want %q", sub, got, want)
+	}
+
+	if layers := LayersFor(repo); layers[len(layers)-1].Name != LayerRepository {
+		t.Errorf("last layer at the repository top = %v, want repository", layers[len(layers)-1])
+	}
+}
+
+func TestLoadDirDirectoryConfigs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	billing := filepath.Join(repo, "services", "billing")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(billing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(repo, ".watchman.yml"):    "rules:\n  scope: true\nscope:\n  block: [vendor/**]\n",
+		filepath.Join(billing, ".watchman.yml"): "scope:\n  allow: [\"*.go\"]\ncommands:\n  block: [psql]\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := LoadDir(billing)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	if len(cfg.Scope.Allow) != 0 || !reflect.DeepEqual(cfg.Scope.Block, []string{"vendor/**"}) {
+		t.Errorf("Scope = %+v, want the repository scope only", cfg.Scope)
+	}
+	if !reflect.DeepEqual(cfg.Commands.Block, []string{"psql"}) {
+		t.Errorf("Commands.Block = %v, want [psql]", cfg.Commands.Block)
+	}
+
+	dirs := cfg.DirectoriesFor(filepath.Join(billing, "invoice.go"))
+	if len(dirs) != 1 || dirs[0].Dir != billing || !reflect.DeepEqual(dirs[0].Scope.Allow, []string{"*.go"}) {
+		t.Errorf("DirectoriesFor(invoice.go) = %+v, want the billing scope", dirs)
+	}
+	if dirs := cfg.DirectoriesFor(filepath.Join(repo, "main.go")); len(dirs) != 0 {
+		t.Errorf("DirectoriesFor(main.go) = %+v, want none", dirs)
 	}
 }
 
-func TestAppendUnique(t *testing.T) {
-	base := []string{"a", "b"}
-	items := []string{"b", "c"}
+func TestLoadLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	system := filepath.Join(t.TempDir(), "system.yml")
+	defer func(path string) { systemConfigPath = path }(systemConfigPath)
+	systemConfigPath = system
 
-	result := appendUnique(base, items)
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "tools")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(globalConfigPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
 
-	if len(result) != 3 {
-		t.Errorf("len(result) = %d, want 3", len(result))
+	files := map[string]string{
+		system:                               "rules:\n  workspace: true\nenforce:\n  locked: [rules.workspace]\n",
+		globalConfigPath():                   "commands:\n  block: [sudo]\n",
+		filepath.Join(repo, ".watchman.yml"): "rules:\n  workspace: false\n  scope: true\ncommands:\n  block: [curl]\n",
+		filepath.Join(sub, ".watchman.yml"):  "rules:\n  scope: false\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	os.Chdir(sub)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Layers()) != 4 {
+		t.Errorf("Layers() = %v, want 4 layers", cfg.Layers())
+	}
+	if !cfg.Rules.Workspace {
+		t.Error("Rules.Workspace is locked by the system config")
+	}
+	if cfg.Rules.Scope {
+		t.Error("Rules.Scope should be false from the directory config")
+	}
+	if !reflect.DeepEqual(cfg.Commands.Block, []string{"sudo", "curl"}) {
+		t.Errorf("Commands.Block = %v, want [sudo curl]", cfg.Commands.Block)
+	}
+
+	out, err := cfg.Effective()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"workspace: true # system, locked by system",
+		"scope: false # directory",
+		"- curl # repository",
+		"rules.workspace from " + filepath.Join(repo, ".watchman.yml") + " ignored: locked by system",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Effective() missing %q:\n%s", want, out)
+		}
 	}
 }
 
 func TestLoadWithLocalConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
 	defer os.Chdir(originalWd)
@@ -178,6 +480,7 @@ rules:
 }
 
 func TestLoadWithoutConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
 	defer os.Chdir(originalWd)
@@ -193,26 +496,50 @@ func TestLoadWithoutConfig(t *testing.T) {
 	}
 }
 
+func TestLoadProtectedAddsUp(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Dir(globalConfigPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := "protected:\n  paths:\n    - ~/.kube/config\n"
+	if err := os.WriteFile(globalConfigPath(), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	os.Chdir(project)
+	local := "protected:\n  paths:\n    - ~/.npmrc\n"
+	if err := os.WriteFile(".watchman.yml", []byte(local), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []string{"~/.kube/config", "~/.npmrc"}
+	if !reflect.DeepEqual(cfg.Protected.Paths, want) {
+		t.Errorf("Protected.Paths = %q, want %q", cfg.Protected.Paths, want)
+	}
+}
+
 func TestLoadFromInvalidYAML(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yml")
 
 	os.WriteFile(configPath, []byte("invalid: yaml: content:"), 0644)
 
-	cfg := Default()
-	err := cfg.loadFrom(configPath)
-
-	if err == nil {
-		t.Error("loadFrom should return error for invalid YAML")
+	if _, err := LoadFile(configPath); err == nil {
+		t.Error("LoadFile should return error for invalid YAML")
 	}
 }
 
 func TestLoadFromNonexistentFile(t *testing.T) {
-	cfg := Default()
-	err := cfg.loadFrom("/nonexistent/path/config.yml")
-
-	if err == nil {
-		t.Error("loadFrom should return error for nonexistent file")
+	if _, err := LoadFile("/nonexistent/path/config.yml"); err == nil {
+		t.Error("LoadFile should return error for nonexistent file")
 	}
 }
 
@@ -230,16 +557,32 @@ func TestGlobalConfigPath(t *testing.T) {
 	}
 }
 
-func TestLocalConfigPath(t *testing.T) {
-	path := localConfigPath()
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "strict.yml")
+	content := `
+rules:
+  workspace: true
+  scope: true
+scope:
+  allow:
+    - src/**
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
 
-	if path == "" {
-		t.Error("localConfigPath should return non-empty path")
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if cfg.Version != 1 {
+		t.Errorf("Version = %d, want default 1", cfg.Version)
+	}
+	if !cfg.Rules.Scope || len(cfg.Scope.Allow) != 1 {
+		t.Errorf("unexpected config: %+v", cfg)
 	}
 
-	cwd, _ := os.Getwd()
-	expected := filepath.Join(cwd, ".watchman.yml")
-	if path != expected {
-		t.Errorf("localConfigPath = %s, want %s", path, expected)
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("expected error for missing file")
 	}
 }
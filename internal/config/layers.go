@@ -0,0 +1,694 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config layers, in load order. Each layer overrides the ones before it,
+// within the limits the system and global configs enforce.
+const (
+	LayerDefault    = "default"
+	LayerSystem     = "system"
+	LayerGlobal     = "global"
+	LayerRepository = "repository"
+	LayerDirectory  = "directory"
+	LayerFile       = "file" // a file named explicitly, see LoadFile
+)
+
+// Enforcement modes, see EnforceConfig.
+const (
+	EnforceLocked  = "locked"
+	EnforceMinimum = "minimum"
+)
+
+// systemConfigPath is the machine-wide config, usually managed by an
+// organization. A variable so tests can move it.
+var systemConfigPath = "/etc/watchman/config.yml"
+
+// Layer is a config file and the level it is loaded at.
+type Layer struct {
+	Name string
+	Path string
+}
+
+// enforces reports whether the layer may set floors for later layers.
+func (l Layer) enforces() bool {
+	return l.Name == LayerSystem || l.Name == LayerGlobal
+}
+
+type enforcement struct {
+	mode  string
+	layer string
+}
+
+// Override is a value a config file set that was ignored, because a layer
+// loaded before it enforces the key.
+type Override struct {
+	Key   string // such as rules.workspace
+	Layer Layer  // the file that set the value
+	Mode  string // locked or minimum
+	By    string // the enforcing layer
+}
+
+// String describes the override in one line.
+func (o Override) String() string {
+	return fmt.Sprintf("%s from %s ignored: %s by %s", o.Key, o.Layer.Path, o.Mode, o.By)
+}
+
+// Layers returns every config file Load considers for the working
+// directory, see LayersFor.
+func Layers() []Layer {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return LayersFor("")
+	}
+	return LayersFor(cwd)
+}
+
+// LayersFor returns every config file that applies to dir, whether it
+// exists or not, in load order: system, global, repository, then one
+// directory layer for each directory below the repository top down to dir.
+// The repository config lies at the top of the git repository around dir,
+// or in dir itself outside a repository. An empty dir only returns the
+// system and global layers.
+func LayersFor(dir string) []Layer {
+	var layers []Layer
+	if systemConfigPath != "" {
+		layers = append(layers, Layer{Name: LayerSystem, Path: systemConfigPath})
+	}
+	if path := globalConfigPath(); path != "" {
+		layers = append(layers, Layer{Name: LayerGlobal, Path: path})
+	}
+	if dir == "" {
+		return layers
+	}
+
+	dir = filepath.Clean(dir)
+	top := RepositoryRoot(dir)
+	if top == "" {
+		top = dir
+	}
+	layers = append(layers, Layer{Name: LayerRepository, Path: filepath.Join(top, ".watchman.yml")})
+
+	var below []Layer
+	for d := dir; d != top; d = filepath.Dir(d) {
+		below = append(below, Layer{Name: LayerDirectory, Path: filepath.Join(d, ".watchman.yml")})
+	}
+	for i := len(below) - 1; i >= 0; i-- {
+		layers = append(layers, below[i])
+	}
+	return layers
+}
+
+// RepositoryRoot returns the closest directory at or above dir holding
+// .git, or empty if there is none.
+func RepositoryRoot(dir string) string {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// existing returns the layers whose file exists.
+func existing(layers []Layer) []Layer {
+	var found []Layer
+	for _, l := range layers {
+		if _, err := os.Stat(l.Path); err == nil {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+// Load loads every config layer of the working directory that exists over
+// the defaults. Each file is validated strictly; a file with errors fails
+// the load.
+func Load() (*Config, error) {
+	return load(existing(Layers()))
+}
+
+// LoadDir is Load for the config layers of dir, the working directory of
+// a tool call.
+func LoadDir(dir string) (*Config, error) {
+	return load(existing(LayersFor(dir)))
+}
+
+// Paths returns the config files Load would read, in load order.
+func Paths() []string {
+	var paths []string
+	for _, l := range existing(Layers()) {
+		paths = append(paths, l.Path)
+	}
+	return paths
+}
+
+// WatchPaths returns every file whose creation, change or removal affects
+// LoadDir, whether it exists or not.
+func WatchPaths(dir string) []string {
+	var paths []string
+	for _, l := range LayersFor(dir) {
+		paths = append(paths, l.Path)
+	}
+	return paths
+}
+
+// LoadFile loads configuration from a single file on top of the defaults,
+// ignoring config discovery.
+func LoadFile(path string) (*Config, error) {
+	return load([]Layer{{Name: LayerFile, Path: path}})
+}
+
+// load merges the layers over the defaults, in order.
+func load(layers []Layer) (*Config, error) {
+	m, err := newMerger()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ""
+	for _, l := range layers {
+		data, err := os.ReadFile(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if diags := Validate(l.Path, data); HasErrors(diags) {
+			var errs []Diagnostic
+			for _, d := range diags {
+				if d.Severity == SeverityError {
+					errs = append(errs, d)
+				}
+			}
+			return nil, &ValidationError{Diagnostics: errs}
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		m.apply(l, &doc)
+
+		sum := sha256.Sum256(append([]byte(hash), data...))
+		hash = hex.EncodeToString(sum[:])
+	}
+
+	cfg := &Config{}
+	if err := m.root.Decode(cfg); err != nil {
+		return nil, err
+	}
+	cfg.hash = hash
+	cfg.layers = layers
+	cfg.merged = m.root
+	cfg.origins = m.origins
+	cfg.enforced = m.enforced
+	cfg.overrides = m.overrides
+	cfg.Directories = m.dirs
+	return cfg, nil
+}
+
+// Hash identifies the content of the loaded config files.
+// Returns empty string when only defaults apply.
+func (c *Config) Hash() string {
+	if len(c.hash) < 16 {
+		return c.hash
+	}
+	return c.hash[:16]
+}
+
+// Layers returns the config files the config was loaded from, in order.
+func (c *Config) Layers() []Layer {
+	return c.layers
+}
+
+// Overrides returns the values config files set that were ignored because
+// an earlier layer enforces them.
+func (c *Config) Overrides() []Override {
+	return c.overrides
+}
+
+// DirectoriesFor returns the directory configs that apply to a path, from
+// the top down. Relative paths are taken from the working directory.
+func (c *Config) DirectoriesFor(path string) []DirectoryConfig {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	var dirs []DirectoryConfig
+	for _, d := range c.Directories {
+		if strings.HasPrefix(abs, d.Dir+string(filepath.Separator)) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// merger merges config documents node by node, so only the keys a file
+// sets override earlier layers:
+//   - mappings merge key by key
+//   - lists add the items they do not have yet; items with a name are
+//     matched by name and the first one stays
+//   - workspace.root and events lists, scalars and values of another kind
+//     replace what was there
+type merger struct {
+	root      *yaml.Node             // the merged mapping
+	origins   map[string]string      // key path -> layer that set the value
+	enforced  map[string]enforcement // dotted key -> floor set by a layer
+	overrides []Override
+	dirs      []DirectoryConfig
+}
+
+func newMerger() (*merger, error) {
+	data, err := yaml.Marshal(Default())
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &merger{
+		root:     doc.Content[0],
+		origins:  map[string]string{"": LayerDefault},
+		enforced: make(map[string]enforcement),
+	}, nil
+}
+
+// apply merges a layer's document and records the floors it enforces.
+func (m *merger) apply(l Layer, doc *yaml.Node) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	overlay := doc.Content[0]
+	if l.Name == LayerDirectory {
+		overlay = m.splitDirectory(l, overlay)
+	}
+	m.mergeMapping(m.root, overlay, nil, l)
+
+	if !l.enforces() {
+		return
+	}
+	var layer struct {
+		Enforce EnforceConfig `yaml:"enforce"`
+	}
+	if err := overlay.Decode(&layer); err != nil {
+		return
+	}
+	for _, key := range layer.Enforce.Minimum {
+		if m.enforced[key].mode != EnforceLocked {
+			m.enforced[key] = enforcement{mode: EnforceMinimum, layer: l.Name}
+		}
+	}
+	for _, key := range layer.Enforce.Locked {
+		m.enforced[key] = enforcement{mode: EnforceLocked, layer: l.Name}
+	}
+}
+
+// splitDirectory takes the scope and invariants out of a directory layer,
+// since they only apply under its directory, and returns the rest.
+func (m *merger) splitDirectory(l Layer, overlay *yaml.Node) *yaml.Node {
+	rest := &yaml.Node{Kind: yaml.MappingNode, Tag: overlay.Tag}
+	local := &yaml.Node{Kind: yaml.MappingNode, Tag: overlay.Tag}
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		switch overlay.Content[i].Value {
+		case "scope", "invariants":
+			local.Content = append(local.Content, overlay.Content[i], overlay.Content[i+1])
+		default:
+			rest.Content = append(rest.Content, overlay.Content[i], overlay.Content[i+1])
+		}
+	}
+
+	if len(local.Content) > 0 {
+		d := DirectoryConfig{Dir: filepath.Dir(l.Path)}
+		if err := local.Decode(&d); err == nil {
+			m.dirs = append(m.dirs, d)
+		}
+	}
+	return rest
+}
+
+func (m *merger) mergeMapping(base, overlay *yaml.Node, p keyPath, l Layer) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if len(p) == 0 && key.Value == "enforce" && !l.enforces() {
+			// Validation warns about it.
+			continue
+		}
+
+		kp := p.with(key.Value)
+		j := mappingIndex(base, key.Value)
+		if j < 0 {
+			if merged := m.mergeValue(nil, value, kp, l); merged != nil {
+				base.Content = append(base.Content, plainCopy(key), merged)
+			}
+			continue
+		}
+		base.Content[j+1] = m.mergeValue(base.Content[j+1], value, kp, l)
+	}
+}
+
+// mergeValue merges value over current, which is nil when no earlier layer
+// set the key, and returns the result.
+func (m *merger) mergeValue(current, value *yaml.Node, p keyPath, l Layer) *yaml.Node {
+	e, enforced := lookupEnforcement(m.enforced, p)
+	if enforced && e.mode == EnforceLocked {
+		m.ignore(p, l, e)
+		return current
+	}
+
+	switch {
+	case current == nil && value.Kind == yaml.MappingNode:
+		// Merged key by key, so the floors of the keys inside apply.
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: value.Tag}
+		m.mergeMapping(merged, value, p, l)
+		return merged
+	case current == nil && enforced:
+		return m.mergeNew(value, p, l, e)
+	case current == nil:
+		m.setOrigin(p, l)
+		return plainCopy(value)
+	case current.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+		m.mergeMapping(current, value, p, l)
+		return current
+	case current.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && !replacesList(p):
+		m.appendItems(current, value, p, l, e, enforced)
+		return current
+	}
+
+	// Scalars, replaced lists and values of another kind are replaced.
+	if enforced && !tightens(current, value, p) {
+		m.ignore(p, l, e)
+		return current
+	}
+	m.setOrigin(p, l)
+	return plainCopy(value)
+}
+
+// mergeNew merges a value no earlier layer set under a minimum: a list
+// grows from an empty one, so each item is checked, and other values must
+// tighten the default they leave unset.
+func (m *merger) mergeNew(value *yaml.Node, p keyPath, l Layer, e enforcement) *yaml.Node {
+	switch {
+	case value.Kind == yaml.SequenceNode && !replacesList(p):
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: value.Tag}
+		m.appendItems(list, value, p, l, e, true)
+		if len(list.Content) == 0 {
+			return nil
+		}
+		return list
+	case tightens(nil, value, p):
+		m.setOrigin(p, l)
+		return plainCopy(value)
+	}
+	m.ignore(p, l, e)
+	return nil
+}
+
+// appendItems adds the items of value that list does not have yet.
+func (m *merger) appendItems(list, value *yaml.Node, p keyPath, l Layer, e enforcement, enforced bool) {
+	seen := make(map[string]bool)
+	for _, item := range list.Content {
+		seen[itemKey(item)] = true
+	}
+	for _, item := range value.Content {
+		key := itemKey(item)
+		if key != "" && seen[key] {
+			continue
+		}
+		if enforced && loosens(p) {
+			m.ignore(p, l, e)
+			continue
+		}
+		seen[key] = true
+		m.setOrigin(p.with(len(list.Content)), l)
+		list.Content = append(list.Content, plainCopy(item))
+		list.Style &^= yaml.FlowStyle
+	}
+}
+
+func (m *merger) setOrigin(p keyPath, l Layer) {
+	key := p.String()
+	for k := range m.origins {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			delete(m.origins, k)
+		}
+	}
+	m.origins[key] = l.Name
+}
+
+func (m *merger) ignore(p keyPath, l Layer, e enforcement) {
+	o := Override{Key: p.String(), Layer: l, Mode: e.mode, By: e.layer}
+	for _, existing := range m.overrides {
+		if existing == o {
+			return
+		}
+	}
+	m.overrides = append(m.overrides, o)
+}
+
+// lookupEnforcement returns the floor that applies to a key: its own or
+// that of a section around it. A lock wins over a minimum.
+func lookupEnforcement(enforced map[string]enforcement, p keyPath) (enforcement, bool) {
+	var found enforcement
+	ok := false
+	key := ""
+	for _, part := range p {
+		name, isKey := part.(string)
+		if !isKey {
+			break
+		}
+		if key != "" {
+			key += "."
+		}
+		key += name
+		if e, has := enforced[key]; has {
+			if e.mode == EnforceLocked {
+				return e, true
+			}
+			found, ok = e, true
+		}
+	}
+	return found, ok
+}
+
+// replacesList reports whether a list replaces the one before it instead
+// of adding to it.
+func replacesList(p keyPath) bool {
+	return p.String() == "workspace.root" || lastKey(p) == "events"
+}
+
+// loosens reports whether setting the key, or adding to it, relaxes policy.
+func loosens(p keyPath) bool {
+	switch lastKey(p) {
+	case "allow", "read_allow", "write_allow", "toolchain_caches", "disabled", "root":
+		return true
+	}
+	return false
+}
+
+// strictness orders the values of the keys that choose how a violation is
+// handled, from the weakest to the strongest, along with the value an
+// unset key stands for.
+var strictness = map[string]struct {
+	values []string
+	unset  string
+}{
+	"mode":          {[]string{ModeAudit, ModeEnforce}, ModeEnforce},
+	"on_violation":  {[]string{ViolationWarn, ViolationAsk, ViolationDeny}, ViolationDeny},
+	"on_unresolved": {[]string{UnresolvedAllow, UnresolvedWarn, UnresolvedDeny}, UnresolvedWarn},
+	"on_error":      {[]string{"allow", "deny"}, "allow"},
+}
+
+// tightens reports whether replacing current with value is allowed under
+// a minimum: a boolean may be turned on, unless that loosens the policy,
+// and the keys in strictness may become stricter. Other values may not
+// change. A nil current is a key no layer set.
+func tightens(current, value *yaml.Node, p keyPath) bool {
+	if loosens(p) || value.Kind != yaml.ScalarNode {
+		return false
+	}
+	if current != nil && current.Kind == yaml.ScalarNode && current.Value == value.Value {
+		return true
+	}
+	if order, ok := strictness[lastKey(p)]; ok {
+		from := order.unset
+		if current != nil && current.Value != "" {
+			from = current.Value
+		}
+		return rank(order.values, value.Value) >= rank(order.values, from)
+	}
+	if value.ShortTag() != "!!bool" || current != nil && current.ShortTag() != "!!bool" {
+		return false
+	}
+	on, _ := strconv.ParseBool(value.Value)
+	return on
+}
+
+// rank returns the position of value in values, -1 when it is not there.
+func rank(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastKey(p keyPath) string {
+	for i := len(p) - 1; i >= 0; i-- {
+		if key, ok := p[i].(string); ok {
+			return key
+		}
+	}
+	return ""
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemKey identifies a list item: named items by name, scalars by value.
+// Other items have no key and are always added.
+func itemKey(item *yaml.Node) string {
+	switch item.Kind {
+	case yaml.ScalarNode:
+		return "value:" + item.Value
+	case yaml.MappingNode:
+		if j := mappingIndex(item, "name"); j >= 0 {
+			return "name:" + item.Content[j+1].Value
+		}
+	}
+	return ""
+}
+
+// plainCopy deep-copies a node without its comments.
+func plainCopy(n *yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.HeadComment, c.LineComment, c.FootComment = "", "", ""
+	c.Anchor = ""
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = plainCopy(child)
+	}
+	return &c
+}
+
+// Effective renders the loaded configuration as YAML, with the layer that
+// set each value next to it, followed by the directory configs and the
+// ignored overrides.
+func (c *Config) Effective() ([]byte, error) {
+	root := c.merged
+	if root == nil {
+		m, err := newMerger()
+		if err != nil {
+			return nil, err
+		}
+		root = m.root
+	}
+	root = plainCopy(root)
+	c.annotate(root, nil)
+
+	var b strings.Builder
+	if len(c.layers) == 0 {
+		b.WriteString("# No config files found, defaults apply.\n")
+	}
+	for _, l := range c.layers {
+		fmt.Fprintf(&b, "# %-10s %s\n", l.Name, l.Path)
+	}
+
+	out, err := encode(root)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(out)
+
+	for _, d := range c.Directories {
+		out, err := encode(d)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "\n# Only for files under %s:\n", d.Dir)
+		for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+			fmt.Fprintf(&b, "#   %s\n", line)
+		}
+	}
+
+	if len(c.overrides) > 0 {
+		b.WriteString("\n# Ignored:\n")
+		for _, o := range c.overrides {
+			fmt.Fprintf(&b, "#   %s\n", o)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// encode renders YAML indented by two spaces, as config files are written.
+func encode(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (c *Config) annotate(node *yaml.Node, p keyPath) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c.annotate(node.Content[i+1], p.with(node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			node.LineComment = c.label(p)
+		}
+		// Comments only fit after items in block style.
+		node.Style &^= yaml.FlowStyle
+		for i, item := range node.Content {
+			c.annotate(item, p.with(i))
+		}
+	default:
+		node.LineComment = c.label(p)
+	}
+}
+
+// label names the layer that set a value and the floor that applies to it.
+func (c *Config) label(p keyPath) string {
+	origin := LayerDefault
+	for i := len(p); i >= 0; i-- {
+		if layer, ok := c.origins[p[:i].String()]; ok {
+			origin = layer
+			break
+		}
+	}
+	if e, ok := lookupEnforcement(c.enforced, p); ok {
+		return origin + ", " + e.mode + " by " + e.layer
+	}
+	return origin
+}
@@ -0,0 +1,653 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/adrianpk/watchman/internal/glob"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found while validating a config file.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+}
+
+// String formats the diagnostic as file:line: severity: message.
+func (d Diagnostic) String() string {
+	loc := d.File
+	if d.Line > 0 {
+		loc += ":" + strconv.Itoa(d.Line)
+	}
+	return loc + ": " + d.Severity + ": " + d.Message
+}
+
+// ValidationError reports the error diagnostics of an invalid config file.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		msgs = append(msgs, d.String())
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// HasErrors reports whether any diagnostic is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile reads and validates a config file.
+func ValidateFile(path string) ([]Diagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(path, data), nil
+}
+
+// Validate checks config content strictly: unknown keys, type mismatches,
+// regexes, globs, hook commands, contradictory options and rule names.
+// The file name is only used to label diagnostics.
+func Validate(file string, data []byte) []Diagnostic {
+	v := &validator{file: file}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		v.add(SeverityError, yamlErrorLine(err.Error()), describeYAMLError(strings.TrimPrefix(err.Error(), "yaml: ")))
+		return v.diags
+	}
+	v.root = &root
+
+	// Known-fields decoding reports unknown keys and type mismatches with lines
+	var strict Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&strict); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			for _, msg := range typeErr.Errors {
+				v.add(SeverityError, yamlErrorLine(msg), describeYAMLError(msg))
+			}
+		} else if !errors.Is(err, io.EOF) {
+			v.add(SeverityError, 0, err.Error())
+		}
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return v.diags
+	}
+
+	v.checkWorkspace(&cfg.Workspace)
+	v.checkScope(&cfg.Scope)
+	v.checkVersioning(&cfg.Versioning)
+	v.checkIncremental(&cfg.Incremental)
+	v.checkInvariants(&cfg.Invariants)
+	v.checkTools(&cfg.Tools)
+	v.checkCommands(&cfg.Commands)
+	v.checkProtected(&cfg.Protected)
+	v.checkHooks(cfg.Hooks)
+	v.checkEnforcement(&cfg)
+	v.checkReminders(cfg.Reminders)
+	v.checkAudit(&cfg.Audit)
+	v.checkEnforce(&cfg.Enforce)
+
+	return v.diags
+}
+
+// keyPath addresses a node in the config document: strings are mapping
+// keys and ints are sequence indexes.
+type keyPath []interface{}
+
+func at(parts ...interface{}) keyPath {
+	return keyPath(parts)
+}
+
+func (p keyPath) String() string {
+	var b strings.Builder
+	for _, part := range p {
+		switch v := part.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, v)
+		}
+	}
+	return b.String()
+}
+
+// with returns a copy of the path extended with more parts.
+func (p keyPath) with(parts ...interface{}) keyPath {
+	out := make(keyPath, 0, len(p)+len(parts))
+	return append(append(out, p...), parts...)
+}
+
+type validator struct {
+	file  string
+	root  *yaml.Node
+	diags []Diagnostic
+}
+
+func (v *validator) add(severity string, line int, msg string) {
+	v.diags = append(v.diags, Diagnostic{File: v.file, Line: line, Severity: severity, Message: msg})
+}
+
+func (v *validator) errorf(p keyPath, format string, args ...interface{}) {
+	v.add(SeverityError, v.line(p), p.String()+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) warnf(p keyPath, format string, args ...interface{}) {
+	v.add(SeverityWarning, v.line(p), p.String()+": "+fmt.Sprintf(format, args...))
+}
+
+// line returns the line of the deepest node found along the path.
+func (v *validator) line(p keyPath) int {
+	if v.root == nil || len(v.root.Content) == 0 {
+		return 0
+	}
+	node := v.root.Content[0]
+	line := node.Line
+
+	for _, part := range p {
+		var next *yaml.Node
+		switch key := part.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					next = node.Content[i+1]
+					line = node.Content[i].Line
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return line
+			}
+			next = node.Content[key]
+			line = next.Line
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
+
+func (v *validator) checkRegex(p keyPath, pattern string) {
+	if pattern == "" {
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		v.errorf(p, "invalid regex: %v", err)
+	}
+}
+
+func (v *validator) checkGlob(p keyPath, pattern string) {
+	if pattern == "" {
+		v.errorf(p, "empty pattern")
+		return
+	}
+	if err := glob.Validate(pattern); err != nil {
+		v.errorf(p, "invalid glob %q: %v", pattern, err)
+	}
+}
+
+func (v *validator) checkGlobs(p keyPath, patterns []string) {
+	for i, pattern := range patterns {
+		v.checkGlob(p.with(i), pattern)
+	}
+}
+
+func (v *validator) checkOverlap(p keyPath, allow, block []string, what string) {
+	blocked := make(map[string]bool)
+	for _, b := range block {
+		blocked[b] = true
+	}
+	for i, a := range allow {
+		if blocked[a] {
+			v.errorf(p.with("allow", i), "%s %q is both allowed and blocked", what, a)
+		}
+	}
+}
+
+// checkName flags empty and duplicate names; merging deduplicates by name,
+// so either would silently drop checks.
+func (v *validator) checkName(p keyPath, name string, seen map[string]bool) {
+	if strings.TrimSpace(name) == "" {
+		v.errorf(p.with("name"), "name is required (entries are merged by name)")
+		return
+	}
+	if seen[name] {
+		v.errorf(p.with("name"), "duplicate name %q", name)
+	}
+	seen[name] = true
+}
+
+func (v *validator) checkWorkspace(cfg *WorkspaceConfig) {
+	v.checkEvents(at("workspace", "events"), cfg.Events, EventPreToolUse, EventPostToolUse)
+	v.checkGlobs(at("workspace", "allow"), cfg.Allow)
+	v.checkGlobs(at("workspace", "read_allow"), cfg.ReadAllow)
+	v.checkGlobs(at("workspace", "write_allow"), cfg.WriteAllow)
+	v.checkGlobs(at("workspace", "block"), cfg.Block)
+	v.checkOverlap(at("workspace"), cfg.Allow, cfg.Block, "path")
+
+	blocked := make(map[string]bool)
+	for _, b := range cfg.Block {
+		blocked[b] = true
+	}
+	writable := make(map[string]bool)
+	for _, w := range cfg.WriteAllow {
+		writable[w] = true
+	}
+	for i, r := range cfg.ReadAllow {
+		if blocked[r] {
+			v.errorf(at("workspace", "read_allow", i), "path %q is both allowed and blocked", r)
+		}
+		if writable[r] {
+			v.warnf(at("workspace", "read_allow", i), "path %q is in read_allow and write_allow, write_allow already grants reading", r)
+		}
+	}
+	for i, w := range cfg.WriteAllow {
+		if blocked[w] {
+			v.errorf(at("workspace", "write_allow", i), "path %q is both allowed and blocked", w)
+		}
+	}
+
+	switch cfg.OnUnresolved {
+	case "", UnresolvedDeny, UnresolvedWarn, UnresolvedAllow:
+	default:
+		v.errorf(at("workspace", "on_unresolved"), "must be deny, warn or allow, got %q", cfg.OnUnresolved)
+	}
+
+	for i, root := range cfg.Root {
+		if strings.TrimSpace(root) == "" {
+			v.errorf(at("workspace", "root", i), "must be cwd, git or a path, got an empty value")
+		}
+	}
+}
+
+func (v *validator) checkScope(cfg *ScopeConfig) {
+	v.checkEvents(at("scope", "events"), cfg.Events, EventPreToolUse, EventPostToolUse)
+	v.checkGlobs(at("scope", "allow"), cfg.Allow)
+	v.checkGlobs(at("scope", "block"), cfg.Block)
+	v.checkOverlap(at("scope"), cfg.Allow, cfg.Block, "pattern")
+}
+
+func (v *validator) checkVersioning(cfg *VersioningConfig) {
+	v.checkEvents(at("versioning", "events"), cfg.Events, EventPreToolUse, EventPostToolUse)
+	commit := cfg.Commit
+	if commit.NoPeriod && commit.RequirePeriod {
+		v.errorf(at("versioning", "commit", "require_period"), "contradicts no_period")
+	}
+	if commit.Conventional && commit.ForbidColons {
+		v.errorf(at("versioning", "commit", "forbid_colons"), "contradicts conventional (conventional commits use a colon)")
+	}
+	if commit.MaxLength < 0 {
+		v.errorf(at("versioning", "commit", "max_length"), "must not be negative")
+	}
+	if commit.MaxFiles < 0 {
+		v.errorf(at("versioning", "commit", "max_files"), "must not be negative")
+	}
+	v.checkRegex(at("versioning", "commit", "prefix_pattern"), commit.PrefixPattern)
+
+	switch cfg.Workflow {
+	case "", "linear", "merge":
+	default:
+		v.errorf(at("versioning", "workflow"), "must be linear, merge or empty, got %q", cfg.Workflow)
+	}
+	switch cfg.Tool {
+	case "", "git", "jj":
+	default:
+		v.errorf(at("versioning", "tool"), "must be git, jj or empty, got %q", cfg.Tool)
+	}
+}
+
+// checkEvents flags event names the rule cannot run on.
+func (v *validator) checkEvents(p keyPath, events []string, supported ...string) {
+	for i, e := range events {
+		ok := false
+		for _, s := range supported {
+			if e == s {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			v.errorf(p.with(i), "unsupported event %q (supported: %s)", e, strings.Join(supported, ", "))
+		}
+	}
+}
+
+func (v *validator) checkIncremental(cfg *IncrementalConfig) {
+	v.checkEvents(at("incremental", "events"), cfg.Events, EventPreToolUse, EventStop)
+	if cfg.MaxFiles < 0 {
+		v.errorf(at("incremental", "max_files"), "must not be negative")
+	}
+	if cfg.WarnRatio < 0 || cfg.WarnRatio >= 1 {
+		v.errorf(at("incremental", "warn_ratio"), "must be between 0 and 1, got %v", cfg.WarnRatio)
+	}
+}
+
+func (v *validator) checkInvariants(cfg *InvariantsConfig) {
+	v.checkEvents(at("invariants", "events"), cfg.Events, EventPreToolUse, EventPostToolUse, EventStop)
+
+	seen := make(map[string]bool)
+	for i, c := range cfg.Coexistence {
+		p := at("invariants", "coexistence", i)
+		v.checkName(p, c.Name, seen)
+		v.checkMode(p.with("mode"), c.Mode)
+		v.checkGlob(p.with("if"), c.If)
+		if c.Require == "" {
+			v.errorf(p.with("require"), "require is required")
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, c := range cfg.Content {
+		p := at("invariants", "content", i)
+		v.checkName(p, c.Name, seen)
+		v.checkMode(p.with("mode"), c.Mode)
+		v.checkGlobs(p.with("paths"), c.Paths)
+		if c.Require == "" && c.Forbid == "" {
+			v.errorf(p, "one of require or forbid is required")
+		}
+		v.checkRegex(p.with("require"), c.Require)
+		v.checkRegex(p.with("forbid"), c.Forbid)
+	}
+
+	seen = make(map[string]bool)
+	for i, c := range cfg.Imports {
+		p := at("invariants", "imports", i)
+		v.checkName(p, c.Name, seen)
+		v.checkMode(p.with("mode"), c.Mode)
+		v.checkGlobs(p.with("paths"), c.Paths)
+		if c.Forbid == "" {
+			v.errorf(p.with("forbid"), "forbid is required")
+		}
+		v.checkRegex(p.with("forbid"), c.Forbid)
+	}
+
+	seen = make(map[string]bool)
+	for i, c := range cfg.Naming {
+		p := at("invariants", "naming", i)
+		v.checkName(p, c.Name, seen)
+		v.checkMode(p.with("mode"), c.Mode)
+		v.checkGlobs(p.with("paths"), c.Paths)
+		if c.Pattern == "" {
+			v.errorf(p.with("pattern"), "pattern is required")
+		}
+		v.checkRegex(p.with("pattern"), c.Pattern)
+	}
+
+	seen = make(map[string]bool)
+	for i, c := range cfg.Required {
+		p := at("invariants", "required", i)
+		v.checkName(p, c.Name, seen)
+		v.checkMode(p.with("mode"), c.Mode)
+		v.checkGlob(p.with("dirs"), c.Dirs)
+		if c.When != "" {
+			v.checkGlob(p.with("when"), c.When)
+		}
+		if c.Require == "" {
+			v.errorf(p.with("require"), "require is required")
+		}
+	}
+}
+
+func (v *validator) checkTools(cfg *ToolsConfig) {
+	blocked := make(map[string]bool)
+	for _, b := range cfg.Block {
+		blocked[strings.ToLower(b)] = true
+	}
+	for i, a := range cfg.Allow {
+		if blocked[strings.ToLower(a)] {
+			v.errorf(at("tools", "allow", i), "tool %q is both allowed and blocked", a)
+		}
+	}
+}
+
+func (v *validator) checkCommands(cfg *CommandsConfig) {
+	v.checkEvents(at("commands", "events"), cfg.Events, EventPreToolUse, EventPostToolUse)
+}
+
+func (v *validator) checkProtected(cfg *ProtectedConfig) {
+	v.checkEvents(at("protected", "events"), cfg.Events, EventPreToolUse, EventPostToolUse)
+	for i, p := range cfg.Paths {
+		if strings.TrimSpace(p) == "" {
+			v.errorf(at("protected", "paths", i), "empty path")
+		}
+	}
+	for i, name := range cfg.Filenames {
+		if name == "" || strings.Contains(name, "/") {
+			v.errorf(at("protected", "filenames", i), "must be a file name, got %q", name)
+		}
+	}
+	v.checkGlobs(at("protected", "globs"), cfg.Globs)
+}
+
+func (v *validator) checkEnforce(cfg *EnforceConfig) {
+	if len(cfg.Locked) == 0 && len(cfg.Minimum) == 0 {
+		return
+	}
+	if file := filepath.Clean(v.file); file != globalConfigPath() && file != systemConfigPath {
+		v.warnf(at("enforce"), "enforce only applies in the system config %s and the global config %s", systemConfigPath, globalConfigPath())
+	}
+	for i, key := range cfg.Locked {
+		if !knownKey(key) {
+			v.errorf(at("enforce", EnforceLocked, i), "unknown config key %q", key)
+		}
+	}
+	for i, key := range cfg.Minimum {
+		if !knownKey(key) {
+			v.errorf(at("enforce", EnforceMinimum, i), "unknown config key %q", key)
+		}
+	}
+}
+
+// knownKey reports whether a dotted key names a config section or option.
+// Keys cannot reach into list items.
+func knownKey(key string) bool {
+	t := reflect.TypeOf(Config{})
+	for _, name := range strings.Split(key, ".") {
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		field, ok := fieldByTag(t, name)
+		if !ok {
+			return false
+		}
+		t = field.Type
+	}
+	return true
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); tag == name && f.IsExported() {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func (v *validator) checkHooks(hooks []HookConfig) {
+	seen := make(map[string]bool)
+	for i, h := range hooks {
+		p := at("hooks", i)
+		v.checkName(p, h.Name, seen)
+
+		switch h.OnError {
+		case "", "allow", "deny":
+		default:
+			v.errorf(p.with("on_error"), "must be allow or deny, got %q", h.OnError)
+		}
+		v.checkViolation(p.with("on_violation"), h.OnViolation)
+		v.checkMode(p.with("mode"), h.Mode)
+
+		if h.Command == "" {
+			v.errorf(p.with("command"), "command is required")
+		} else if _, err := exec.LookPath(h.Command); err != nil {
+			// A missing hook only blocks calls when it fails closed
+			msg := "command %q is not an executable file: %v"
+			if h.OnError == "deny" {
+				v.errorf(p.with("command"), msg, h.Command, err)
+			} else {
+				v.warnf(p.with("command"), msg, h.Command, err)
+			}
+		}
+
+		v.checkEvents(p.with("events"), h.Events, Events...)
+		if len(h.Tools) == 0 && (RunsOn(h.Events, EventPreToolUse) || RunsOn(h.Events, EventPostToolUse)) {
+			v.warnf(p, "no tools configured, hook never runs on tool events")
+		}
+		if h.Timeout < 0 {
+			v.errorf(p.with("timeout"), "must not be negative")
+		}
+		v.checkRegex(p.with("match_command"), h.MatchCommand)
+		v.checkGlobs(p.with("paths"), h.Paths)
+	}
+}
+
+// checkEnforcement checks the on_violation and mode of every rule; hooks
+// and invariant checks are checked with the rest of their settings.
+func (v *validator) checkEnforcement(cfg *Config) {
+	rules := []struct {
+		key         string
+		onViolation string
+		mode        string
+	}{
+		{"tools", cfg.Tools.OnViolation, cfg.Tools.Mode},
+		{"commands", cfg.Commands.OnViolation, cfg.Commands.Mode},
+		{"workspace", cfg.Workspace.OnViolation, cfg.Workspace.Mode},
+		{"scope", cfg.Scope.OnViolation, cfg.Scope.Mode},
+		{"versioning", cfg.Versioning.OnViolation, cfg.Versioning.Mode},
+		{"incremental", cfg.Incremental.OnViolation, cfg.Incremental.Mode},
+		{"invariants", cfg.Invariants.OnViolation, cfg.Invariants.Mode},
+	}
+	for _, r := range rules {
+		v.checkViolation(at(r.key, "on_violation"), r.onViolation)
+		v.checkMode(at(r.key, "mode"), r.mode)
+	}
+}
+
+func (v *validator) checkViolation(p keyPath, value string) {
+	switch value {
+	case "", ViolationDeny, ViolationAsk, ViolationWarn:
+	default:
+		v.errorf(p, "must be deny, ask or warn, got %q", value)
+	}
+}
+
+func (v *validator) checkMode(p keyPath, value string) {
+	switch value {
+	case "", ModeEnforce, ModeAudit:
+	default:
+		v.errorf(p, "must be enforce or audit, got %q", value)
+	}
+}
+
+func (v *validator) checkReminders(reminders []ReminderConfig) {
+	seen := make(map[string]bool)
+	for i, r := range reminders {
+		p := at("reminders", i)
+		v.checkName(p, r.Name, seen)
+		switch {
+		case r.Message == "" && r.MessageFile == "":
+			v.errorf(p.with("message"), "message or message_file is required")
+		case r.Message != "" && r.MessageFile != "":
+			v.errorf(p.with("message_file"), "set either message or message_file")
+		}
+		if r.Section != "" && r.MessageFile == "" {
+			v.errorf(p.with("section"), "requires message_file")
+		}
+		if r.EveryTasks < 0 || r.EveryMinutes < 0 || r.SameFile < 0 {
+			v.errorf(p, "every_tasks, every_minutes and same_file must not be negative")
+		}
+		v.checkGlobs(p.with("paths"), r.Paths)
+
+		switch r.On {
+		case ReminderOnTool:
+			if r.EveryTasks == 0 && r.EveryMinutes == 0 && r.SameFile == 0 && len(r.Tools) == 0 && len(r.Paths) == 0 {
+				v.warnf(p, "no condition is set, reminder never triggers")
+			}
+		case ReminderOnSessionStart, ReminderOnCommit, ReminderOnDeny:
+			if r.EveryMinutes != 0 || r.SameFile != 0 || len(r.Tools) != 0 || len(r.Paths) != 0 {
+				v.errorf(p.with("on"), "every_minutes, same_file, tools and paths only apply to tool calls")
+			}
+		default:
+			v.errorf(p.with("on"), "must be session_start, commit, deny or empty")
+		}
+	}
+}
+
+func (v *validator) checkAudit(cfg *AuditConfig) {
+	if cfg.MaxSizeMB < 0 {
+		v.errorf(at("audit", "max_size_mb"), "must not be negative")
+	}
+	if cfg.MaxFiles < 0 {
+		v.errorf(at("audit", "max_files"), "must not be negative")
+	}
+	if cfg.MaxAgeDays < 0 {
+		v.errorf(at("audit", "max_age_days"), "must not be negative")
+	}
+}
+
+var (
+	yamlLinePattern     = regexp.MustCompile(`line (\d+)`)
+	unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type config\.(\w+)`)
+)
+
+// yamlErrorLine extracts the line number from a yaml error message.
+func yamlErrorLine(msg string) int {
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// describeYAMLError rewrites yaml decoder messages without the line prefix.
+func describeYAMLError(msg string) string {
+	if m := unknownFieldPattern.FindStringSubmatch(msg); m != nil {
+		return "unknown key " + strconv.Quote(m[1]) + " in " + m[2]
+	}
+	if idx := strings.Index(msg, ": "); idx != -1 && strings.HasPrefix(msg, "line ") {
+		return msg[idx+2:]
+	}
+	return msg
+}
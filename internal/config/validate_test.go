@@ -0,0 +1,323 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateValid(t *testing.T) {
+	content := `
+version: 1
+rules:
+  workspace: true
+  invariants: true
+scope:
+  allow: ["src/**/*.go"]
+invariants:
+  content:
+    - name: no-todos
+      paths: ["**/*.go", "!**/*_test.go"]
+      forbid: "TODO|FIXME"
+hooks:
+  - name: shell
+    command: sh
+    tools: [Write]
+    match_command: "^go "
+reminders:
+  - name: agents
+    message: re-read AGENTS.md
+    every_tasks: 10
+  - name: conventions
+    message_file: CONVENTIONS.md
+    section: Testing
+    tools: [Write, Edit]
+    paths: ["**/*_test.go"]
+    same_file: 3
+  - name: after-commit
+    message: "committed on ${branch}, ${modified_files} files modified"
+    on: commit
+`
+	diags := Validate("a.yml", []byte(content))
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestValidateEmpty(t *testing.T) {
+	if diags := Validate("a.yml", nil); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestValidateDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		want    string
+	}{
+		{
+			name:    "unknown key",
+			content: "rules:\n  workspace: true\n  scopes: true\n",
+			line:    3,
+			want:    `unknown key "scopes"`,
+		},
+		{
+			name:    "type mismatch",
+			content: "incremental:\n  max_files: many\n",
+			line:    2,
+			want:    "cannot unmarshal",
+		},
+		{
+			name:    "syntax error",
+			content: "rules:\n  workspace: true\n bad indent\n",
+			line:    2,
+			want:    "did not find expected key",
+		},
+		{
+			name:    "invalid content regex",
+			content: "invariants:\n  content:\n    - name: x\n      paths: [\"*.go\"]\n      forbid: \"(unclosed\"\n",
+			line:    5,
+			want:    "invariants.content[0].forbid: invalid regex",
+		},
+		{
+			name:    "invalid naming regex",
+			content: "invariants:\n  naming:\n    - name: x\n      pattern: \"[a-\"\n",
+			line:    4,
+			want:    "invariants.naming[0].pattern: invalid regex",
+		},
+		{
+			name:    "invalid match_command",
+			content: "hooks:\n  - name: h\n    command: sh\n    tools: [Bash]\n    match_command: \"*go\"\n",
+			line:    5,
+			want:    "hooks[0].match_command: invalid regex",
+		},
+		{
+			name:    "invalid glob",
+			content: "scope:\n  allow:\n    - src/**\n    - \"[a-\"\n",
+			line:    4,
+			want:    "scope.allow[1]: invalid glob",
+		},
+		{
+			name:    "protected filename with slash",
+			content: "protected:\n  filenames:\n    - secrets/key\n",
+			line:    3,
+			want:    "protected.filenames[0]: must be a file name",
+		},
+		{
+			name:    "protected locked",
+			content: "protected:\n  paths: [~/.kube/]\n  locked: true\n",
+			line:    3,
+			want:    `unknown key "locked"`,
+		},
+		{
+			name:    "enforce unknown key",
+			content: "enforce:\n  minimum:\n    - rules\n    - rules.workspaces\n",
+			line:    4,
+			want:    `enforce.minimum[1]: unknown config key "rules.workspaces"`,
+		},
+		{
+			name:    "unclosed brace",
+			content: "workspace:\n  block:\n    - \"*.{pem,key\"\n",
+			line:    3,
+			want:    "workspace.block[0]: invalid glob",
+		},
+		{
+			name:    "contradicting period options",
+			content: "versioning:\n  commit:\n    no_period: true\n    require_period: true\n",
+			line:    4,
+			want:    "contradicts no_period",
+		},
+		{
+			name:    "empty name",
+			content: "invariants:\n  imports:\n    - paths: [\"*.go\"]\n      forbid: x\n",
+			line:    3,
+			want:    "name is required",
+		},
+		{
+			name:    "duplicate name",
+			content: "reminders:\n  - name: r\n    message: a\n    every_tasks: 1\n  - name: r\n    message: b\n    every_tasks: 2\n",
+			line:    5,
+			want:    `duplicate name "r"`,
+		},
+		{
+			name:    "missing hook command fails closed",
+			content: "hooks:\n  - name: h\n    command: /nonexistent/hook\n    tools: [Write]\n    on_error: deny\n",
+			line:    3,
+			want:    "is not an executable file",
+		},
+		{
+			name:    "tool allowed and blocked",
+			content: "tools:\n  allow: [Read, Bash]\n  block: [bash]\n",
+			line:    2,
+			want:    `tool "Bash" is both allowed and blocked`,
+		},
+		{
+			name:    "read_allow blocked",
+			content: "workspace:\n  read_allow: [/opt/sdk]\n  block: [/opt/sdk]\n",
+			line:    2,
+			want:    `workspace.read_allow[0]: path "/opt/sdk" is both allowed and blocked`,
+		},
+		{
+			name:    "empty root",
+			content: "workspace:\n  root:\n    - git\n    - \"\"\n",
+			line:    4,
+			want:    "workspace.root[1]: must be cwd, git or a path",
+		},
+		{
+			name:    "root mapping",
+			content: "workspace:\n  root:\n    git: true\n",
+			line:    3,
+			want:    "cannot unmarshal",
+		},
+		{
+			name:    "bad on_unresolved",
+			content: "workspace:\n  on_unresolved: ask\n",
+			line:    2,
+			want:    "must be deny, warn or allow",
+		},
+		{
+			name:    "bad workflow",
+			content: "versioning:\n  workflow: squash\n",
+			line:    2,
+			want:    "must be linear, merge or empty",
+		},
+		{
+			name:    "reminder without message",
+			content: "reminders:\n  - name: r\n    every_tasks: 1\n",
+			line:    2,
+			want:    "message or message_file is required",
+		},
+		{
+			name:    "reminder section without file",
+			content: "reminders:\n  - name: r\n    message: m\n    section: Testing\n",
+			line:    4,
+			want:    "requires message_file",
+		},
+		{
+			name:    "unknown reminder condition",
+			content: "reminders:\n  - name: r\n    message: m\n    on: push\n",
+			line:    4,
+			want:    "must be session_start, commit, deny or empty",
+		},
+		{
+			name:    "same_file on commit",
+			content: "reminders:\n  - name: r\n    message: m\n    on: commit\n    same_file: 2\n",
+			line:    4,
+			want:    "only apply to tool calls",
+		},
+		{
+			name:    "bad on_violation",
+			content: "scope:\n  allow: [\"src/**\"]\n  on_violation: block\n",
+			line:    3,
+			want:    "scope.on_violation: must be deny, ask or warn",
+		},
+		{
+			name:    "bad hook on_violation",
+			content: "hooks:\n  - name: h\n    command: sh\n    tools: [Write]\n    on_violation: allow\n",
+			line:    5,
+			want:    "hooks[0].on_violation: must be deny, ask or warn",
+		},
+		{
+			name:    "bad mode",
+			content: "versioning:\n  mode: dry-run\n",
+			line:    2,
+			want:    "versioning.mode: must be enforce or audit",
+		},
+		{
+			name:    "bad invariant check mode",
+			content: "invariants:\n  naming:\n    - name: n\n      paths: [\"*.go\"]\n      pattern: \"^[a-z_]+\\\\.go$\"\n      mode: shadow\n",
+			line:    6,
+			want:    "invariants.naming[0].mode: must be enforce or audit",
+		},
+		{
+			name:    "unsupported scope event",
+			content: "scope:\n  events:\n    - PostToolUse\n    - Stop\n",
+			line:    4,
+			want:    `scope.events[1]: unsupported event "Stop"`,
+		},
+		{
+			name:    "unsupported invariants event",
+			content: "invariants:\n  events:\n    - PostToolUse\n    - SessionStart\n",
+			line:    4,
+			want:    `invariants.events[1]: unsupported event "SessionStart"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := Validate("cfg.yml", []byte(tt.content))
+			if !HasErrors(diags) {
+				t.Fatalf("expected errors, got %v", diags)
+			}
+
+			var found *Diagnostic
+			for i := range diags {
+				if strings.Contains(diags[i].Message, tt.want) {
+					found = &diags[i]
+					break
+				}
+			}
+			if found == nil {
+				t.Fatalf("no diagnostic contains %q: %v", tt.want, diags)
+			}
+			if found.Line != tt.line {
+				t.Errorf("line = %d, want %d (%s)", found.Line, tt.line, found)
+			}
+			if !strings.HasPrefix(found.String(), "cfg.yml:") {
+				t.Errorf("unexpected format: %s", found)
+			}
+		})
+	}
+}
+
+func TestValidateWarnings(t *testing.T) {
+	content := "hooks:\n  - name: h\n    command: /nonexistent/hook\n    tools: [Write]\n"
+	diags := Validate("cfg.yml", []byte(content))
+
+	if HasErrors(diags) {
+		t.Errorf("missing hook that fails open should only warn: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != SeverityWarning {
+		t.Errorf("expected one warning, got %v", diags)
+	}
+}
+
+func TestValidateEnforceOutsideGlobal(t *testing.T) {
+	content := "enforce:\n  locked: [rules.workspace]\n"
+	diags := Validate(".watchman.yml", []byte(content))
+
+	if len(diags) != 1 || diags[0].Severity != SeverityWarning || !strings.Contains(diags[0].Message, "enforce only applies") {
+		t.Errorf("expected one warning about enforce, got %v", diags)
+	}
+}
+
+func TestValidateReadAndWriteAllow(t *testing.T) {
+	content := "workspace:\n  read_allow: [/opt/sdk/]\n  write_allow: [/opt/sdk/]\n"
+	diags := Validate("cfg.yml", []byte(content))
+
+	if len(diags) != 1 || diags[0].Severity != SeverityWarning || !strings.Contains(diags[0].Message, "already grants reading") {
+		t.Errorf("expected one warning about the redundant read_allow, got %v", diags)
+	}
+}
+
+func TestLoadFromRejectsInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := "invariants:\n  content:\n    - name: x\n      forbid: \"(\"\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected *ValidationError, got %T", err)
+	}
+	if !strings.Contains(err.Error(), path+":4") {
+		t.Errorf("error should point at file and line: %v", err)
+	}
+}
@@ -3,71 +3,37 @@ package glob
 import (
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
-// Match matches a path against a glob pattern.
-// Supports ** for recursive directory matching.
-func Match(path, pattern string) bool {
-	path = filepath.Clean(path)
-	pattern = filepath.Clean(pattern)
-
-	if strings.Contains(pattern, "**") {
-		return matchDoublestar(path, pattern)
-	}
-
-	matched, _ := filepath.Match(pattern, path)
-	if matched {
-		return true
-	}
-
-	matched, _ = filepath.Match(pattern, filepath.Base(path))
-	return matched
-}
-
-// matchDoublestar handles ** glob patterns.
-func matchDoublestar(path, pattern string) bool {
-	parts := strings.Split(pattern, "**")
-	if len(parts) != 2 {
-		return false
-	}
-
-	prefix := strings.TrimSuffix(parts[0], string(filepath.Separator))
-	suffix := strings.TrimPrefix(parts[1], string(filepath.Separator))
+// compiled caches the matchers of patterns seen by Match, since patterns
+// come from the config and are matched against many paths.
+var compiled sync.Map // pattern -> *Matcher, or nil when malformed
 
-	if prefix != "" && !strings.HasPrefix(path, prefix) {
+// Match matches a path against a glob pattern, see Matcher for the syntax.
+// Both are cleaned first, and a pattern that does not match the whole path
+// is tried on its base name, so *.go matches src/main.go. Malformed patterns
+// match nothing.
+func Match(path, pattern string) bool {
+	m := cached(filepath.ToSlash(filepath.Clean(pattern)))
+	if m == nil {
 		return false
 	}
 
-	if suffix == "" {
-		return true
-	}
-
-	remaining := path
-	if prefix != "" {
-		remaining = strings.TrimPrefix(path, prefix)
-		remaining = strings.TrimPrefix(remaining, string(filepath.Separator))
-	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	return m.Match(path) || m.Match(filepath.Base(path))
+}
 
-	if suffix == "" {
-		return true
+func cached(pattern string) *Matcher {
+	if m, ok := compiled.Load(pattern); ok {
+		return m.(*Matcher)
 	}
-
-	pathParts := strings.Split(remaining, string(filepath.Separator))
-	for i := range pathParts {
-		candidate := strings.Join(pathParts[i:], string(filepath.Separator))
-		matched, _ := filepath.Match(suffix, candidate)
-		if matched {
-			return true
-		}
-		if len(pathParts[i:]) == 1 {
-			matched, _ = filepath.Match(suffix, pathParts[len(pathParts)-1])
-			if matched {
-				return true
-			}
-		}
+	m, err := Compile(pattern)
+	if err != nil {
+		m = nil
 	}
-
-	return false
+	compiled.Store(pattern, m)
+	return m
 }
 
 // MatchAny returns true if the path matches any of the patterns.
@@ -79,3 +45,10 @@ func MatchAny(path string, patterns []string) bool {
 	}
 	return false
 }
+
+// Validate reports whether a pattern is well formed.
+// A leading ! (exclusion) is accepted and ignored.
+func Validate(pattern string) error {
+	_, err := Compile(strings.TrimPrefix(pattern, "!"))
+	return err
+}
@@ -44,13 +44,18 @@ func TestMatchDoublestar(t *testing.T) {
 		{"no match wrong prefix", "external/hook/eval.go", "internal/**/*.go", false},
 		{"match everything", "any/path/here", "**", true},
 		{"match directory", "vendor/lib/code.go", "vendor/**", true},
+		{"match directory itself", "vendor", "vendor/**", true},
+		{"no match sibling", "vendorx/code.go", "vendor/**", false},
+		{"two doublestars", "src/a/testdata/b/c.json", "src/**/testdata/**/*.json", true},
+		{"two doublestars empty", "src/testdata/c.json", "src/**/testdata/**/*.json", true},
+		{"two doublestars no match", "src/a/data/c.json", "src/**/testdata/**/*.json", false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := matchDoublestar(tt.path, tt.pattern)
+			got := Match(tt.path, tt.pattern)
 			if got != tt.want {
-				t.Errorf("matchDoublestar(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
 			}
 		})
 	}
@@ -102,3 +107,30 @@ func TestMatchAny(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"*.go", true},
+		{"src/**/*.go", true},
+		{"!vendor/**", true},
+		{"[a-z]*.md", true},
+		{"[a-", false},
+		{"src/**/[", false},
+		{"*.{go,mod}", true},
+		{"*.{go,mod", false},
+		{`\*`, true},
+		{`a\`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := Validate(tt.pattern)
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate(%q) = %v, want valid=%v", tt.pattern, err, tt.valid)
+			}
+		})
+	}
+}
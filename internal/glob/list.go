@@ -0,0 +1,112 @@
+package glob
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// List is an ordered list of gitignore-style patterns.
+//
+// A pattern with a slash at the start or in the middle is anchored: it
+// matches from the start of the path. Any other pattern floats and matches
+// a name at any depth. A pattern ending in / only matches directories, and
+// a pattern that matches a directory also matches everything inside it.
+// A pattern starting with ! re-includes what earlier patterns matched; the
+// last pattern that matches a path decides.
+//
+// Unlike .gitignore, a leading / makes a pattern absolute, matching paths
+// from the filesystem root, since lists name paths outside the project too.
+// Use ./ to anchor a single name to the start of a relative path.
+//
+// Patterns use the Matcher syntax. Malformed patterns are ignored; config
+// validation reports them.
+type List struct {
+	rules []rule
+}
+
+type rule struct {
+	matcher  *Matcher
+	negate   bool
+	dirOnly  bool // the pattern ended in /
+	contents bool // the pattern ended in /**: only what is inside matches
+}
+
+// NewList parses patterns into a list. Empty patterns are ignored.
+func NewList(patterns []string) *List {
+	l := &List{}
+	for _, p := range patterns {
+		if r, ok := parseRule(p); ok {
+			l.rules = append(l.rules, r)
+		}
+	}
+	return l
+}
+
+func parseRule(pattern string) (rule, bool) {
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	}
+	if len(pattern) > 1 && strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if strings.HasSuffix(pattern, "/**") {
+		r.contents = true
+		pattern = strings.TrimSuffix(pattern, "/**")
+	}
+	if pattern == "" {
+		return r, false
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "/"):
+		pattern = cleanPath(pattern)
+	case strings.HasPrefix(pattern, "./"):
+		pattern = cleanPath(strings.TrimLeft(pattern[2:], "/"))
+	case strings.Contains(pattern, "/"):
+		pattern = cleanPath(pattern)
+	default:
+		pattern = "**/" + pattern
+	}
+
+	r.matcher = cached(pattern)
+	return r, r.matcher != nil
+}
+
+// Match reports whether the path matches the list. isDir tells whether the
+// path itself is a directory, for patterns ending in /. Relative paths are
+// matched as given; absolute paths only match floating and absolute patterns.
+func (l *List) Match(path string, isDir bool) bool {
+	path = cleanPath(path)
+	matched := false
+	for _, r := range l.rules {
+		if r.negate != matched {
+			// This rule cannot change the outcome.
+			continue
+		}
+		if r.match(path, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// match tries the rule on the path and on each directory above it.
+func (r rule) match(path string, isDir bool) bool {
+	for i := 1; i < len(path); i++ {
+		// The part before each separator is a directory containing the path.
+		if path[i] == '/' && r.matcher.Match(path[:i]) {
+			return true
+		}
+	}
+	if r.contents || (r.dirOnly && !isDir) {
+		return false
+	}
+	return r.matcher.Match(path)
+}
+
+func cleanPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,51 @@
+package glob
+
+import "testing"
+
+func TestListMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"floating name", []string{".env"}, ".env", false, true},
+		{"floating name nested", []string{".env"}, "config/.env", false, true},
+		{"floating name absolute", []string{".env"}, "/home/me/project/.env", false, true},
+		{"floating glob", []string{".env.*"}, "deploy/.env.production", false, true},
+		{"floating extension", []string{"*.pem"}, "certs/server.pem", false, true},
+		{"floating no partial", []string{".env"}, ".envrc", false, false},
+		{"inside matched directory", []string{"secrets"}, "app/secrets/key.pem", false, true},
+		{"anchored", []string{"build/out"}, "build/out/a.o", false, true},
+		{"anchored not nested", []string{"build/out"}, "src/build/out", false, false},
+		{"anchored dot slash", []string{"./build"}, "build/a.o", false, true},
+		{"anchored dot slash not nested", []string{"./build"}, "src/build", false, false},
+		{"anchored relative vs absolute", []string{"src/main.go"}, "/src/main.go", false, false},
+		{"absolute", []string{"/tmp"}, "/tmp/x/y", false, true},
+		{"absolute exact", []string{"/etc/passwd"}, "/etc/passwd", false, true},
+		{"absolute no partial", []string{"/etc/ssh"}, "/etc/sshd", false, false},
+		{"absolute vs relative", []string{"/tmp"}, "tmp/x", false, false},
+		{"directory only file", []string{"logs/"}, "logs", false, false},
+		{"directory only dir", []string{"logs/"}, "logs", true, true},
+		{"directory only contents", []string{"logs/"}, "a/logs/today.txt", false, true},
+		{"doublestar middle", []string{"src/**/gen"}, "src/a/b/gen/x.go", false, true},
+		{"doublestar zero", []string{"src/**/gen"}, "src/gen", true, true},
+		{"doublestar leading", []string{"**/fixtures/*.json"}, "a/b/fixtures/x.json", false, true},
+		{"doublestar contents", []string{"vendor/**"}, "vendor/lib/x.go", false, true},
+		{"doublestar contents not dir itself", []string{"vendor/**"}, "vendor", true, false},
+		{"negation", []string{".env*", "!.env.example"}, ".env.example", false, false},
+		{"negation other", []string{".env*", "!.env.example"}, ".env.local", false, true},
+		{"negation then match", []string{"*.key", "!public.key", "keys/"}, "keys/public.key", false, true},
+		{"no patterns", nil, "a", false, false},
+		{"empty pattern", []string{""}, "a", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewList(tt.patterns).Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("NewList(%q).Match(%q, %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package glob
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBadPattern reports a malformed pattern.
+var ErrBadPattern = errors.New("syntax error in pattern")
+
+// maxAlternatives bounds how many patterns a brace expression may expand to.
+const maxAlternatives = 1024
+
+// Matcher is a compiled glob pattern. It is safe for concurrent use.
+//
+// Paths and patterns use / as the separator. In a pattern:
+//   - * matches any run of characters except /
+//   - ? matches any single character except /
+//   - [abc] matches one character in the class; ranges like [a-z] are
+//     allowed, [!abc] or [^abc] negate, and ] right after [ or [! is literal
+//   - {a,b} matches either alternative; braces nest and may contain /
+//   - \x matches the character x literally
+//   - ** as a whole segment matches any number of segments, including none:
+//     a/**/b matches a/b and a/x/y/b, and a/** matches a and everything below
+//   - ** inside a segment matches any run of characters including /:
+//     src/**_test.go matches src/a/b_test.go
+//
+// A pattern matches the whole path, never part of it. Classes, ? and * never
+// match /.
+type Matcher struct {
+	pattern string
+	alts    [][]token
+}
+
+type tokenKind int
+
+const (
+	tokenLiteral  tokenKind = iota
+	tokenAny                // ?
+	tokenClass              // [...]
+	tokenStar               // *
+	tokenSuper              // ** inside a segment
+	tokenSegments           // **/ : zero or more segments with their /
+	tokenRest               // trailing /** : nothing, or / and anything
+)
+
+type token struct {
+	kind    tokenKind
+	literal string
+	class   *charClass
+}
+
+type charClass struct {
+	negate bool
+	ranges []runeRange
+}
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// Compile parses a pattern into a Matcher.
+func Compile(pattern string) (*Matcher, error) {
+	count := 0
+	expanded, err := expandBraces(pattern, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Matcher{pattern: pattern}
+	for _, alt := range expanded {
+		tokens, err := tokenize(alt)
+		if err != nil {
+			return nil, err
+		}
+		m.alts = append(m.alts, tokens)
+	}
+	return m, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is malformed.
+func MustCompile(pattern string) *Matcher {
+	m, err := Compile(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("glob: Compile(%q): %v", pattern, err))
+	}
+	return m
+}
+
+// String returns the source pattern.
+func (m *Matcher) String() string {
+	return m.pattern
+}
+
+// Match reports whether the whole path matches the pattern. The path is
+// matched as given; use Match to clean it first.
+func (m *Matcher) Match(path string) bool {
+	for _, tokens := range m.alts {
+		mt := matching{tokens: tokens, path: path}
+		if mt.match(0, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// matching holds the state of one match. Failed (token, offset) pairs are
+// remembered, so stars cannot make matching exponential.
+type matching struct {
+	tokens []token
+	path   string
+	failed map[[2]int]bool
+}
+
+func (mt *matching) match(ti, pi int) bool {
+	for ti < len(mt.tokens) {
+		t := mt.tokens[ti]
+		switch t.kind {
+		case tokenLiteral:
+			if !strings.HasPrefix(mt.path[pi:], t.literal) {
+				return false
+			}
+			pi += len(t.literal)
+		case tokenAny, tokenClass:
+			if pi >= len(mt.path) {
+				return false
+			}
+			r, size := utf8.DecodeRuneInString(mt.path[pi:])
+			if r == '/' || (t.kind == tokenClass && !t.class.matches(r)) {
+				return false
+			}
+			pi += size
+		case tokenRest:
+			return pi == len(mt.path) || mt.path[pi] == '/'
+		default:
+			return mt.matchRun(ti, pi)
+		}
+		ti++
+	}
+	return pi == len(mt.path)
+}
+
+// matchRun matches a star token: either it ends here, or it takes the next
+// character (a whole segment for **/) and tries again.
+func (mt *matching) matchRun(ti, pi int) bool {
+	key := [2]int{ti, pi}
+	if mt.failed[key] {
+		return false
+	}
+	if mt.match(ti+1, pi) {
+		return true
+	}
+
+	next := -1
+	switch rest := mt.path[pi:]; mt.tokens[ti].kind {
+	case tokenStar:
+		if rest != "" && rest[0] != '/' {
+			next = pi + 1
+		}
+	case tokenSuper:
+		if rest != "" {
+			next = pi + 1
+		}
+	case tokenSegments:
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			next = pi + i + 1
+		}
+	}
+	if next >= 0 && mt.matchRun(ti, next) {
+		return true
+	}
+
+	if mt.failed == nil {
+		mt.failed = make(map[[2]int]bool)
+	}
+	mt.failed[key] = true
+	return false
+}
+
+func (c *charClass) matches(r rune) bool {
+	for _, rg := range c.ranges {
+		if rg.lo <= r && r <= rg.hi {
+			return !c.negate
+		}
+	}
+	return c.negate
+}
+
+// expandBraces returns the brace-free patterns a pattern stands for.
+func expandBraces(pattern string, count *int) ([]string, error) {
+	open, closing, commas, err := findBraces(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if open < 0 {
+		*count++
+		if *count > maxAlternatives {
+			return nil, fmt.Errorf("%w: more than %d brace alternatives", ErrBadPattern, maxAlternatives)
+		}
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:open], pattern[closing+1:]
+	start := open + 1
+	var expanded []string
+	for _, end := range append(commas, closing) {
+		alts, err := expandBraces(prefix+pattern[start:end]+suffix, count)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, alts...)
+		start = end + 1
+	}
+	return expanded, nil
+}
+
+// findBraces locates the first top-level brace expression, returning the
+// offsets of its braces and of the commas between its alternatives. open is
+// -1 when there is none.
+func findBraces(pattern string) (open, closing int, commas []int, err error) {
+	open = -1
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			if end := classEnd(pattern, i); end > 0 {
+				i = end
+			}
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return open, i, commas, nil
+			}
+		}
+	}
+	if depth > 0 {
+		return -1, -1, nil, fmt.Errorf("%w: unclosed {", ErrBadPattern)
+	}
+	return -1, -1, nil, nil
+}
+
+// classEnd returns the offset of the ] closing the class that starts at i,
+// or -1 if it is not closed.
+func classEnd(pattern string, i int) int {
+	j := i + 1
+	if j < len(pattern) && (pattern[j] == '!' || pattern[j] == '^') {
+		j++
+	}
+	if j < len(pattern) && pattern[j] == ']' {
+		j++
+	}
+	for ; j < len(pattern); j++ {
+		switch pattern[j] {
+		case '\\':
+			j++
+		case ']':
+			return j
+		}
+	}
+	return -1
+}
+
+// tokenize compiles a brace-free pattern.
+func tokenize(pattern string) ([]token, error) {
+	segments := splitSegments(pattern)
+	last := len(segments) - 1
+
+	var tokens []token
+	for i, seg := range segments {
+		if seg == "**" {
+			switch {
+			case i < last:
+				tokens = append(tokens, token{kind: tokenSegments})
+			case i == 0:
+				tokens = append(tokens, token{kind: tokenSuper})
+			}
+			// Otherwise the final ** was taken by the segment before it.
+			continue
+		}
+
+		segTokens, err := tokenizeSegment(seg)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, segTokens...)
+
+		switch {
+		case i == last:
+		case i+1 == last && segments[last] == "**":
+			tokens = append(tokens, token{kind: tokenRest})
+		default:
+			tokens = append(tokens, token{kind: tokenLiteral, literal: "/"})
+		}
+	}
+	return mergeLiterals(tokens), nil
+}
+
+// splitSegments splits a pattern on the separators that are not escaped
+// or inside a class.
+func splitSegments(pattern string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			if end := classEnd(pattern, i); end > 0 {
+				i = end
+			}
+		case '/':
+			segments = appendSegment(segments, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return appendSegment(segments, pattern[start:])
+}
+
+// appendSegment appends a segment, folding repeated ** segments into one.
+func appendSegment(segments []string, seg string) []string {
+	if seg == "**" && len(segments) > 0 && segments[len(segments)-1] == "**" {
+		return segments
+	}
+	return append(segments, seg)
+}
+
+func tokenizeSegment(seg string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(seg); {
+		switch seg[i] {
+		case '*':
+			j := i
+			for j < len(seg) && seg[j] == '*' {
+				j++
+			}
+			kind := tokenStar
+			if j-i > 1 {
+				kind = tokenSuper
+			}
+			tokens = append(tokens, token{kind: kind})
+			i = j
+		case '?':
+			tokens = append(tokens, token{kind: tokenAny})
+			i++
+		case '[':
+			class, n, err := parseClass(seg[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenClass, class: class})
+			i += n
+		case '\\':
+			if i+1 >= len(seg) {
+				return nil, fmt.Errorf("%w: trailing \\", ErrBadPattern)
+			}
+			_, size := utf8.DecodeRuneInString(seg[i+1:])
+			tokens = append(tokens, token{kind: tokenLiteral, literal: seg[i+1 : i+1+size]})
+			i += 1 + size
+		default:
+			tokens = append(tokens, token{kind: tokenLiteral, literal: seg[i : i+1]})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// parseClass parses the class at the start of s and returns its length.
+func parseClass(s string) (*charClass, int, error) {
+	class := &charClass{}
+	i := 1
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		class.negate = true
+		i++
+	}
+
+	first := true
+	for {
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("%w: unclosed [", ErrBadPattern)
+		}
+		if s[i] == ']' && !first {
+			return class, i + 1, nil
+		}
+		first = false
+
+		lo, n, err := classRune(s[i:])
+		if err != nil {
+			return nil, 0, err
+		}
+		i += n
+		hi := lo
+		if i+1 < len(s) && s[i] == '-' && s[i+1] != ']' {
+			hi, n, err = classRune(s[i+1:])
+			if err != nil {
+				return nil, 0, err
+			}
+			i += 1 + n
+		}
+		class.ranges = append(class.ranges, runeRange{lo, hi})
+	}
+}
+
+func classRune(s string) (rune, int, error) {
+	n := 0
+	if s[0] == '\\' {
+		n = 1
+		if len(s) < 2 {
+			return 0, 0, fmt.Errorf("%w: unclosed [", ErrBadPattern)
+		}
+	}
+	r, size := utf8.DecodeRuneInString(s[n:])
+	return r, n + size, nil
+}
+
+func mergeLiterals(tokens []token) []token {
+	var merged []token
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind != tokenLiteral {
+			merged = append(merged, tokens[i])
+			continue
+		}
+		var b strings.Builder
+		for ; i < len(tokens) && tokens[i].kind == tokenLiteral; i++ {
+			b.WriteString(tokens[i].literal)
+		}
+		merged = append(merged, token{kind: tokenLiteral, literal: b.String()})
+		i--
+	}
+	return merged
+}
@@ -0,0 +1,213 @@
+package glob
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Stars and single characters
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"*", "", true},
+		{"?.go", "a.go", true},
+		{"?.go", "ab.go", false},
+		{"a?b", "a/b", false},
+		{"é?", "éñ", true},
+
+		// Classes
+		{"[abc].go", "b.go", true},
+		{"[abc].go", "d.go", false},
+		{"[a-c]x", "bx", true},
+		{"[!a-c]x", "bx", false},
+		{"[^a-c]x", "dx", true},
+		{"[]]", "]", true},
+		{"[!]]", "a", true},
+		{"[a-]", "-", true},
+		{`[\]]`, "]", true},
+		{"a[/]b", "a/b", false},
+		{"[{]", "{", true},
+
+		// Braces
+		{"*.{go,mod}", "go.mod", true},
+		{"*.{go,mod}", "main.go", true},
+		{"*.{go,mod}", "go.sum", false},
+		{"{src,lib}/**/*.go", "lib/a/b.go", true},
+		{"{a,b{c,d}}", "bd", true},
+		{"{,x}y", "y", true},
+		{"{a/b,c}/d", "a/b/d", true},
+		{"a,b", "a,b", true},
+		{"a}", "a}", true},
+
+		// Escapes
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{`\{a,b\}`, "{a,b}", true},
+		{`\[a]`, "[a]", true},
+
+		// Doublestar segments
+		{"**", "", true},
+		{"**", "a/b/c", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/main.go", true},
+		{"**/*.go", "/abs/main.go", true},
+		{"a/**", "a", true},
+		{"a/**", "a/b/c", true},
+		{"a/**", "ab", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/xb", false},
+		{"a/**/**/b", "a/b", true},
+		{"a/**/**", "a", true},
+		{"**/**", "x/y", true},
+		{"/**", "/etc/passwd", true},
+		{"src/**/testdata/**/*.json", "src/x/testdata/y/z/c.json", true},
+		{"**/a/**/b/**/c", "a/b/c", true},
+		{"**/a/**/b/**/c", "x/a/y/b/z/c", true},
+		{"**/a/**/b/**/c", "x/a/y/c/z/b", false},
+
+		// Doublestar inside a segment
+		{"src/**_test.go", "src/a/b_test.go", true},
+		{"src/**_test.go", "src/b_test.go", true},
+		{"src/**_test.go", "lib/b_test.go", false},
+		{"**.go", "a/b.go", true},
+		{"a**", "abc/d", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			m, err := Compile(tt.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) error: %v", tt.pattern, err)
+			}
+			if got := m.Match(tt.path); got != tt.want {
+				t.Errorf("Compile(%q).Match(%q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"[",
+		"[a",
+		"[!",
+		"[]",
+		"a/[b",
+		"{a,b",
+		"{a,{b}",
+		`a\`,
+		`[a\`,
+		strings.Repeat("{a,b}", 11),
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			if _, err := Compile(pattern); err == nil {
+				t.Errorf("Compile(%q) succeeded, want error", pattern)
+			}
+		})
+	}
+}
+
+func TestMatcherLongPath(t *testing.T) {
+	m := MustCompile("**/a*a*a*a*a*a*b/**")
+	path := strings.Repeat("a", 100) + "/" + strings.Repeat("a/", 50) + "c"
+	if m.Match(path) {
+		t.Errorf("Match(%q) = true, want false", path)
+	}
+}
+
+// FuzzMatch checks the matcher against filepath.Match on the syntax both
+// share: no braces, escapes, ** or [! classes. Unlike filepath.Match, a
+// class never matches /, so classes are only tried on single names.
+func FuzzMatch(f *testing.F) {
+	f.Add("*.go", "main.go")
+	f.Add("src/*/x?[a-c]", "src/pkg/xyb")
+	f.Add("[^a]*", "ba/c")
+	f.Add("a[/]b", "a")
+	f.Add("*a*b*c", "aXbYcZc")
+
+	f.Fuzz(func(t *testing.T, pattern, path string) {
+		if strings.ContainsAny(pattern, `{}\`) || strings.Contains(pattern, "**") || strings.Contains(pattern, "[!") {
+			return
+		}
+		if strings.Contains(pattern, "[") && strings.Contains(path, "/") {
+			return
+		}
+		want, err := filepath.Match(pattern, path)
+		if err != nil {
+			return
+		}
+		// filepath.Match may stop before it reaches a malformed part.
+		m, err := Compile(pattern)
+		if err != nil {
+			return
+		}
+		if got := m.Match(path); got != want {
+			t.Errorf("Compile(%q).Match(%q) = %v, filepath.Match says %v", pattern, path, got, want)
+		}
+	})
+}
+
+// FuzzEscape checks that an escaped string only matches itself, and that
+// braces match whatever one of their alternatives matches.
+func FuzzEscape(f *testing.F) {
+	f.Add("a*b", "c")
+	f.Add("{x,y}", "[!]")
+	f.Add(`\`, "**/")
+
+	f.Fuzz(func(t *testing.T, a, b string) {
+		m, err := Compile(escape(a))
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", escape(a), err)
+		}
+		if !m.Match(a) {
+			t.Errorf("escaped %q does not match itself", a)
+		}
+		if a != b && m.Match(b) {
+			t.Errorf("escaped %q matches %q", a, b)
+		}
+
+		braces, err := Compile("{" + escape(a) + "," + escape(b) + "}")
+		if err != nil {
+			t.Fatalf("Compile braces of %q, %q error: %v", a, b, err)
+		}
+		if !braces.Match(a) || !braces.Match(b) {
+			t.Errorf("braces of %q and %q do not match both", a, b)
+		}
+	})
+}
+
+// FuzzCompile checks that no pattern makes Compile or Match panic.
+func FuzzCompile(f *testing.F) {
+	f.Add("src/**/testdata/**/*.{json,yaml}", "src/a/testdata/b.json")
+	f.Add(`[]!\-]{a,{b,c}}\`, "]")
+	f.Add("**a**/**b", "xa/b")
+
+	f.Fuzz(func(t *testing.T, pattern, path string) {
+		m, err := Compile(pattern)
+		if err != nil {
+			return
+		}
+		m.Match(path)
+		NewList([]string{pattern, "!" + pattern}).Match(path, true)
+	})
+}
+
+// escape quotes every character, so the pattern is taken literally.
+func escape(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		b.WriteByte('\\')
+		b.WriteString(s[:size])
+		s = s[size:]
+	}
+	return b.String()
+}
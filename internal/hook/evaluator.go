@@ -6,28 +6,61 @@ import (
 	"encoding/json"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/adrianpk/watchman/internal/audit"
 	"github.com/adrianpk/watchman/internal/config"
 	"github.com/adrianpk/watchman/internal/parser"
 	"github.com/adrianpk/watchman/internal/policy"
+	"github.com/adrianpk/watchman/internal/request"
 	"github.com/adrianpk/watchman/internal/state"
 )
 
 // Input represents the hook input from Claude Code.
 type Input struct {
-	HookType  string
-	ToolName  string
-	ToolInput map[string]interface{}
-	CWD       string
+	HookType       string
+	ToolName       string
+	ToolInput      map[string]interface{}
+	CWD            string
+	SessionID      string
+	TranscriptPath string
+	PermissionMode string
+	Prompt         string // UserPromptSubmit only
+	StopHookActive bool   // Stop only: the agent is already continuing because of a Stop hook
+}
+
+// Event returns the hook event, treating an unnamed event as PreToolUse.
+func (i Input) Event() string {
+	if i.HookType == "" {
+		return config.EventPreToolUse
+	}
+	return i.HookType
+}
+
+// Context returns the request context of the input.
+func (i Input) Context() request.Context {
+	return request.Context{
+		CWD:            i.CWD,
+		SessionID:      i.SessionID,
+		TranscriptPath: i.TranscriptPath,
+		Event:          i.Event(),
+		PermissionMode: i.PermissionMode,
+	}
 }
 
 // Result represents the evaluation result.
+// A result that asks is not allowed: the user decides, prompted with Reason.
+// Audit holds the reasons of a rule's violations allowed in audit mode; the
+// trace keeps them for the whole evaluation, see Result.Audited.
 type Result struct {
 	Allowed bool
+	Ask     bool
 	Reason  string
 	Warning string
+	Audit   []string
+	Trace   []Step
 }
 
 // Evaluator evaluates hook inputs against configured rules.
@@ -35,21 +68,21 @@ type Evaluator struct {
 	cfg                *config.Config
 	hookMatcher        *HookMatcher
 	hookExec           *HookExecutor
-	stateManager       *state.Manager
+	protected          *policy.Protected   // protected paths from config and watchman's own files
 	hookProtectedPaths map[string][]string // hook name -> protected paths
+	dryRun             bool                // skip side effects such as reminder state
+	audit              bool                // WATCHMAN_MODE=audit: every rule is in audit mode
 }
 
 // NewEvaluator creates a new hook evaluator.
 func NewEvaluator(cfg *config.Config) *Evaluator {
-	sm := state.NewManager()
-	_ = sm.Load() // Ignore error, use fresh state if load fails
-
 	eval := &Evaluator{
 		cfg:                cfg,
 		hookMatcher:        NewHookMatcher(),
 		hookExec:           NewHookExecutor(),
-		stateManager:       sm,
+		protected:          newProtected(cfg),
 		hookProtectedPaths: make(map[string][]string),
+		audit:              os.Getenv("WATCHMAN_MODE") == config.ModeAudit,
 	}
 
 	eval.loadHookProtectedPaths()
@@ -57,6 +90,24 @@ func NewEvaluator(cfg *config.Config) *Evaluator {
 	return eval
 }
 
+// newProtected returns the protected paths from config, along with the
+// state directory and audit log, wherever the environment and config put
+// them.
+func newProtected(cfg *config.Config) *policy.Protected {
+	protected := policy.NewProtected(&cfg.Protected)
+	paths := append([]string{state.Root()}, audit.NewLogger(&cfg.Audit).RotationPaths()...)
+	protected.Paths = append(paths, protected.Paths...)
+	return protected
+}
+
+// NewDryRunEvaluator creates an evaluator that never persists reminder state.
+// Used by commands that inspect decisions without affecting the agent session.
+func NewDryRunEvaluator(cfg *config.Config) *Evaluator {
+	eval := NewEvaluator(cfg)
+	eval.dryRun = true
+	return eval
+}
+
 // loadHookProtectedPaths queries each hook for its protected paths.
 func (e *Evaluator) loadHookProtectedPaths() {
 	for _, hook := range e.cfg.Hooks {
@@ -68,137 +119,405 @@ func (e *Evaluator) loadHookProtectedPaths() {
 }
 
 // Evaluate processes the hook input and returns a result.
+// The result carries a trace of every rule that ran, in order.
 func (e *Evaluator) Evaluate(input Input) Result {
-	// Check tool blocklist
-	if e.isToolBlocked(input.ToolName) {
-		return Result{Allowed: false, Reason: "tool is blocked by configuration: " + input.ToolName}
-	}
+	// Rules resolve paths against the agent's directory; only a payload
+	// without one falls back to the process directory.
+	input.CWD = input.Context().Dir()
+
+	t := &trace{}
+	var result Result
+	switch input.Event() {
+	case config.EventPostToolUse:
+		result = e.evaluatePostToolUse(input, t)
+	case config.EventSessionStart:
+		result = e.evaluateSessionStart(input, t)
+	case config.EventUserPromptSubmit:
+		result = e.evaluateUserPromptSubmit(input, t)
+	case config.EventStop:
+		result = e.evaluateStop(input, t)
+	default:
+		result = e.evaluate(input, t)
+	}
+	if !result.Allowed && !result.Ask {
+		e.recordDenial(input, result.Reason)
+	}
+	result.Trace = t.steps
+	return result
+}
+
+func (e *Evaluator) evaluate(input Input, t *trace) Result {
+	// Rules that ask do not stop the evaluation, so a later denial still wins.
+	asks := &pendingAsk{}
 
-	// Check tool allowlist
-	if !e.isToolAllowed(input.ToolName) {
-		return Result{Allowed: false, Reason: "tool is not in allowed list: " + input.ToolName}
+	// Check tool blocklist and allowlist
+	if result := t.record("tools", e.enforce("tools", e.cfg.Tools.Mode, e.cfg.Tools.OnViolation, e.evaluateTools(input.ToolName))); asks.stop(result) {
+		return result
 	}
 
 	// Non-filesystem tools are always allowed (but still track reminders)
 	if !isFilesystemTool(input.ToolName) {
-		return e.withReminders(Result{Allowed: true})
+		t.skip("paths", "not a filesystem tool")
+		return asks.result(e.withReminders(input, Result{Allowed: true}, t))
 	}
 
 	// Check command blocklist for Bash
-	if input.ToolName == "Bash" {
-		if cmd, ok := input.ToolInput["command"].(string); ok {
-			if blocked := e.isCommandBlocked(cmd); blocked != "" {
-				return Result{Allowed: false, Reason: "command is blocked by configuration: " + blocked}
-			}
+	switch {
+	case input.ToolName != "Bash":
+		t.skip("commands", "not a Bash command")
+	case !config.RunsOn(e.cfg.Commands.Events, config.EventPreToolUse):
+		t.skip("commands", "not enabled for PreToolUse")
+	default:
+		if result := t.record("commands", e.enforce("commands", e.cfg.Commands.Mode, e.cfg.Commands.OnViolation, e.evaluateCommands(input))); asks.stop(result) {
+			return result
 		}
 	}
 
 	// Check protected paths
-	paths := ExtractPaths(input.ToolName, input.ToolInput)
-	for _, p := range paths {
-		if policy.IsAlwaysProtected(p) {
-			return Result{Allowed: false, Reason: "path is protected and cannot be accessed. User must perform this action manually."}
-		}
-		if hook := e.isHookProtected(p); hook != "" {
-			return Result{Allowed: false, Reason: "path is protected by hook " + hook + ". User must perform this action manually."}
-		}
+	if result := t.record("protected", e.evaluateProtected(input)); !result.Allowed {
+		return result
 	}
 
-	// Apply workspace rule
-	if e.cfg.Rules.Workspace {
-		if result := e.evaluateWorkspace(input); !result.Allowed {
+	// Apply workspace rule; a warning about unresolved paths does not stop later rules
+	var warning string
+	switch {
+	case !e.cfg.Rules.Workspace:
+		t.skip("workspace", "rule disabled")
+	case !config.RunsOn(e.cfg.Workspace.Events, config.EventPreToolUse):
+		t.skip("workspace", "not enabled for PreToolUse")
+	default:
+		result := t.record("workspace", e.enforce("workspace", e.cfg.Workspace.Mode, e.cfg.Workspace.OnViolation, e.evaluateWorkspace(input)))
+		if asks.stop(result) {
 			return result
 		}
+		warning = result.Warning
 	}
 
 	// Apply scope rule
-	if e.cfg.Rules.Scope {
-		if result := e.evaluateScope(input); !result.Allowed {
+	switch {
+	case !e.cfg.Rules.Scope:
+		t.skip("scope", "rule disabled")
+	case !config.RunsOn(e.cfg.Scope.Events, config.EventPreToolUse):
+		t.skip("scope", "not enabled for PreToolUse")
+	default:
+		result := t.record("scope", e.enforce("scope", e.cfg.Scope.Mode, e.cfg.Scope.OnViolation, e.evaluateScope(input)))
+		if asks.stop(result) {
 			return result
 		}
+		warning = joinWarnings(warning, result.Warning)
 	}
 
 	// Apply versioning rule
-	if e.cfg.Rules.Versioning && input.ToolName == "Bash" {
-		if result := e.evaluateVersioning(input); !result.Allowed {
+	switch {
+	case !e.cfg.Rules.Versioning:
+		t.skip("versioning", "rule disabled")
+	case !config.RunsOn(e.cfg.Versioning.Events, config.EventPreToolUse):
+		t.skip("versioning", "not enabled for PreToolUse")
+	case input.ToolName != "Bash":
+		t.skip("versioning", "not a Bash command")
+	default:
+		result := t.record("versioning", e.enforce("versioning", e.cfg.Versioning.Mode, e.cfg.Versioning.OnViolation, e.evaluateVersioning(input)))
+		if asks.stop(result) {
 			return result
 		}
+		warning = joinWarnings(warning, result.Warning)
 	}
 
 	// Apply incremental rule
-	if e.cfg.Rules.Incremental && isModificationTool(input.ToolName) {
-		if result := e.evaluateIncremental(); !result.Allowed {
+	switch {
+	case !e.cfg.Rules.Incremental:
+		t.skip("incremental", "rule disabled")
+	case !config.RunsOn(e.cfg.Incremental.Events, config.EventPreToolUse):
+		t.skip("incremental", "not enabled for PreToolUse")
+	case !isModificationTool(input.ToolName) && len(bashWrites(input)) == 0:
+		t.skip("incremental", "not a modification tool")
+	default:
+		result := t.record("incremental", e.enforce("incremental", e.cfg.Incremental.Mode, e.cfg.Incremental.OnViolation, e.evaluateIncremental(input)))
+		if asks.stop(result) {
 			return result
-		} else if result.Warning != "" {
-			return e.withReminders(result)
 		}
+		warning = joinWarnings(warning, result.Warning)
 	}
 
 	// Apply invariants rule
-	if e.cfg.Rules.Invariants && isModificationTool(input.ToolName) {
-		if result := e.evaluateInvariants(input); !result.Allowed {
+	switch {
+	case !e.cfg.Rules.Invariants:
+		t.skip("invariants", "rule disabled")
+	case !config.RunsOn(e.cfg.Invariants.Events, config.EventPreToolUse):
+		t.skip("invariants", "not enabled for PreToolUse")
+	case !isModificationTool(input.ToolName) && input.ToolName != "Bash":
+		t.skip("invariants", "not a modification tool")
+	default:
+		result := t.record("invariants", e.enforce("invariants", e.cfg.Invariants.Mode, e.cfg.Invariants.OnViolation, e.evaluateInvariants(input)))
+		if asks.stop(result) {
 			return result
 		}
+		warning = joinWarnings(warning, result.Warning)
 	}
 
 	// Apply external hooks
 	if len(e.cfg.Hooks) > 0 {
-		if result := e.evaluateHooks(input); !result.Allowed {
+		result := e.evaluateHooks(input, t)
+		if asks.stop(result) {
 			return result
-		} else if result.Warning != "" {
-			return e.withReminders(result)
 		}
+		warning = joinWarnings(warning, result.Warning)
 	}
 
 	// Check reminders (post-execution, always runs for allowed operations)
-	return e.evaluateReminders()
+	return asks.result(withWarning(e.evaluateReminders(input, t), warning))
 }
 
-func (e *Evaluator) evaluateWorkspace(input Input) Result {
-	rule := policy.NewConfineToWorkspace(&e.cfg.Workspace)
-	paths := ExtractPaths(input.ToolName, input.ToolInput)
+// enforce applies a rule's mode and on_violation to its result. In audit
+// mode, or with WATCHMAN_MODE=audit, a violation only adds its reason to
+// Audit and the call goes on; otherwise on_violation decides.
+func (e *Evaluator) enforce(rule, mode, action string, result Result) Result {
+	if result.Allowed || !e.audits(mode) {
+		return onViolation(rule, action, result)
+	}
+	return Result{Allowed: true, Warning: result.Warning, Audit: append(result.Audit, result.Reason)}
+}
+
+// audits reports whether a rule with the given mode is in audit mode.
+func (e *Evaluator) audits(mode string) bool {
+	return e.audit || mode == config.ModeAudit
+}
+
+// onViolation applies a rule's on_violation to its result: ask turns a
+// denial into a question for the user, and warn into a warning naming the
+// rule, or unnamed when rule is empty.
+func onViolation(rule, mode string, result Result) Result {
+	if result.Allowed || result.Ask {
+		return result
+	}
+	switch mode {
+	case config.ViolationAsk:
+		result.Ask = true
+	case config.ViolationWarn:
+		warning := result.Reason
+		if rule != "" {
+			warning = rule + ": " + warning
+		}
+		return Result{Allowed: true, Warning: joinWarnings(result.Warning, warning), Audit: result.Audit}
+	}
+	return result
+}
+
+// pendingAsk keeps the first rule result that asks the user while the
+// evaluation goes on.
+type pendingAsk struct {
+	ask *Result
+}
+
+// stop reports whether a rule result ends the evaluation: a denial does,
+// an ask is kept for the end.
+func (p *pendingAsk) stop(result Result) bool {
+	if result.Ask {
+		if p.ask == nil {
+			p.ask = &result
+		}
+		return false
+	}
+	return !result.Allowed
+}
+
+// result returns the outcome of an evaluation no rule denied: the first
+// ask, carrying the warnings of the allowed result, or that result.
+func (p *pendingAsk) result(result Result) Result {
+	if p.ask == nil || !result.Allowed {
+		return result
+	}
+	ask := *p.ask
+	ask.Warning = joinWarnings(ask.Warning, result.Warning)
+	return ask
+}
+
+// withWarning puts an earlier warning in front of the result's own.
+func withWarning(result Result, warning string) Result {
+	result.Warning = joinWarnings(warning, result.Warning)
+	return result
+}
+
+func (e *Evaluator) evaluateTools(tool string) Result {
+	if e.isToolBlocked(tool) {
+		return Result{Allowed: false, Reason: "tool is blocked by configuration: " + tool}
+	}
+	if !e.isToolAllowed(tool) {
+		return Result{Allowed: false, Reason: "tool is not in allowed list: " + tool}
+	}
+	return Result{Allowed: true}
+}
+
+func (e *Evaluator) evaluateCommands(input Input) Result {
+	if cmd, ok := input.ToolInput["command"].(string); ok {
+		if blocked := e.isCommandBlocked(cmd); blocked != "" {
+			return Result{Allowed: false, Reason: "command is blocked by configuration: " + blocked}
+		}
+	}
+	return Result{Allowed: true}
+}
+
+func (e *Evaluator) evaluateProtected(input Input) Result {
+	paths := inputPaths(input)
 	for _, p := range paths {
-		parsed := parser.Command{Args: []string{p}}
-		decision := rule.Evaluate(parsed, input.CWD)
-		if !decision.Allowed {
-			return Result{Allowed: false, Reason: decision.Reason}
+		if policy.IsAlwaysProtected(resolve(p, input.CWD)) || e.protected.Match(p, input.CWD) {
+			return Result{Allowed: false, Reason: "path is protected and cannot be accessed. User must perform this action manually."}
+		}
+		if hook := e.isHookProtected(p, input.CWD); hook != "" {
+			return Result{Allowed: false, Reason: "path is protected by hook " + hook + ". User must perform this action manually."}
 		}
 	}
 	return Result{Allowed: true}
 }
 
+// roots resolves workspace.root for the working directory of a tool call.
+func (e *Evaluator) roots(cwd string) []string {
+	return policy.ResolveRoots(e.cfg.Workspace.Root, cwd)
+}
+
+func (e *Evaluator) evaluateWorkspace(input Input) Result {
+	rule := policy.NewConfineToWorkspace(&e.cfg.Workspace)
+	rule.Roots = e.roots(input.CWD)
+	rule.Protected = e.protected
+	// Links are checked by where they lead before their words are checked
+	// as paths, so the reason names the link. Files a command writes need
+	// write access, including those it creates inside a directory it names.
+	writes := make(map[string]bool)
+	for _, w := range bashWrites(input) {
+		if w.LinkTo != "" {
+			if decision := rule.CheckLink(w.Path, resolve(w.LinkTo, input.CWD), input.CWD); !decision.Allowed {
+				return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
+			}
+		}
+		if decision := rule.CheckPath(w.Path, input.CWD, policy.AccessWrite); !decision.Allowed {
+			return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
+		}
+		writes[w.Path] = true
+	}
+	for _, p := range inputPaths(input) {
+		access := policy.AccessRead
+		if isModificationTool(input.ToolName) || writes[p] {
+			access = policy.AccessWrite
+		}
+		if decision := rule.CheckPath(p, input.CWD, access); !decision.Allowed {
+			return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
+		}
+	}
+	return e.evaluateUnresolved(input)
+}
+
+// evaluateUnresolved applies workspace.on_unresolved to Bash words whose
+// value is only known when the command runs, since they could name any path.
+func (e *Evaluator) evaluateUnresolved(input Input) Result {
+	cmd, ok := input.ToolInput["command"].(string)
+	if input.ToolName != "Bash" || !ok {
+		return Result{Allowed: true}
+	}
+
+	var unresolved []string
+	for _, c := range bashCommands(cmd, input.CWD) {
+		unresolved = append(unresolved, c.Unresolved...)
+	}
+	if len(unresolved) == 0 {
+		return Result{Allowed: true}
+	}
+
+	msg := "cannot resolve " + strings.Join(unresolved, ", ") + " before the command runs"
+	switch e.cfg.Workspace.OnUnresolved {
+	case config.UnresolvedAllow:
+		return Result{Allowed: true}
+	case config.UnresolvedDeny:
+		return Result{Allowed: false, Reason: "workspace.on_unresolved: " + msg}
+	default:
+		return Result{Allowed: true, Warning: "workspace: " + msg}
+	}
+}
+
 func (e *Evaluator) evaluateScope(input Input) Result {
 	rule := policy.NewScopeToFiles(&e.cfg.Scope)
-	paths := ExtractPaths(input.ToolName, input.ToolInput)
-	for _, p := range paths {
-		parsed := parser.Command{Args: []string{p}}
-		decision := rule.Evaluate(input.ToolName, parsed, input.CWD)
-		if !decision.Allowed {
-			return Result{Allowed: false, Reason: decision.Reason}
+	rule.Roots = e.roots(input.CWD)
+	if input.ToolName == "Bash" {
+		// Only the files the command writes, creates or deletes are in question.
+		for _, w := range bashWrites(input) {
+			if decision := e.checkScope(rule, w.Path, input.CWD); !decision.Allowed {
+				return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
+			}
+		}
+		return Result{Allowed: true}
+	}
+	if !isModificationTool(input.ToolName) {
+		return Result{Allowed: true}
+	}
+	for _, p := range inputPaths(input) {
+		if decision := e.checkScope(rule, p, input.CWD); !decision.Allowed {
+			return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
 		}
 	}
 	return Result{Allowed: true}
 }
 
+// checkScope checks a path the agent modifies against the scope rule and
+// the scope of every directory config the path lies under.
+func (e *Evaluator) checkScope(rule *policy.ScopeToFiles, p, cwd string) policy.Decision {
+	if decision := rule.CheckPath(p, cwd); !decision.Allowed {
+		return decision
+	}
+	abs := resolve(p, cwd)
+	for _, d := range e.cfg.DirectoriesFor(abs) {
+		local := policy.NewScopeToFiles(&d.Scope)
+		local.Roots = []string{d.Dir}
+		if decision := local.CheckPath(abs, cwd); !decision.Allowed {
+			decision.Reason = directoryConfig(d) + ": " + decision.Reason
+			return decision
+		}
+	}
+	return policy.Decision{Allowed: true}
+}
+
 func (e *Evaluator) evaluateVersioning(input Input) Result {
 	cmd, ok := input.ToolInput["command"].(string)
 	if !ok {
 		return Result{Allowed: true}
 	}
 	rule := policy.NewVersioningRule(&e.cfg.Versioning)
-	decision := rule.Evaluate(cmd)
-	return Result{Allowed: decision.Allowed, Reason: decision.Reason}
+	for _, c := range parser.ParseAll(cmd) {
+		switch filepath.Base(c.Program) {
+		case "git", "jj":
+		default:
+			continue
+		}
+		if decision := rule.Evaluate(c.Raw); !decision.Allowed {
+			return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason}
+		}
+	}
+	return Result{Allowed: true}
 }
 
-func (e *Evaluator) evaluateIncremental() Result {
+func (e *Evaluator) evaluateIncremental(input Input) Result {
 	rule := policy.NewIncrementalRule(&e.cfg.Incremental)
+	rule.Roots = e.roots(input.CWD)
+	rule.Dir = input.Context().Dir()
 	decision := rule.Evaluate()
-	return Result{Allowed: decision.Allowed, Reason: decision.Reason, Warning: decision.Warning}
+	return Result{Allowed: decision.Allowed, Ask: decision.Ask, Reason: decision.Reason, Warning: decision.Warning}
 }
 
 func (e *Evaluator) evaluateInvariants(input Input) Result {
-	rule := policy.NewInvariantsRule(&e.cfg.Invariants)
-	paths := ExtractPaths(input.ToolName, input.ToolInput)
+	var audit []string
+	if input.ToolName == "Bash" {
+		// Only writes whose resulting content is known up front can be checked.
+		for _, w := range bashWrites(input) {
+			if w.Op != parser.OpWrite || !w.HasContent {
+				continue
+			}
+			decision := e.checkInvariants(w.Path, w.Content, input.CWD)
+			audit = append(audit, prefixed(w.Path+": ", decision.Audit)...)
+			if !decision.Allowed {
+				return Result{Allowed: false, Ask: decision.Ask, Reason: w.Path + ": " + decision.Reason, Audit: audit}
+			}
+		}
+		return Result{Allowed: true, Audit: audit}
+	}
+	paths := inputPaths(input)
 
 	// Get content for content-based checks
 	content := ""
@@ -207,30 +526,74 @@ func (e *Evaluator) evaluateInvariants(input Input) Result {
 	}
 
 	for _, p := range paths {
-		decision := rule.Evaluate(input.ToolName, p, content)
+		decision := e.checkInvariants(p, content, input.CWD)
+		audit = append(audit, decision.Audit...)
 		if !decision.Allowed {
-			return Result{Allowed: false, Reason: decision.Reason}
+			return Result{Allowed: false, Ask: decision.Ask, Reason: decision.Reason, Audit: audit}
 		}
 	}
-	return Result{Allowed: true}
+	return Result{Allowed: true, Audit: audit}
 }
 
-func (e *Evaluator) evaluateHooks(input Input) Result {
-	paths := ExtractPaths(input.ToolName, input.ToolInput)
+// checkInvariants checks the new content of a file against the invariants
+// and those of every directory config the file lies under. Directory configs
+// follow the mode of the invariants rule; their checks can set their own.
+func (e *Evaluator) checkInvariants(p, content, cwd string) policy.Decision {
+	rule := policy.NewInvariantsRule(&e.cfg.Invariants)
+	rule.Audit = e.audits(e.cfg.Invariants.Mode)
+	decision := rule.CheckFile(p, content)
+	if !decision.Allowed {
+		return decision
+	}
+	audit := decision.Audit
+	abs := resolve(p, cwd)
+	for _, d := range e.cfg.DirectoriesFor(abs) {
+		local := policy.NewInvariantsRule(&d.Invariants)
+		local.Dir = d.Dir
+		local.Audit = rule.Audit
+		decision := local.CheckFile(abs, content)
+		audit = append(audit, prefixed(directoryConfig(d)+": ", decision.Audit)...)
+		if !decision.Allowed {
+			decision.Reason = directoryConfig(d) + ": " + decision.Reason
+			decision.Audit = audit
+			return decision
+		}
+	}
+	return policy.Decision{Allowed: true, Audit: audit}
+}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		cwd = ""
+// prefixed returns the reasons with prefix in front of each.
+func prefixed(prefix string, reasons []string) []string {
+	out := make([]string, len(reasons))
+	for i, r := range reasons {
+		out[i] = prefix + r
 	}
+	return out
+}
 
+// directoryConfig names the file a directory config comes from.
+func directoryConfig(d config.DirectoryConfig) string {
+	return filepath.Join(d.Dir, ".watchman.yml")
+}
+
+func (e *Evaluator) evaluateHooks(input Input, t *trace) Result {
+	paths := inputPaths(input)
+
+	event := input.Event()
 	hookInput := HookInput{
-		ToolName:   input.ToolName,
-		ToolInput:  input.ToolInput,
-		Paths:      paths,
-		WorkingDir: cwd,
+		Event:          event,
+		ToolName:       input.ToolName,
+		ToolInput:      input.ToolInput,
+		Paths:          paths,
+		WorkingDir:     input.CWD,
+		SessionID:      input.SessionID,
+		TranscriptPath: input.TranscriptPath,
+		PermissionMode: input.PermissionMode,
+		Prompt:         input.Prompt,
 	}
 
 	var warnings []string
+	asks := &pendingAsk{}
 
 	// Extract command for match_command filtering
 	command, _ := input.ToolInput["command"].(string)
@@ -238,13 +601,22 @@ func (e *Evaluator) evaluateHooks(input Input) Result {
 	for i := range e.cfg.Hooks {
 		hookCfg := &e.cfg.Hooks[i]
 
-		if !e.hookMatcher.Matches(hookCfg, input.ToolName, paths, command) {
+		rule := "hook " + hookCfg.Name
+		if !config.RunsOn(hookCfg.Events, event) {
+			t.skip(rule, "not enabled for "+event)
+			continue
+		}
+		if isToolEvent(event) && !e.hookMatcher.Matches(hookCfg, input.ToolName, paths, command) {
+			t.skip(rule, "tool, path or command does not match")
 			continue
 		}
 
-		result := e.hookExec.Execute(hookCfg, hookInput)
+		result := t.record(rule, e.enforce("", hookCfg.Mode, hookCfg.OnViolation, e.hookExec.Execute(hookCfg, hookInput)))
 
-		if !result.Allowed {
+		if result.Ask {
+			result.Reason = hookCfg.Name + ": " + result.Reason
+		}
+		if asks.stop(result) {
 			return Result{
 				Allowed: false,
 				Reason:  hookCfg.Name + ": " + result.Reason,
@@ -256,53 +628,7 @@ func (e *Evaluator) evaluateHooks(input Input) Result {
 		}
 	}
 
-	if len(warnings) > 0 {
-		return Result{Allowed: true, Warning: strings.Join(warnings, "; ")}
-	}
-
-	return Result{Allowed: true}
-}
-
-func (e *Evaluator) evaluateReminders() Result {
-	if len(e.cfg.Reminders) == 0 {
-		return Result{Allowed: true}
-	}
-
-	// Increment task count
-	e.stateManager.IncrementTaskCount()
-
-	// Check if any reminders should trigger
-	triggered := e.stateManager.CheckReminders(e.cfg.Reminders)
-
-	// Save state (ignore errors, non-critical)
-	_ = e.stateManager.Save()
-
-	if len(triggered) > 0 {
-		return Result{
-			Allowed: true,
-			Warning: strings.Join(triggered, "; "),
-		}
-	}
-
-	return Result{Allowed: true}
-}
-
-// withReminders combines a result with any triggered reminders.
-// Should be called for all allowed operations to ensure reminders are tracked.
-func (e *Evaluator) withReminders(result Result) Result {
-	if !result.Allowed {
-		return result
-	}
-
-	reminderResult := e.evaluateReminders()
-	if reminderResult.Warning != "" {
-		if result.Warning != "" {
-			result.Warning = result.Warning + "; " + reminderResult.Warning
-		} else {
-			result.Warning = reminderResult.Warning
-		}
-	}
-	return result
+	return asks.result(Result{Allowed: true, Warning: strings.Join(warnings, "; ")})
 }
 
 func (e *Evaluator) isToolBlocked(tool string) bool {
@@ -327,17 +653,24 @@ func (e *Evaluator) isToolAllowed(tool string) bool {
 }
 
 func (e *Evaluator) isCommandBlocked(cmd string) string {
+	cmds := bashCommands(cmd, "")
 	for _, pattern := range e.cfg.Commands.Block {
-		// Patterns with spaces (like "rm -rf /") use substring matching
+		// Patterns with spaces (like "rm -rf /") use substring matching,
+		// against the raw text and against each command with quoting removed
 		if strings.Contains(pattern, " ") {
 			if strings.Contains(cmd, pattern) {
 				return pattern
 			}
+			for _, c := range cmds {
+				if strings.Contains(strings.Join(c.Words, " "), pattern) {
+					return pattern
+				}
+			}
 			continue
 		}
 
 		// Single-word patterns match only in command position
-		if isCommandInPosition(cmd, pattern) {
+		if isProgram(cmds, pattern) {
 			return pattern
 		}
 	}
@@ -346,10 +679,10 @@ func (e *Evaluator) isCommandBlocked(cmd string) string {
 
 // isHookProtected checks if a path is protected by any hook's protected paths.
 // Returns the hook name if protected, empty string otherwise.
-func (e *Evaluator) isHookProtected(path string) string {
+func (e *Evaluator) isHookProtected(path, cwd string) string {
 	for hookName, patterns := range e.hookProtectedPaths {
 		for _, pattern := range patterns {
-			if policy.MatchProtectedPath(path, pattern) {
+			if policy.MatchProtectedPath(path, pattern, cwd) {
 				return hookName
 			}
 		}
@@ -377,144 +710,16 @@ func queryHookProtectedPaths(command string) []string {
 	return paths
 }
 
-// isCommandInPosition checks if pattern appears as an actual command
-// (first token of a pipeline/chain segment), not as an argument.
-func isCommandInPosition(cmd, pattern string) bool {
-	// Split by command separators: |, &&, ||, ;
-	// We iterate through segments to find command positions
-	segments := splitCommandSegments(cmd)
-
-	for _, seg := range segments {
-		seg = strings.TrimSpace(seg)
-		if seg == "" {
-			continue
-		}
-
-		// Extract the command (first token)
-		command := extractCommandName(seg)
-		if command == pattern {
+// isProgram reports whether any command runs the program, by name or by path.
+func isProgram(cmds []parser.Command, program string) bool {
+	for _, c := range cmds {
+		if c.Program == program || filepath.Base(c.Program) == program {
 			return true
 		}
 	}
 	return false
 }
 
-// splitCommandSegments splits a shell command by |, &&, ||, ;
-func splitCommandSegments(cmd string) []string {
-	var segments []string
-	var current strings.Builder
-	i := 0
-
-	for i < len(cmd) {
-		ch := cmd[i]
-
-		switch ch {
-		case '|':
-			segments = append(segments, current.String())
-			current.Reset()
-			// Skip || (treat as single separator)
-			if i+1 < len(cmd) && cmd[i+1] == '|' {
-				i++
-			}
-		case '&':
-			if i+1 < len(cmd) && cmd[i+1] == '&' {
-				segments = append(segments, current.String())
-				current.Reset()
-				i++ // Skip second &
-			} else {
-				// Background &, still part of current segment
-				current.WriteByte(ch)
-			}
-		case ';':
-			segments = append(segments, current.String())
-			current.Reset()
-		case '\'', '"':
-			// Skip quoted strings entirely
-			quote := ch
-			current.WriteByte(ch)
-			i++
-			for i < len(cmd) && cmd[i] != quote {
-				if cmd[i] == '\\' && i+1 < len(cmd) {
-					current.WriteByte(cmd[i])
-					i++
-				}
-				if i < len(cmd) {
-					current.WriteByte(cmd[i])
-					i++
-				}
-			}
-			if i < len(cmd) {
-				current.WriteByte(cmd[i])
-			}
-		default:
-			current.WriteByte(ch)
-		}
-		i++
-	}
-
-	if current.Len() > 0 {
-		segments = append(segments, current.String())
-	}
-
-	return segments
-}
-
-// extractCommandName extracts the actual command from a segment.
-// Handles: VAR=value cmd, env cmd, leading spaces, etc.
-func extractCommandName(segment string) string {
-	segment = strings.TrimSpace(segment)
-	tokens := tokenize(segment)
-
-	for _, tok := range tokens {
-		// Skip environment variable assignments (VAR=value)
-		if strings.Contains(tok, "=") && !strings.HasPrefix(tok, "-") {
-			continue
-		}
-		// Return first non-assignment token as the command
-		return tok
-	}
-	return ""
-}
-
-// tokenize splits a command segment into space-separated tokens,
-// respecting quotes.
-func tokenize(s string) []string {
-	var tokens []string
-	var current strings.Builder
-	inQuote := byte(0)
-
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-
-		if inQuote != 0 {
-			if ch == inQuote {
-				inQuote = 0
-			} else {
-				current.WriteByte(ch)
-			}
-			continue
-		}
-
-		switch ch {
-		case '\'', '"':
-			inQuote = ch
-		case ' ', '\t':
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
-		default:
-			current.WriteByte(ch)
-		}
-	}
-
-	if current.Len() > 0 {
-		tokens = append(tokens, current.String())
-	}
-
-	return tokens
-}
-
 var filesystemTools = map[string]bool{
 	"Bash":  true,
 	"Read":  true,
@@ -1,9 +1,16 @@
 package hook
 
 import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/state"
 )
 
 func TestNewEvaluator(t *testing.T) {
@@ -93,7 +100,7 @@ func TestEvaluatorIsToolAllowed(t *testing.T) {
 func TestEvaluatorIsCommandBlocked(t *testing.T) {
 	cfg := &config.Config{
 		Commands: config.CommandsConfig{
-			Block: []string{"sudo", "rm -rf"},
+			Block: []string{"sudo", "rm -rf", "dd"},
 		},
 	}
 	e := NewEvaluator(cfg)
@@ -106,6 +113,29 @@ func TestEvaluatorIsCommandBlocked(t *testing.T) {
 		{"rm -rf /", "rm -rf"},
 		{"ls -la", ""},
 		{"echo hello", ""},
+
+		// Single-word patterns do not match inside paths or arguments
+		{"cd pkg/plp/middleware && go test", ""},
+		{"echo add", ""},
+		{"cat /path/to/odd/file", ""},
+		{"echo 'dd is a command'", ""},
+		{"echo \"dd of=out\"", ""},
+
+		// Single-word patterns match in command position
+		{"dd if=/dev/zero of=file", "dd"},
+		{"ls | dd of=file", "dd"},
+		{"VAR=value dd if=/dev/zero", "dd"},
+		{"ls && dd of=out", "dd"},
+		{"ls; dd of=out", "dd"},
+		{"ls || dd of=out", "dd"},
+		{"ls\ndd of=out", "dd"},
+		{"/bin/dd if=/dev/zero", "dd"},
+
+		// Nested commands are checked too
+		{"echo $(dd if=/dev/zero)", "dd"},
+		{"echo `dd if=/dev/zero`", "dd"},
+		{"(cd /tmp; dd of=out)", "dd"},
+		{"diff <(dd if=a) b", "dd"},
 	}
 
 	for _, tt := range tests {
@@ -244,6 +274,18 @@ func TestEvaluatorEvaluateWorkspace(t *testing.T) {
 	if !result.Allowed {
 		t.Errorf("expected workspace rule to allow relative path: %s", result.Reason)
 	}
+
+	// Should allow redirects to and from devices
+	for _, cmd := range []string{"ls 2>/dev/null", "cat </dev/null", "go test ./... > /dev/null 2>&1"} {
+		result = e.Evaluate(Input{
+			ToolName:  "Bash",
+			ToolInput: map[string]interface{}{"command": cmd},
+			CWD:       "/project",
+		})
+		if !result.Allowed {
+			t.Errorf("expected workspace rule to allow %q: %s", cmd, result.Reason)
+		}
+	}
 }
 
 func TestEvaluatorEvaluateScope(t *testing.T) {
@@ -313,6 +355,40 @@ func TestEvaluatorEvaluateVersioning(t *testing.T) {
 	}
 }
 
+func TestEvaluatorEvaluateVersioningStructured(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Versioning: true},
+		Versioning: config.VersioningConfig{
+			Workflow: "linear",
+			Commit:   config.CommitConfig{RequireUppercase: true},
+		},
+	}
+	e := NewEvaluator(cfg)
+
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"merge later in list", "git fetch && git merge origin/main", false},
+		{"merge mentioned in echo", `echo "run git merge later"`, true},
+		{"commit after add", `git add . && git commit -m "lowercase"`, false},
+		{"heredoc message", "git commit -m \"$(cat <<'EOF'\nUppercase message\nEOF\n)\"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(Input{
+				ToolName:  "Bash",
+				ToolInput: map[string]interface{}{"command": tt.command},
+			})
+			if result.Allowed != tt.allowed {
+				t.Errorf("Evaluate(%q) allowed = %v, want %v (%s)", tt.command, result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
 func TestEvaluatorEvaluateIncremental(t *testing.T) {
 	cfg := &config.Config{
 		Rules: config.RulesConfig{Incremental: true},
@@ -480,82 +556,806 @@ func TestEvaluatorEvaluateMultipleHooks(t *testing.T) {
 	}
 }
 
-func TestIsCommandInPosition(t *testing.T) {
+func TestEvaluatorEvaluateTrace(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Workspace: true},
+		Hooks: []config.HookConfig{
+			{
+				Name:    "js-only",
+				Command: testdataPath("deny.sh"),
+				Tools:   []string{"Read"},
+				Paths:   []string{"**/*.js"},
+			},
+		},
+	}
+	e := NewDryRunEvaluator(cfg)
+
+	result := e.Evaluate(Input{
+		ToolName:  "Read",
+		ToolInput: map[string]interface{}{"file_path": "main.go"},
+	})
+	if !result.Allowed {
+		t.Fatalf("expected allow: %s", result.Reason)
+	}
+
+	verdicts := make(map[string]string)
+	for _, step := range result.Trace {
+		verdicts[step.Rule] = step.Verdict
+	}
+
+	want := map[string]string{
+		"tools":        VerdictAllow,
+		"commands":     VerdictSkip,
+		"protected":    VerdictAllow,
+		"workspace":    VerdictAllow,
+		"scope":        VerdictSkip,
+		"hook js-only": VerdictSkip,
+	}
+	for rule, verdict := range want {
+		if verdicts[rule] != verdict {
+			t.Errorf("step %q = %q, want %q", rule, verdicts[rule], verdict)
+		}
+	}
+}
+
+func TestEvaluatorEvaluateTraceStopsAtDeny(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Workspace: true, Scope: true},
+	}
+	e := NewDryRunEvaluator(cfg)
+
+	result := e.Evaluate(Input{
+		ToolName:  "Write",
+		ToolInput: map[string]interface{}{"file_path": "/etc/hosts"},
+	})
+	if result.Allowed {
+		t.Fatal("expected deny")
+	}
+
+	last := result.Trace[len(result.Trace)-1]
+	if last.Rule != "workspace" || last.Verdict != VerdictDeny {
+		t.Errorf("last step = %+v, want workspace deny", last)
+	}
+	if last.Reason != result.Reason {
+		t.Errorf("step reason = %q, want %q", last.Reason, result.Reason)
+	}
+}
+
+func TestEvaluatorEvaluateOnViolation(t *testing.T) {
+	readPasswd := Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "/etc/passwd"}}
+	hook := func(name, script, onViolation string) config.HookConfig {
+		return config.HookConfig{Name: name, Command: testdataPath(script), Tools: []string{"Read", "Bash"}, OnViolation: onViolation}
+	}
+
 	tests := []struct {
-		cmd     string
-		pattern string
-		want    bool
+		name    string
+		cfg     config.Config
+		input   Input
+		allowed bool
+		ask     bool
+		rule    string
+		text    string // in the reason, or the warning when allowed
+		warning string
 	}{
-		// Pattern should NOT match when embedded in path/argument
-		{"cd pkg/plp/middleware && go test", "dd", false},
-		{"echo add", "dd", false},
-		{"cat /path/to/odd/file", "dd", false},
+		{
+			name: "workspace asks",
+			cfg: config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{OnViolation: config.ViolationAsk},
+			},
+			input: readPasswd,
+			ask:   true,
+			rule:  "workspace",
+			text:  "/etc/passwd",
+		},
+		{
+			name: "workspace warns",
+			cfg: config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{OnViolation: config.ViolationWarn},
+			},
+			input:   readPasswd,
+			allowed: true,
+			rule:    "workspace",
+			text:    "workspace: ",
+		},
+		{
+			name: "later deny wins over ask",
+			cfg: config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{OnViolation: config.ViolationAsk},
+				Hooks:     []config.HookConfig{hook("veto", "deny.sh", "")},
+			},
+			input: readPasswd,
+			rule:  "hook veto",
+			text:  "veto: test denial",
+		},
+		{
+			name: "ask keeps later warnings",
+			cfg: config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{OnViolation: config.ViolationAsk},
+				Hooks:     []config.HookConfig{hook("lint", "advise.sh", "")},
+			},
+			input:   readPasswd,
+			ask:     true,
+			rule:    "workspace",
+			text:    "/etc/passwd",
+			warning: "lint: consider this",
+		},
+		{
+			name: "blocked command asks",
+			cfg: config.Config{
+				Commands: config.CommandsConfig{Block: []string{"sudo"}, OnViolation: config.ViolationAsk},
+			},
+			input: Input{ToolName: "Bash", ToolInput: map[string]interface{}{"command": "sudo ls"}},
+			ask:   true,
+			rule:  "commands",
+			text:  "sudo",
+		},
+		{
+			name:  "hook asks",
+			cfg:   config.Config{Hooks: []config.HookConfig{hook("review", "ask.sh", "")}},
+			input: Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "main.go"}},
+			ask:   true,
+			rule:  "hook review",
+			text:  "review: needs a human",
+		},
+		{
+			name:    "hook denial warns",
+			cfg:     config.Config{Hooks: []config.HookConfig{hook("veto", "deny.sh", config.ViolationWarn)}},
+			input:   Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "main.go"}},
+			allowed: true,
+			rule:    "hook veto",
+			text:    "veto: test denial",
+		},
+	}
 
-		// Pattern should match when in command position
-		{"dd if=/dev/zero of=file", "dd", true},
-		{"ls | dd of=file", "dd", true},
-		{"sudo apt install", "sudo", true},
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewDryRunEvaluator(&tt.cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed || result.Ask != tt.ask {
+				t.Fatalf("result = %+v, want allowed %v, ask %v", result, tt.allowed, tt.ask)
+			}
+			if got := result.DecidingRule(); got != tt.rule {
+				t.Errorf("deciding rule = %q, want %q", got, tt.rule)
+			}
+			text := result.Reason
+			if tt.allowed {
+				text = result.Warning
+			}
+			if !strings.Contains(text, tt.text) {
+				t.Errorf("got %q, want it to contain %q", text, tt.text)
+			}
+			if !strings.Contains(result.Warning, tt.warning) {
+				t.Errorf("warning = %q, want it to contain %q", result.Warning, tt.warning)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateMode(t *testing.T) {
+	writeOutside := Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": "/opt/app/main.go", "content": "// TODO"}}
+	veto := config.HookConfig{Name: "veto", Command: testdataPath("deny.sh"), Tools: []string{"Write"}, Mode: config.ModeAudit}
 
-		// Additional edge cases
-		{"VAR=value dd if=/dev/zero", "dd", true},
-		{"echo 'dd is a command'", "dd", false},
-		{"ls && dd of=out", "dd", true},
-		{"ls; dd of=out", "dd", true},
-		{"ls || dd of=out", "dd", true},
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		env     string // WATCHMAN_MODE
+		input   Input
+		allowed bool
+		rule    string
+		audited []string // in order, each in the matching audited violation
+	}{
+		{
+			name: "later rule still denies",
+			cfg: config.Config{
+				Rules:     config.RulesConfig{Workspace: true, Scope: true},
+				Workspace: config.WorkspaceConfig{Mode: config.ModeAudit},
+				Scope:     config.ScopeConfig{Allow: []string{"src/**"}},
+			},
+			input:   writeOutside,
+			rule:    "scope",
+			audited: []string{"workspace: "},
+		},
+		{
+			name: "every violation is recorded",
+			cfg: config.Config{
+				Rules:      config.RulesConfig{Workspace: true, Scope: true, Invariants: true},
+				Workspace:  config.WorkspaceConfig{Mode: config.ModeAudit},
+				Scope:      config.ScopeConfig{Allow: []string{"src/**"}, Mode: config.ModeAudit},
+				Invariants: config.InvariantsConfig{Mode: config.ModeAudit, Content: []config.ContentCheck{{Name: "no-todo", Paths: []string{"**/*.go"}, Forbid: "TODO"}}},
+				Hooks:      []config.HookConfig{veto},
+			},
+			input:   writeOutside,
+			allowed: true,
+			rule:    "workspace,scope,invariants,hook veto",
+			audited: []string{"workspace: ", "scope: ", "invariants: content check failed: no-todo", "hook veto: test denial"},
+		},
+		{
+			name: "audit ignores on_violation",
+			cfg: config.Config{
+				Tools: config.ToolsConfig{Block: []string{"WebFetch"}, Mode: config.ModeAudit, OnViolation: config.ViolationWarn},
+			},
+			input:   Input{ToolName: "WebFetch"},
+			allowed: true,
+			rule:    "tools",
+			audited: []string{"tools: tool is blocked by configuration: WebFetch"},
+		},
+		{
+			name: "invariant check in audit mode",
+			cfg: config.Config{
+				Rules: config.RulesConfig{Invariants: true},
+				Invariants: config.InvariantsConfig{Content: []config.ContentCheck{
+					{Name: "no-todo", Paths: []string{"**/*.go"}, Forbid: "TODO", Mode: config.ModeAudit},
+				}},
+			},
+			input:   Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": "main.go", "content": "// TODO"}},
+			allowed: true,
+			rule:    "invariants",
+			audited: []string{"invariants: content check failed: no-todo"},
+		},
+		{
+			name: "WATCHMAN_MODE audits every rule",
+			cfg: config.Config{
+				Rules:    config.RulesConfig{Workspace: true},
+				Commands: config.CommandsConfig{Block: []string{"sudo"}},
+			},
+			env:     config.ModeAudit,
+			input:   Input{ToolName: "Bash", ToolInput: map[string]interface{}{"command": "sudo cat /etc/hosts"}},
+			allowed: true,
+			rule:    "commands,workspace",
+			audited: []string{"commands: command is blocked by configuration: sudo", "workspace: "},
+		},
+		{
+			name:  "WATCHMAN_MODE keeps protected paths",
+			cfg:   config.Config{Protected: config.ProtectedConfig{Paths: []string{"secrets/"}}},
+			env:   config.ModeAudit,
+			input: Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "secrets/key"}, CWD: "/project"},
+			rule:  "protected",
+		},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.cmd, func(t *testing.T) {
-			got := isCommandInPosition(tt.cmd, tt.pattern)
-			if got != tt.want {
-				t.Errorf("isCommandInPosition(%q, %q) = %v, want %v", tt.cmd, tt.pattern, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHMAN_MODE", tt.env)
+			result := NewDryRunEvaluator(&tt.cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Fatalf("result = %+v, want allowed %v", result, tt.allowed)
+			}
+			if result.Warning != "" {
+				t.Errorf("audited violations should not warn the agent: %q", result.Warning)
+			}
+			if got := result.DecidingRule(); got != tt.rule {
+				t.Errorf("deciding rule = %q, want %q", got, tt.rule)
+			}
+			audited := result.Audited()
+			if len(audited) != len(tt.audited) {
+				t.Fatalf("audited = %q, want %d violations", audited, len(tt.audited))
+			}
+			for i, want := range tt.audited {
+				if !strings.Contains(audited[i], want) {
+					t.Errorf("audited[%d] = %q, want it to contain %q", i, audited[i], want)
+				}
 			}
 		})
 	}
 }
 
-func TestSplitCommandSegments(t *testing.T) {
+func TestEvaluatorEvaluateWrappedCommands(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Workspace: true, Versioning: true, Scope: true},
+		Commands: config.CommandsConfig{
+			Block: []string{"curl", "rm -rf"},
+		},
+		Scope: config.ScopeConfig{Allow: []string{"src/**"}},
+		Versioning: config.VersioningConfig{
+			Commit: config.CommitConfig{RequireUppercase: true},
+		},
+	}
+	e := NewEvaluator(cfg)
+
 	tests := []struct {
-		cmd  string
-		want int // number of segments
+		name    string
+		command string
+		rule    string // deciding rule, empty when allowed
 	}{
-		{"ls", 1},
-		{"ls | grep foo", 2},
-		{"ls && pwd", 2},
-		{"ls || pwd", 2},
-		{"ls; pwd", 2},
-		{"ls | grep foo && pwd", 3},
-		{"echo 'hello | world'", 1}, // quoted pipe should not split
+		{"bash -c", `bash -c "rm -rf ~"`, "commands"},
+		{"env", `env FOO=1 curl https://example.com`, "commands"},
+		{"timeout", `timeout 5 curl https://example.com`, "commands"},
+		{"xargs", `ls | xargs rm -rf`, "commands"},
+		{"find exec", `find . -exec rm -rf {} \;`, "commands"},
+		{"git alias", `git -c alias.x='!curl https://example.com' x`, "commands"},
+		{"versioning in sh -c", `sh -c 'git commit -m "lowercase"'`, "versioning"},
+		{"workspace in sudo", `sudo cat /etc/shadow`, "workspace"},
+		{"scope in nohup", `nohup cp src/a.go internal/a.go`, "scope"},
+		{"plain launcher", `timeout 5 go test ./...`, ""},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.cmd, func(t *testing.T) {
-			got := len(splitCommandSegments(tt.cmd))
-			if got != tt.want {
-				t.Errorf("splitCommandSegments(%q) returned %d segments, want %d", tt.cmd, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(Input{
+				ToolName:  "Bash",
+				ToolInput: map[string]interface{}{"command": tt.command},
+			})
+			if tt.rule == "" {
+				if !result.Allowed {
+					t.Errorf("expected %q to be allowed: %s", tt.command, result.Reason)
+				}
+				return
+			}
+			if result.Allowed || result.DecidingRule() != tt.rule {
+				t.Errorf("expected %q to be denied by %s, got %q (%s)", tt.command, tt.rule, result.DecidingRule(), result.Reason)
 			}
 		})
 	}
 }
 
-func TestExtractCommandName(t *testing.T) {
+func TestEvaluatorEvaluateExpandedPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
 	tests := []struct {
-		segment string
-		want    string
+		name         string
+		command      string
+		onUnresolved string
+		allowed      bool
+		warning      bool
 	}{
-		{"ls -la", "ls"},
-		{"VAR=value cmd", "cmd"},
-		{"FOO=1 BAR=2 command arg", "command"},
-		{"  dd if=/dev/zero", "dd"},
-		{"'quoted cmd'", "quoted cmd"},
+		{"home variable", "cat $HOME/notes.txt", "", false, false},
+		{"braced home", "ls ${HOME}/projects", "", false, false},
+		{"earlier assignment", "D=/etc; cat $D/passwd", "", false, false},
+		{"pwd inside", `cat "$PWD/main.go"`, "", true, false},
+		{"pwd escape", `cat "$PWD/../x"`, "", false, false},
+		{"unresolved warns by default", "cat $(git rev-parse --show-toplevel)/x", "", true, true},
+		{"unresolved denied", "cat $(git rev-parse --show-toplevel)/x", config.UnresolvedDeny, false, false},
+		{"unresolved allowed", "cat $(git rev-parse --show-toplevel)/x", config.UnresolvedAllow, true, false},
+		{"commit message substitution", `git commit -m "$(cat msg.txt)"`, config.UnresolvedDeny, true, false},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.segment, func(t *testing.T) {
-			got := extractCommandName(tt.segment)
-			if got != tt.want {
-				t.Errorf("extractCommandName(%q) = %q, want %q", tt.segment, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{OnUnresolved: tt.onUnresolved},
+			}
+			cwd := t.TempDir()
+			result := NewEvaluator(cfg).Evaluate(Input{
+				ToolName:  "Bash",
+				ToolInput: map[string]interface{}{"command": tt.command},
+				CWD:       cwd,
+			})
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+			if (result.Warning != "") != tt.warning {
+				t.Errorf("Warning = %q, want warning: %v", result.Warning, tt.warning)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateSymlinks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(cwd, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"root":   "/",
+		"keys":   filepath.Join(home, ".ssh"),
+		"alias":  "sub",
+		"dangle": filepath.Join(outside, "new.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(cwd, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		input   Input
+		allowed bool
+		reason  string
+	}{
+		{"read through link to root", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "root/etc/passwd"}}, false, "outside project"},
+		{"write through link to protected dir", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(cwd, "keys/id_rsa")}}, false, "path is protected"},
+		{"write through dangling link", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": "dangle"}}, false, "outside project"},
+		{"read through link inside", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "alias/main.go"}}, true, ""},
+		{"ln to outside", bashInput("ln -s /etc etc", ""), false, "link etc points to /etc"},
+		{"ln to protected", bashInput("ln -sf ~/.ssh/id_rsa key", ""), false, "protected path: link key"},
+		{"ln relative to link directory", bashInput("ln -s ../../.. sub/up", ""), false, "workspace boundary"},
+		{"ln inside", bashInput("ln -s main.go sub/", ""), true, ""},
+		{"ln up from link directory", bashInput("ln -s ../main.go sub/main.go", ""), true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Rules: config.RulesConfig{Workspace: true}}
+			tt.input.CWD = cwd
+			result := NewEvaluator(cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+			if !strings.Contains(result.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", result.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateWorkspaceRoot(t *testing.T) {
+	root := t.TempDir()
+	cwd := filepath.Join(root, "pkg", "a")
+
+	tests := []struct {
+		name    string
+		root    config.Roots
+		path    string
+		allowed bool
+	}{
+		{"sibling outside cwd", nil, "../b/b.go", false},
+		{"sibling inside root", config.Roots{root}, "../b/b.go", true},
+		{"outside root", config.Roots{root}, "../../../x", false},
+		{"second root", config.Roots{"cwd", "/srv/shared"}, "/srv/shared/lib.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Rules:     config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{Root: tt.root},
+			}
+			result := NewEvaluator(cfg).Evaluate(Input{
+				ToolName:  "Read",
+				ToolInput: map[string]interface{}{"file_path": tt.path},
+				CWD:       cwd,
+			})
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateReadOnlyAllow(t *testing.T) {
+	sdk := t.TempDir()
+	out := t.TempDir()
+	cwd := t.TempDir()
+	lib := filepath.Join(sdk, "lib.go")
+
+	tests := []struct {
+		name    string
+		input   Input
+		allowed bool
+	}{
+		{"read", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": lib}}, true},
+		{"grep", Input{ToolName: "Grep", ToolInput: map[string]interface{}{"pattern": "x", "path": sdk}}, true},
+		{"write", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": lib, "content": "x"}}, false},
+		{"edit", Input{ToolName: "Edit", ToolInput: map[string]interface{}{"file_path": lib}}, false},
+		{"bash read", bashInput("cat "+lib, ""), true},
+		{"bash copy into", bashInput("cp main.go "+sdk, ""), false},
+		{"bash writable target", bashInput("go test ./... > "+filepath.Join(out, "test.log"), ""), true},
+		{"bash read writable", bashInput("cat "+filepath.Join(out, "test.log"), ""), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Rules: config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{
+					ReadAllow:  []string{sdk + "/"},
+					WriteAllow: []string{out + "/"},
+				},
+			}
+			tt.input.CWD = cwd
+			result := NewEvaluator(cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateConfigProtected(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cwd := t.TempDir()
+	kube := filepath.Join(home, ".kube", "config")
+
+	tests := []struct {
+		name    string
+		input   Input
+		allowed bool
+	}{
+		{"read protected path", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": kube}}, false},
+		{"bash protected path", bashInput("cat ~/.kube/config", ""), false},
+		{"protected filename", Input{ToolName: "Edit", ToolInput: map[string]interface{}{"file_path": filepath.Join(cwd, "web", ".npmrc")}}, false},
+		{"protected glob", bashInput("cp vault.kdbx backup/", ""), false},
+		{"link to protected path", bashInput("ln -s ~/.kube kube", ""), false},
+		{"unprotected file", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": filepath.Join(cwd, "main.go")}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Rules: config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{
+					Allow: []string{home + "/"},
+				},
+				Protected: config.ProtectedConfig{
+					Paths:     []string{"~/.kube/"},
+					Filenames: []string{".npmrc"},
+					Globs:     []string{"*.kdbx"},
+				},
+			}
+			tt.input.CWD = cwd
+			result := NewEvaluator(cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
 			}
 		})
 	}
 }
+
+func TestEvaluatorEvaluateProtectedFromSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo, _ := filepath.EvalSymlinks(t.TempDir())
+	cwd := filepath.Join(repo, "svc", "billing")
+	os.MkdirAll(cwd, 0755)
+	os.MkdirAll(filepath.Join(repo, "secrets"), 0755)
+	os.WriteFile(filepath.Join(repo, "secrets", "k"), []byte("key"), 0600)
+	if out, err := exec.Command("git", "-C", repo, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	tests := []struct {
+		name    string
+		input   Input
+		allowed bool
+	}{
+		{"absolute path", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": filepath.Join(repo, "secrets", "k")}}, false},
+		{"relative to the subdirectory", bashInput("cat ../../secrets/k", ""), false},
+		{"same name in the subdirectory", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "secrets/k"}}, true},
+		{"unprotected file", Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": filepath.Join(repo, "README.md")}}, true},
+	}
+
+	cfg := &config.Config{
+		Rules:     config.RulesConfig{Workspace: true},
+		Workspace: config.WorkspaceConfig{Root: config.Roots{config.RootGit}},
+		Protected: config.ProtectedConfig{Paths: []string{"secrets/"}},
+	}
+	e := NewEvaluator(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.CWD = cwd
+			result := e.Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+			if !tt.allowed && !strings.Contains(result.Reason, "protected") {
+				t.Errorf("expected a protected path denial, got %q", result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateIncrementalWarningKeepsEvaluating(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo, _ := filepath.EvalSymlinks(t.TempDir())
+	if out, err := exec.Command("git", "-C", repo, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		os.WriteFile(filepath.Join(repo, name), []byte("package main\n"), 0644)
+	}
+	if out, err := exec.Command("git", "-C", repo, "add", ".").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+
+	cfg := &config.Config{
+		Rules:       config.RulesConfig{Incremental: true, Invariants: true},
+		Incremental: config.IncrementalConfig{MaxFiles: 1, OnViolation: config.ViolationWarn},
+		Invariants: config.InvariantsConfig{Content: []config.ContentCheck{
+			{Name: "no-todo", Paths: []string{"**/*.go"}, Forbid: "TODO"},
+		}},
+	}
+	e := NewEvaluator(cfg)
+
+	write := func(content string) Result {
+		return e.Evaluate(Input{
+			ToolName:  "Write",
+			ToolInput: map[string]interface{}{"file_path": "c.go", "content": content},
+			CWD:       repo,
+		})
+	}
+
+	result := write("// TODO")
+	if result.Allowed {
+		t.Fatal("expected the invariant to deny despite the incremental warning")
+	}
+	if !strings.Contains(result.Reason, "no-todo") {
+		t.Errorf("expected an invariant denial, got %q", result.Reason)
+	}
+	var rules []string
+	for _, step := range result.Trace {
+		rules = append(rules, step.Rule)
+	}
+	if !strings.Contains(strings.Join(rules, " "), "invariants") {
+		t.Errorf("trace rules = %v, want an invariants step", rules)
+	}
+
+	result = write("package main")
+	if !result.Allowed {
+		t.Fatalf("expected clean content to be allowed: %s", result.Reason)
+	}
+	if !strings.Contains(result.Warning, "incremental: maximum modified files reached") {
+		t.Errorf("expected the incremental warning, got %q", result.Warning)
+	}
+}
+
+func TestEvaluatorEvaluateOwnFilesProtected(t *testing.T) {
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+	logs := t.TempDir()
+	auditLog := filepath.Join(logs, "decisions.jsonl")
+
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{"session state", filepath.Join(stateHome, "watchman", "0123", "s.json"), false},
+		{"audit log", auditLog, false},
+		{"rotated audit log", auditLog + ".2", false},
+		{"next to the audit log", filepath.Join(logs, "notes.md"), true},
+	}
+
+	cfg := &config.Config{
+		Rules:     config.RulesConfig{Workspace: true},
+		Workspace: config.WorkspaceConfig{WriteAllow: []string{stateHome + "/", logs + "/"}},
+		Audit:     config.AuditConfig{Path: auditLog},
+	}
+	e := NewEvaluator(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": tt.path}, CWD: t.TempDir()}
+			if result := e.Evaluate(input); result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateDirectoryConfig(t *testing.T) {
+	repo := t.TempDir()
+	billing := filepath.Join(repo, "services", "billing")
+
+	tests := []struct {
+		name    string
+		input   Input
+		allowed bool
+	}{
+		{"directory scope allows", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(billing, "invoice.go"), "content": "package billing"}}, true},
+		{"directory scope denies", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(billing, "notes.md")}}, false},
+		{"directory scope from relative path", bashInput("touch services/billing/notes.md", ""), false},
+		{"outside the directory", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(repo, "notes.md")}}, true},
+		{"directory invariant", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(billing, "tax.go"), "content": "float64"}}, false},
+		{"directory invariant outside", Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": filepath.Join(repo, "tax.go"), "content": "float64"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Rules: config.RulesConfig{Scope: true, Invariants: true},
+				Directories: []config.DirectoryConfig{{
+					Dir:   billing,
+					Scope: config.ScopeConfig{Allow: []string{"*.go"}},
+					Invariants: config.InvariantsConfig{
+						Content: []config.ContentCheck{{Name: "no-floats", Paths: []string{"*.go"}, Forbid: "float64"}},
+					},
+				}},
+			}
+			tt.input.CWD = repo
+			result := NewEvaluator(cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateRequestContext(t *testing.T) {
+	cwd := t.TempDir()
+	capture := filepath.Join(t.TempDir(), "input.json")
+	t.Setenv("WATCHMAN_CAPTURE", capture)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	cfg := &config.Config{
+		Protected: config.ProtectedConfig{Paths: []string{"deploy/key"}},
+		Hooks: []config.HookConfig{
+			{Name: "capture", Command: testdataPath("capture.sh"), Tools: []string{"Write"}},
+		},
+		Reminders: []config.ReminderConfig{{Name: "tests", Message: "run the tests", EveryTasks: 1}},
+	}
+	e := NewEvaluator(cfg)
+
+	input := Input{
+		HookType:       config.EventPreToolUse,
+		ToolName:       "Write",
+		ToolInput:      map[string]interface{}{"file_path": "main.go"},
+		CWD:            cwd,
+		SessionID:      "s-1",
+		TranscriptPath: "/tmp/s-1.jsonl",
+		PermissionMode: "acceptEdits",
+	}
+	if result := e.Evaluate(input); !result.Allowed {
+		t.Fatalf("expected allow: %s", result.Reason)
+	}
+
+	data, err := os.ReadFile(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got HookInput
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.WorkingDir != cwd || got.SessionID != "s-1" || got.TranscriptPath != "/tmp/s-1.jsonl" || got.PermissionMode != "acceptEdits" {
+		t.Errorf("hook input = %+v, want the request context", got)
+	}
+
+	if _, err := os.Stat(state.NewManager(input.Context()).StatePath()); err != nil {
+		t.Errorf("reminder state should be kept per session for the agent's directory: %v", err)
+	}
+
+	input.ToolName = "Read"
+	input.ToolInput = map[string]interface{}{"file_path": "deploy/key"}
+	if result := e.Evaluate(input); result.Allowed {
+		t.Error("expected deploy/key to be protected relative to the agent's directory")
+	}
+}
+
+func TestEvaluatorRemindersConcurrentSessions(t *testing.T) {
+	cwd := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	cfg := &config.Config{
+		Reminders: []config.ReminderConfig{{Name: "tests", Message: "run the tests", EveryTasks: 1000}},
+	}
+	e := NewEvaluator(cfg)
+
+	const calls = 20
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Evaluate(Input{HookType: config.EventPreToolUse, ToolName: "Read", CWD: cwd, SessionID: "s-1"})
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(state.NewManager(Input{CWD: cwd, SessionID: "s-1"}.Context()).StatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got state.State
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TaskCount != calls {
+		t.Errorf("task count = %d, want %d: concurrent updates were lost", got.TaskCount, calls)
+	}
+}
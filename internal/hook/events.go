@@ -0,0 +1,202 @@
+package hook
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+// isToolEvent reports whether the event carries a tool call.
+func isToolEvent(event string) bool {
+	return event == config.EventPreToolUse || event == config.EventPostToolUse
+}
+
+// evaluatePostToolUse runs the rules on the call that declare PostToolUse,
+// checks the files a tool just wrote, as they are on disk, and shows the
+// reminders that trigger after commits.
+// The tool already ran, so a deny here is fed back to the agent rather than preventing anything.
+func (e *Evaluator) evaluatePostToolUse(input Input, t *trace) Result {
+	result := e.evaluateCallAfter(input, t)
+	if !result.Allowed {
+		return result
+	}
+	warning := result.Warning
+
+	switch {
+	case !e.cfg.Rules.Invariants:
+		t.skip("invariants", "rule disabled")
+	case !config.RunsOn(e.cfg.Invariants.Events, config.EventPostToolUse):
+		t.skip("invariants", "not enabled for PostToolUse")
+	case input.ToolName == "Bash":
+		paths := writtenPaths(bashWrites(input))
+		if len(paths) == 0 {
+			t.skip("invariants", "command writes no files")
+		} else if result := t.record("invariants", e.checkFilesOnDisk(paths, input.CWD)); !result.Allowed {
+			return result
+		} else {
+			warning = joinWarnings(warning, result.Warning)
+		}
+	case !isModificationTool(input.ToolName):
+		t.skip("invariants", "not a modification tool")
+	default:
+		paths := inputPaths(input)
+		result := t.record("invariants", e.checkFilesOnDisk(paths, input.CWD))
+		if !result.Allowed {
+			return result
+		}
+		warning = joinWarnings(warning, result.Warning)
+	}
+
+	return e.withReminders(input, withWarning(e.evaluateHooks(input, t), warning), t)
+}
+
+// evaluateCallAfter runs the rules on the tool call itself whose events
+// include PostToolUse, in the order they run on PreToolUse. There is no one
+// to ask once the call ran, so an ask is reported like a deny.
+func (e *Evaluator) evaluateCallAfter(input Input, t *trace) Result {
+	event := config.EventPostToolUse
+	bash := input.ToolName == "Bash"
+	files := isFilesystemTool(input.ToolName)
+
+	rules := []struct {
+		name  string
+		runs  bool
+		check func() Result
+	}{
+		{"protected", files && config.RunsOn(e.cfg.Protected.Events, event), func() Result {
+			return e.evaluateProtected(input)
+		}},
+		{"commands", bash && config.RunsOn(e.cfg.Commands.Events, event), func() Result {
+			return e.enforce("commands", e.cfg.Commands.Mode, e.cfg.Commands.OnViolation, e.evaluateCommands(input))
+		}},
+		{"workspace", files && e.cfg.Rules.Workspace && config.RunsOn(e.cfg.Workspace.Events, event), func() Result {
+			return e.enforce("workspace", e.cfg.Workspace.Mode, e.cfg.Workspace.OnViolation, e.evaluateWorkspace(input))
+		}},
+		{"scope", files && e.cfg.Rules.Scope && config.RunsOn(e.cfg.Scope.Events, event), func() Result {
+			return e.enforce("scope", e.cfg.Scope.Mode, e.cfg.Scope.OnViolation, e.evaluateScope(input))
+		}},
+		{"versioning", bash && e.cfg.Rules.Versioning && config.RunsOn(e.cfg.Versioning.Events, event), func() Result {
+			return e.enforce("versioning", e.cfg.Versioning.Mode, e.cfg.Versioning.OnViolation, e.evaluateVersioning(input))
+		}},
+	}
+
+	var warning string
+	for _, r := range rules {
+		if !r.runs {
+			continue
+		}
+		result := t.record(r.name, r.check())
+		if !result.Allowed {
+			return result
+		}
+		warning = joinWarnings(warning, result.Warning)
+	}
+	return Result{Allowed: true, Warning: warning}
+}
+
+// evaluateSessionStart hands the agent a summary of the active policy and the
+// reminders that trigger at session start.
+func (e *Evaluator) evaluateSessionStart(input Input, t *trace) Result {
+	summary := t.record("policy", Result{Allowed: true, Warning: Summary(e.cfg, input.CWD)})
+
+	result := e.evaluateHooks(input, t)
+	if !result.Allowed {
+		return result
+	}
+	return e.withReminders(input, Result{Allowed: true, Warning: joinWarnings(summary.Warning, result.Warning)}, t)
+}
+
+// evaluateUserPromptSubmit runs the hooks registered for prompts.
+func (e *Evaluator) evaluateUserPromptSubmit(input Input, t *trace) Result {
+	return e.evaluateHooks(input, t)
+}
+
+// evaluateStop checks the whole working tree before the agent finishes.
+func (e *Evaluator) evaluateStop(input Input, t *trace) Result {
+	// The agent is already continuing because of a previous block; blocking
+	// again could keep it from ever stopping.
+	if input.StopHookActive {
+		t.skip("stop", "already continuing after a stop hook")
+		return Result{Allowed: true}
+	}
+
+	switch {
+	case !e.cfg.Rules.Invariants:
+		t.skip("invariants", "rule disabled")
+	case !config.RunsOn(e.cfg.Invariants.Events, config.EventStop):
+		t.skip("invariants", "not enabled for Stop")
+	default:
+		paths, err := changedFiles(input.CWD)
+		if err != nil {
+			t.skip("invariants", "cannot list changed files: "+err.Error())
+		} else if result := t.record("invariants", e.checkFilesOnDisk(paths, input.CWD)); !result.Allowed {
+			return result
+		}
+	}
+
+	switch {
+	case !e.cfg.Rules.Incremental:
+		t.skip("incremental", "rule disabled")
+	case !config.RunsOn(e.cfg.Incremental.Events, config.EventStop):
+		t.skip("incremental", "not enabled for Stop")
+	default:
+		if result := t.record("incremental", e.enforce("incremental", e.cfg.Incremental.Mode, e.cfg.Incremental.OnViolation, e.evaluateIncremental(input))); !result.Allowed {
+			return result
+		}
+	}
+
+	return e.evaluateHooks(input, t)
+}
+
+// checkFilesOnDisk runs the invariants against the current content of each file,
+// applying the mode and on_violation of invariants to a violation.
+// Paths are evaluated as given; relative paths are read from cwd.
+func (e *Evaluator) checkFilesOnDisk(paths []string, cwd string) Result {
+	var audit []string
+	for _, p := range paths {
+		content, err := os.ReadFile(resolve(p, cwd))
+		if err != nil {
+			continue // deleted or unreadable, nothing to check
+		}
+		decision := e.checkInvariants(p, string(content), cwd)
+		audit = append(audit, prefixed(p+": ", decision.Audit)...)
+		if !decision.Allowed {
+			result := Result{Allowed: false, Ask: decision.Ask, Reason: p + ": " + decision.Reason, Audit: audit}
+			return e.enforce("invariants", e.cfg.Invariants.Mode, e.cfg.Invariants.OnViolation, result)
+		}
+	}
+	return Result{Allowed: true, Audit: audit}
+}
+
+// changedFiles lists modified and untracked files under dir, relative to dir.
+func changedFiles(dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "--modified", "--others", "--exclude-standard")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		files = append(files, line)
+	}
+	return files, nil
+}
+
+func joinWarnings(warnings ...string) string {
+	var parts []string
+	for _, w := range warnings {
+		if w != "" {
+			parts = append(parts, w)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
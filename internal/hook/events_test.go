@@ -0,0 +1,212 @@
+package hook
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func noTODOConfig(events ...string) *config.Config {
+	return &config.Config{
+		Rules: config.RulesConfig{Invariants: true},
+		Invariants: config.InvariantsConfig{
+			Events: events,
+			Content: []config.ContentCheck{
+				{Name: "no-todo", Paths: []string{"**/*.go"}, Forbid: "TODO"},
+			},
+		},
+	}
+}
+
+func TestParseInputEventName(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{"hook_event_name", `{"hook_event_name":"Stop"}`, "Stop"},
+		{"legacy hook_type", `{"hook_type":"PostToolUse"}`, "PostToolUse"},
+		{"event name wins", `{"hook_event_name":"Stop","hook_type":"PreToolUse"}`, "Stop"},
+		{"unnamed is PreToolUse", `{"tool_name":"Bash"}`, "PreToolUse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := ParseInput([]byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := input.Event(); got != tt.want {
+				t.Errorf("Event() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePostToolUseChecksFileOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // TODO"), 0644)
+
+	// Edit carries no full content, so only the file on disk shows the violation.
+	input := Input{
+		HookType:  config.EventPostToolUse,
+		ToolName:  "Edit",
+		ToolInput: map[string]interface{}{"file_path": "main.go", "new_string": "// TODO"},
+		CWD:       dir,
+	}
+
+	result := NewEvaluator(noTODOConfig(config.EventPostToolUse)).Evaluate(input)
+	if result.Allowed {
+		t.Fatal("expected PostToolUse to block")
+	}
+	if !strings.Contains(result.Reason, "main.go") {
+		t.Errorf("reason should name the file: %s", result.Reason)
+	}
+
+	result = NewEvaluator(noTODOConfig()).Evaluate(input)
+	if !result.Allowed {
+		t.Errorf("invariants without PostToolUse event should not run: %s", result.Reason)
+	}
+}
+
+func TestEvaluatePreToolUseRespectsInvariantEvents(t *testing.T) {
+	input := Input{
+		HookType:  config.EventPreToolUse,
+		ToolName:  "Write",
+		ToolInput: map[string]interface{}{"file_path": "main.go", "content": "// TODO"},
+	}
+
+	if result := NewEvaluator(noTODOConfig()).Evaluate(input); result.Allowed {
+		t.Error("expected default events to include PreToolUse")
+	}
+	if result := NewEvaluator(noTODOConfig(config.EventStop)).Evaluate(input); !result.Allowed {
+		t.Errorf("expected Stop-only invariants to skip PreToolUse: %s", result.Reason)
+	}
+}
+
+func TestEvaluateRuleEvents(t *testing.T) {
+	dir := t.TempDir()
+	write := func(event string) Input {
+		return Input{
+			HookType:  event,
+			ToolName:  "Write",
+			ToolInput: map[string]interface{}{"file_path": "vendor/lib.go", "content": "x"},
+			CWD:       dir,
+		}
+	}
+	bash := func(event, command string) Input {
+		input := bashInput(command, dir)
+		input.HookType = event
+		return input
+	}
+	pre, post := config.EventPreToolUse, config.EventPostToolUse
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		input   Input
+		allowed bool
+	}{
+		{"scope defaults to PreToolUse", config.Config{Rules: config.RulesConfig{Scope: true}, Scope: config.ScopeConfig{Block: []string{"vendor/**"}}}, write(pre), false},
+		{"scope not on PostToolUse by default", config.Config{Rules: config.RulesConfig{Scope: true}, Scope: config.ScopeConfig{Block: []string{"vendor/**"}}}, write(post), true},
+		{"scope on PostToolUse only", config.Config{Rules: config.RulesConfig{Scope: true}, Scope: config.ScopeConfig{Block: []string{"vendor/**"}, Events: []string{post}}}, write(pre), true},
+		{"scope after the call", config.Config{Rules: config.RulesConfig{Scope: true}, Scope: config.ScopeConfig{Block: []string{"vendor/**"}, Events: []string{post}}}, write(post), false},
+		{"workspace after the call", config.Config{Rules: config.RulesConfig{Workspace: true}, Workspace: config.WorkspaceConfig{Events: []string{pre, post}}}, bash(post, "cat /etc/hosts"), false},
+		{"workspace on PostToolUse only", config.Config{Rules: config.RulesConfig{Workspace: true}, Workspace: config.WorkspaceConfig{Events: []string{post}}}, bash(pre, "cat /etc/hosts"), true},
+		{"commands after the call", config.Config{Commands: config.CommandsConfig{Block: []string{"sudo"}, Events: []string{post}}}, bash(post, "sudo ls"), false},
+		{"commands on PostToolUse only", config.Config{Commands: config.CommandsConfig{Block: []string{"sudo"}, Events: []string{post}}}, bash(pre, "sudo ls"), true},
+		{"versioning after the call", config.Config{Rules: config.RulesConfig{Versioning: true}, Versioning: config.VersioningConfig{Operations: config.OperationsConfig{Block: []string{"push --force"}}, Events: []string{post}}}, bash(post, "git push --force"), false},
+		{"protected always before the call", config.Config{Protected: config.ProtectedConfig{Paths: []string{"vendor/"}, Events: []string{post}}}, write(pre), false},
+		{"protected after the call", config.Config{Protected: config.ProtectedConfig{Paths: []string{"vendor/"}, Events: []string{post}}}, write(post), false},
+		{"protected not after the call by default", config.Config{Protected: config.ProtectedConfig{Paths: []string{"vendor/"}}}, write(post), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewEvaluator(&tt.cfg).Evaluate(tt.input)
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluateStop(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	os.WriteFile(filepath.Join(dir, "ok.go"), []byte("package ok"), 0644)
+
+	e := NewEvaluator(noTODOConfig(config.EventStop))
+	input := Input{HookType: config.EventStop, CWD: dir}
+
+	if result := e.Evaluate(input); !result.Allowed {
+		t.Fatalf("expected clean tree to allow stop: %s", result.Reason)
+	}
+
+	os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package bad // TODO"), 0644)
+	result := e.Evaluate(input)
+	if result.Allowed || !strings.Contains(result.Reason, "bad.go") {
+		t.Fatalf("expected stop to be blocked on bad.go, got %+v", result)
+	}
+
+	input.StopHookActive = true
+	if result := e.Evaluate(input); !result.Allowed {
+		t.Errorf("expected stop_hook_active to allow: %s", result.Reason)
+	}
+}
+
+func TestEvaluateSessionStart(t *testing.T) {
+	cfg := &config.Config{
+		Rules:    config.RulesConfig{Workspace: true},
+		Commands: config.CommandsConfig{Block: []string{"sudo"}},
+	}
+
+	result := NewEvaluator(cfg).Evaluate(Input{HookType: config.EventSessionStart})
+	if !result.Allowed {
+		t.Fatalf("SessionStart must not block: %s", result.Reason)
+	}
+	for _, want := range []string{"workspace", "sudo"} {
+		if !strings.Contains(result.Warning, want) {
+			t.Errorf("summary missing %q:\n%s", want, result.Warning)
+		}
+	}
+}
+
+func TestEvaluateEventHooks(t *testing.T) {
+	cfg := &config.Config{
+		Hooks: []config.HookConfig{
+			{Name: "prompt-guard", Command: testdataPath("deny.sh"), Events: []string{config.EventUserPromptSubmit}},
+			{Name: "pre-only", Command: testdataPath("deny.sh"), Tools: []string{"Write"}},
+		},
+	}
+	e := NewEvaluator(cfg)
+
+	result := e.Evaluate(Input{HookType: config.EventUserPromptSubmit, Prompt: "hello"})
+	if result.Allowed || result.Reason != "prompt-guard: test denial" {
+		t.Errorf("expected prompt-guard to block, got %+v", result)
+	}
+
+	result = e.Evaluate(Input{HookType: config.EventStop})
+	if !result.Allowed {
+		t.Errorf("no hook is registered for Stop: %s", result.Reason)
+	}
+
+	result = e.Evaluate(Input{
+		HookType:  config.EventPreToolUse,
+		ToolName:  "Write",
+		ToolInput: map[string]interface{}{"file_path": "a.txt"},
+	})
+	if result.Allowed || result.Reason != "pre-only: test denial" {
+		t.Errorf("expected pre-only to deny, got %+v", result)
+	}
+}
@@ -15,10 +15,15 @@ const defaultTimeout = 5 * time.Second
 
 // HookInput is the JSON structure sent to external hooks via stdin.
 type HookInput struct {
-	ToolName   string                 `json:"tool_name"`
-	ToolInput  map[string]interface{} `json:"tool_input"`
-	Paths      []string               `json:"paths"`
-	WorkingDir string                 `json:"working_dir"`
+	Event          string                 `json:"hook_event_name,omitempty"`
+	ToolName       string                 `json:"tool_name"`
+	ToolInput      map[string]interface{} `json:"tool_input"`
+	Paths          []string               `json:"paths"`
+	WorkingDir     string                 `json:"working_dir"` // the agent's working directory
+	SessionID      string                 `json:"session_id,omitempty"`
+	TranscriptPath string                 `json:"transcript_path,omitempty"`
+	PermissionMode string                 `json:"permission_mode,omitempty"`
+	Prompt         string                 `json:"prompt,omitempty"`
 }
 
 // HookOutput is the JSON structure expected from hook stdout.
@@ -109,6 +114,8 @@ func (e *HookExecutor) outputToResult(output HookOutput) Result {
 	switch output.Decision {
 	case "deny":
 		return Result{Allowed: false, Reason: output.Reason}
+	case "ask":
+		return Result{Allowed: false, Ask: true, Reason: output.Reason, Warning: output.Warning}
 	case "advise":
 		return Result{Allowed: true, Warning: output.Warning}
 	default:
@@ -69,6 +69,22 @@ func TestHookExecutorExecuteAdvise(t *testing.T) {
 	}
 }
 
+func TestHookExecutorExecuteAsk(t *testing.T) {
+	e := NewHookExecutor()
+	hookCfg := &config.HookConfig{
+		Name:    "test-ask",
+		Command: testdataPath("ask.sh"),
+	}
+
+	result := e.Execute(hookCfg, HookInput{})
+	if result.Allowed || !result.Ask {
+		t.Errorf("Execute() = %+v, want ask", result)
+	}
+	if result.Reason != "needs a human" {
+		t.Errorf("Execute() reason = %q, want %q", result.Reason, "needs a human")
+	}
+}
+
 func TestHookExecutorExecuteExitCodeFallback(t *testing.T) {
 	e := NewHookExecutor()
 	hookCfg := &config.HookConfig{
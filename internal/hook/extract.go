@@ -1,12 +1,16 @@
 package hook
 
-import "github.com/adrianpk/watchman/internal/parser"
+import (
+	"os"
+
+	"github.com/adrianpk/watchman/internal/parser"
+)
 
 // ExtractPaths extracts filesystem paths from tool input.
 func ExtractPaths(toolName string, toolInput map[string]interface{}) []string {
 	switch toolName {
 	case "Bash":
-		return extractBashPaths(toolInput)
+		return extractBashPaths(toolInput, "")
 	case "Read", "Write", "Edit":
 		return extractFilePath(toolInput)
 	case "Glob":
@@ -17,25 +21,64 @@ func ExtractPaths(toolName string, toolInput map[string]interface{}) []string {
 	return nil
 }
 
-func extractBashPaths(toolInput map[string]interface{}) []string {
+// inputPaths extracts the paths of a tool call, expanding Bash words
+// against the working directory of the call.
+func inputPaths(input Input) []string {
+	if input.ToolName == "Bash" {
+		return extractBashPaths(input.ToolInput, input.CWD)
+	}
+	return ExtractPaths(input.ToolName, input.ToolInput)
+}
+
+// bashCommands parses a Bash command and expands its variables and tildes
+// against the hook's environment, see parser.Expander.
+func bashCommands(command, cwd string) []parser.Command {
+	return parser.NewExpander(os.LookupEnv, cwd).ExpandAll(parser.ParseAll(command))
+}
+
+func extractBashPaths(toolInput map[string]interface{}, cwd string) []string {
 	cmdStr, ok := toolInput["command"].(string)
 	if !ok {
 		return nil
 	}
-	cmd := parser.Parse(cmdStr)
 	var paths []string
-	paths = append(paths, cmd.Args...)
-	for _, v := range cmd.Flags {
-		if v != "" {
+	for _, cmd := range bashCommands(cmdStr, cwd) {
+		links := symbolicTargets(cmd)
+		for _, a := range cmd.Args {
+			if !links[a] {
+				paths = append(paths, a)
+			}
+		}
+		for _, v := range cmd.Flags {
+			if v != "" {
+				paths = append(paths, v)
+			}
+		}
+		for _, v := range cmd.Env {
 			paths = append(paths, v)
 		}
-	}
-	for _, v := range cmd.Env {
-		paths = append(paths, v)
+		for _, r := range cmd.Redirects {
+			if r.IsFile() && !parser.IsDevice(r.Target) {
+				paths = append(paths, r.Target)
+			}
+		}
 	}
 	return paths
 }
 
+// symbolicTargets returns the targets of the symbolic links a command
+// creates. ln does not open them and they are relative to the link's
+// directory, so the workspace rule checks them as links, not as paths.
+func symbolicTargets(cmd parser.Command) map[string]bool {
+	targets := make(map[string]bool)
+	for _, w := range parser.CommandWrites(cmd) {
+		if w.Symbolic {
+			targets[w.LinkTo] = true
+		}
+	}
+	return targets
+}
+
 func extractFilePath(toolInput map[string]interface{}) []string {
 	if fp, ok := toolInput["file_path"].(string); ok {
 		return []string{fp}
@@ -1,6 +1,9 @@
 package hook
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestExtractPaths(t *testing.T) {
 	tests := []struct {
@@ -100,7 +103,7 @@ func TestExtractBashPaths(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := extractBashPaths(tt.input)
+			got := extractBashPaths(tt.input, "")
 			if len(got) < tt.wantLen {
 				t.Errorf("extractBashPaths() returned %d paths, want at least %d", len(got), tt.wantLen)
 			}
@@ -108,6 +111,41 @@ func TestExtractBashPaths(t *testing.T) {
 	}
 }
 
+func TestExtractBashPathsStructured(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("NOTES", "/home/me/notes")
+
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{"list", "cat a && cat /etc/shadow", []string{"a", "/etc/shadow"}},
+		{"command substitution", "echo $(cat ~/.ssh/id_rsa)", []string{"$(cat ~/.ssh/id_rsa)", "/home/me/.ssh/id_rsa"}},
+		{"backticks", "echo `cat /etc/hosts`", []string{"`cat /etc/hosts`", "/etc/hosts"}},
+		{"process substitution", "diff <(sort /etc/passwd) b", []string{"/dev/fd/63", "b", "/etc/passwd"}},
+		{"subshell", "(cd /tmp; ls)", []string{"/tmp"}},
+		{"redirects", "sort < in.txt > /tmp/out 2>&1", []string{"in.txt", "/tmp/out"}},
+		{"discarded output", "ls 2>/dev/null", nil},
+		{"device input", "cat </dev/null", nil},
+		{"device and file redirects", "go test ./... > /dev/null 2>&1 < in.txt", []string{"./...", "in.txt"}},
+		{"subshell redirect", "(make) > ../build.log", []string{"../build.log"}},
+		{"heredoc body ignored", "cat <<EOF\n/etc/passwd\nEOF", nil},
+		{"quoted", `cat "my file.txt" 'other file'`, []string{"my file.txt", "other file"}},
+		{"variables", `D=/etc; cat $D/passwd "$NOTES/x" ${HOME}/.aws '$HOME'`, []string{"/etc", "/etc/passwd", "/home/me/notes/x", "/home/me/.aws", "$HOME"}},
+		{"unresolved kept as written", "cat $(pwd)/x $1", []string{"$(pwd)/x", "$1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractBashPaths(map[string]interface{}{"command": tt.command}, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractBashPaths(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestExtractFilePath(t *testing.T) {
 	tests := []struct {
 		name    string
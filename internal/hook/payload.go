@@ -0,0 +1,41 @@
+package hook
+
+import "encoding/json"
+
+// Payload is the JSON document Claude Code sends to the hook on stdin.
+// Claude Code names the event in hook_event_name; hook_type is the older spelling.
+type Payload struct {
+	HookEventName  string                 `json:"hook_event_name"`
+	HookType       string                 `json:"hook_type"`
+	ToolName       string                 `json:"tool_name"`
+	ToolInput      map[string]interface{} `json:"tool_input"`
+	CWD            string                 `json:"cwd"`
+	SessionID      string                 `json:"session_id"`
+	TranscriptPath string                 `json:"transcript_path"`
+	PermissionMode string                 `json:"permission_mode"`
+	Prompt         string                 `json:"prompt"`
+	StopHookActive bool                   `json:"stop_hook_active"`
+}
+
+// ParseInput decodes a hook payload into an evaluation input.
+func ParseInput(data []byte) (Input, error) {
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Input{}, err
+	}
+	hookType := p.HookEventName
+	if hookType == "" {
+		hookType = p.HookType
+	}
+	return Input{
+		HookType:       hookType,
+		ToolName:       p.ToolName,
+		ToolInput:      p.ToolInput,
+		CWD:            p.CWD,
+		SessionID:      p.SessionID,
+		TranscriptPath: p.TranscriptPath,
+		PermissionMode: p.PermissionMode,
+		Prompt:         p.Prompt,
+		StopHookActive: p.StopHookActive,
+	}, nil
+}
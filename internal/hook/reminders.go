@@ -0,0 +1,242 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/state"
+)
+
+// evaluateReminders checks the reminders against an allowed call and returns
+// the messages of those that trigger as a warning.
+func (e *Evaluator) evaluateReminders(input Input, t *trace) Result {
+	if len(e.cfg.Reminders) == 0 {
+		return Result{Allowed: true}
+	}
+
+	if e.dryRun {
+		t.skip("reminders", "dry run")
+		return Result{Allowed: true}
+	}
+
+	if messages := e.checkReminders(input, ""); len(messages) > 0 {
+		return t.record("reminders", Result{
+			Allowed: true,
+			Warning: strings.Join(messages, "; "),
+		})
+	}
+
+	return t.record("reminders", Result{Allowed: true})
+}
+
+// withReminders combines a result with any triggered reminders.
+// Should be called for all allowed operations to ensure reminders are tracked.
+func (e *Evaluator) withReminders(input Input, result Result, t *trace) Result {
+	if !result.Allowed {
+		return result
+	}
+
+	reminderResult := e.evaluateReminders(input, t)
+	if reminderResult.Warning != "" {
+		if result.Warning != "" {
+			result.Warning = result.Warning + "; " + reminderResult.Warning
+		} else {
+			result.Warning = reminderResult.Warning
+		}
+	}
+	return result
+}
+
+// recordDenial keeps the reason of a denied call for ${denied_reason}, and
+// the reminders it triggers for the next allowed call.
+func (e *Evaluator) recordDenial(input Input, reason string) {
+	if len(e.cfg.Reminders) == 0 || e.dryRun {
+		return
+	}
+	e.checkReminders(input, reason)
+}
+
+// checkReminders records the call in the session state and returns the
+// messages of the reminders it triggers. Errors are ignored: reminders are
+// not critical.
+func (e *Evaluator) checkReminders(input Input, denied string) []string {
+	a := activity(input, denied)
+
+	var triggered []state.Trigger
+	sm := state.NewManager(input.Context())
+	if err := sm.Update(func() {
+		triggered = sm.CheckReminders(e.cfg.Reminders, a)
+	}); err != nil {
+		return nil
+	}
+
+	var messages []string
+	for _, tr := range triggered {
+		if msg, err := reminderMessage(tr, input, sm.State()); err == nil && msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// activity describes a call for the reminders. Paths are relative to the
+// agent's directory when they are inside it.
+func activity(input Input, denied string) state.Activity {
+	a := state.Activity{Event: input.Event(), Tool: input.ToolName, Denied: denied}
+	if !isToolEvent(a.Event) {
+		return a
+	}
+
+	a.Paths = relativePaths(inputPaths(input), input.CWD)
+	switch {
+	case input.ToolName == "Bash":
+		a.Modified = relativePaths(writtenPaths(bashWrites(input)), input.CWD)
+		a.Commit = isCommit(input)
+	case isModificationTool(input.ToolName):
+		a.Modified = a.Paths
+	}
+	return a
+}
+
+func relativePaths(paths []string, cwd string) []string {
+	var rel []string
+	for _, p := range paths {
+		r, err := filepath.Rel(cwd, resolve(p, cwd))
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			r = p
+		}
+		rel = append(rel, r)
+	}
+	return rel
+}
+
+// isCommit reports whether a Bash call runs git commit or jj commit.
+func isCommit(input Input) bool {
+	command, _ := input.ToolInput["command"].(string)
+	for _, cmd := range bashCommands(command, input.CWD) {
+		switch {
+		case cmd.Program == "git" && cmd.Subcommand == "commit":
+			return true
+		case cmd.Program == "jj" && len(cmd.Args) > 0 && cmd.Args[0] == "commit":
+			return true
+		}
+	}
+	return false
+}
+
+// reminderMessage returns the message of a triggered reminder, read from
+// its message_file when it has one, with its variables expanded.
+func reminderMessage(tr state.Trigger, input Input, s *state.State) (string, error) {
+	msg := tr.Reminder.Message
+	if tr.Reminder.MessageFile != "" {
+		var err error
+		if msg, err = readMessageFile(tr.Reminder, input.CWD); err != nil {
+			return "", err
+		}
+	}
+
+	vars := []string{
+		"${modified_files}", strconv.Itoa(len(s.Modified)),
+		"${denied_reason}", s.LastDenied,
+		"${file}", tr.File,
+		"${tool}", input.ToolName,
+		"${tasks}", strconv.Itoa(s.TaskCount),
+	}
+	if strings.Contains(msg, "${branch}") {
+		vars = append(vars, "${branch}", currentBranch(input.CWD))
+	}
+	return strings.NewReplacer(vars...).Replace(msg), nil
+}
+
+// readMessageFile reads the message of a reminder from its message_file,
+// relative to the repository around cwd, or only its section when set.
+func readMessageFile(r config.ReminderConfig, cwd string) (string, error) {
+	path := r.MessageFile
+	if !filepath.IsAbs(path) {
+		root := config.RepositoryRoot(cwd)
+		if root == "" {
+			root = cwd
+		}
+		path = filepath.Join(root, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+	if r.Section != "" {
+		section, ok := markdownSection(content, r.Section)
+		if !ok {
+			return "", fmt.Errorf("%s: no section %q", r.MessageFile, r.Section)
+		}
+		content = section
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// markdownSection returns the body of the first section of a Markdown
+// document titled title, up to the next heading of the same or a higher
+// level. Titles are compared ignoring case; headings in fenced code blocks
+// are not headings.
+func markdownSection(doc, title string) (string, bool) {
+	var body []string
+	level := 0 // level of the section being collected, 0 until it is found
+	fenced := false
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		} else if l, text := markdownHeading(line); l > 0 && !fenced {
+			if level > 0 && l <= level {
+				break
+			}
+			if level == 0 && strings.EqualFold(text, strings.TrimSpace(title)) {
+				level = l
+				continue
+			}
+		}
+		if level > 0 {
+			body = append(body, line)
+		}
+	}
+	if level == 0 {
+		return "", false
+	}
+	return strings.Join(body, "\n"), true
+}
+
+// markdownHeading returns the level and text of an ATX heading, or 0 when
+// the line is not one.
+func markdownHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, ""
+	}
+	text := strings.TrimSpace(rest)
+	text = strings.TrimSpace(strings.TrimRight(text, "#"))
+	return level, text
+}
+
+// currentBranch returns the branch checked out in dir, empty when unknown.
+func currentBranch(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,152 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestEvaluatorReminders(t *testing.T) {
+	read := Input{ToolName: "Read", ToolInput: map[string]interface{}{"file_path": "a.go"}}
+	write := func(path string) Input {
+		return Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": path}}
+	}
+	bash := func(event, command string) Input {
+		return Input{HookType: event, ToolName: "Bash", ToolInput: map[string]interface{}{"command": command}}
+	}
+
+	type step struct {
+		input Input
+		want  string // empty: no warning
+	}
+	tests := []struct {
+		name     string
+		reminder config.ReminderConfig
+		steps    []step
+	}{
+		{
+			name:     "counts only matching tools",
+			reminder: config.ReminderConfig{Name: "r", Message: "after ${tasks} calls: ${tool}", Tools: []string{"Write"}, EveryTasks: 2},
+			steps: []step{
+				{input: read},
+				{input: write("a.go")},
+				{input: read},
+				{input: write("b.go"), want: "after 4 calls: Write"},
+			},
+		},
+		{
+			name:     "same file",
+			reminder: config.ReminderConfig{Name: "r", Message: "${file} again, ${modified_files} files modified", Paths: []string{"*.go"}, SameFile: 2},
+			steps: []step{
+				{input: write("a.go")},
+				{input: write("notes.md")},
+				{input: write("notes.md")},
+				{input: write("a.go"), want: "a.go again, 2 files modified"},
+			},
+		},
+		{
+			name:     "after a denial",
+			reminder: config.ReminderConfig{Name: "r", Message: "denied: ${denied_reason}", On: config.ReminderOnDeny},
+			steps: []step{
+				{input: read},
+				{input: Input{ToolName: "WebFetch"}},
+				{input: read, want: "denied: tool is blocked by configuration: WebFetch"},
+				{input: read},
+			},
+		},
+		{
+			name:     "after a commit",
+			reminder: config.ReminderConfig{Name: "r", Message: "committed", On: config.ReminderOnCommit},
+			steps: []step{
+				{input: bash(config.EventPreToolUse, "git commit -m 'add x'")},
+				{input: bash(config.EventPostToolUse, "git commit -m 'add x'"), want: "committed"},
+				{input: bash(config.EventPostToolUse, "git status")},
+			},
+		},
+		{
+			name:     "session start",
+			reminder: config.ReminderConfig{Name: "r", Message: "read CONVENTIONS.md", On: config.ReminderOnSessionStart},
+			steps: []step{
+				{input: Input{HookType: config.EventSessionStart}, want: "read CONVENTIONS.md"},
+				{input: read},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			cwd := t.TempDir()
+			cfg := &config.Config{
+				Tools:     config.ToolsConfig{Block: []string{"WebFetch"}},
+				Reminders: []config.ReminderConfig{tt.reminder},
+			}
+			e := NewEvaluator(cfg)
+
+			for i, s := range tt.steps {
+				s.input.CWD = cwd
+				s.input.SessionID = "s-1"
+				result := e.Evaluate(s.input)
+				if s.want == "" && result.Warning != "" || !strings.Contains(result.Warning, s.want) {
+					t.Errorf("step %d: warning = %q, want %q", i, result.Warning, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluatorReminderMessageFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cwd := t.TempDir()
+	doc := "# Conventions\n\n## Testing\n\nRun `go test ./...` before ${tool}.\n\n### Fixtures\n\nKeep them small.\n\n## Style\n\nUse gofmt.\n"
+	if err := os.WriteFile(filepath.Join(cwd, "CONVENTIONS.md"), []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Reminders: []config.ReminderConfig{
+			{Name: "testing", MessageFile: "CONVENTIONS.md", Section: "testing", Tools: []string{"Write"}},
+			{Name: "missing", MessageFile: "CONVENTIONS.md", Section: "Releases", Tools: []string{"Write"}},
+		},
+	}
+	result := NewEvaluator(cfg).Evaluate(Input{
+		ToolName:  "Write",
+		ToolInput: map[string]interface{}{"file_path": "a.go"},
+		CWD:       cwd,
+	})
+
+	want := "Run `go test ./...` before Write.\n\n### Fixtures\n\nKeep them small."
+	if result.Warning != want {
+		t.Errorf("warning = %q, want %q", result.Warning, want)
+	}
+}
+
+func TestMarkdownSection(t *testing.T) {
+	doc := "intro\n# Guide\n## Build\nmake\n```sh\n# not a heading\n```\n## Test\ngo test\n# Other\nx\n"
+	tests := []struct {
+		title string
+		want  string
+		found bool
+	}{
+		{title: "Build", want: "make\n```sh\n# not a heading\n```", found: true},
+		{title: "test", want: "go test", found: true},
+		{title: "Guide", want: "## Build\nmake\n```sh\n# not a heading\n```\n## Test\ngo test", found: true},
+		{title: "not a heading", found: false},
+		{title: "Missing", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, found := markdownSection(doc, tt.title)
+			if found != tt.found {
+				t.Fatalf("found = %v, want %v", found, tt.found)
+			}
+			if got != tt.want {
+				t.Errorf("section = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
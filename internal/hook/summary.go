@@ -0,0 +1,86 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/policy"
+)
+
+// Summary describes the active policy in a few lines, so that the agent
+// knows the rules up front instead of discovering them through denials.
+// cwd resolves workspace.root to the directories the boundary lies at.
+func Summary(cfg *config.Config, cwd string) string {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, "- "+fmt.Sprintf(format, args...))
+	}
+
+	if cfg.Rules.Workspace {
+		add("workspace: stay inside %s; paths outside it are denied", strings.Join(policy.ResolveRoots(cfg.Workspace.Root, cwd), ", "))
+		if len(cfg.Workspace.Allow) > 0 {
+			add("workspace exceptions: %s", strings.Join(cfg.Workspace.Allow, ", "))
+		}
+		readOnly := cfg.Workspace.ReadAllow
+		if cfg.Workspace.ToolchainCaches {
+			readOnly = append(readOnly[:len(readOnly):len(readOnly)], "the Go module and build caches")
+		}
+		if len(readOnly) > 0 {
+			add("workspace read-only exceptions: %s", strings.Join(readOnly, ", "))
+		}
+		if len(cfg.Workspace.WriteAllow) > 0 {
+			add("workspace writable exceptions: %s", strings.Join(cfg.Workspace.WriteAllow, ", "))
+		}
+		if len(cfg.Workspace.Block) > 0 {
+			add("workspace: never access %s", strings.Join(cfg.Workspace.Block, ", "))
+		}
+	}
+	if protected := protectedEntries(&cfg.Protected); len(protected) > 0 {
+		add("protected: never access %s", strings.Join(protected, ", "))
+	}
+	if cfg.Rules.Scope {
+		if len(cfg.Scope.Allow) > 0 {
+			add("scope: only modify %s", strings.Join(cfg.Scope.Allow, ", "))
+		}
+		if len(cfg.Scope.Block) > 0 {
+			add("scope: never modify %s", strings.Join(cfg.Scope.Block, ", "))
+		}
+	}
+	if cfg.Rules.Versioning {
+		add("versioning: commit messages and git operations are checked")
+		if len(cfg.Versioning.Branches.Protected) > 0 {
+			add("protected branches: %s", strings.Join(cfg.Versioning.Branches.Protected, ", "))
+		}
+	}
+	if cfg.Rules.Incremental && cfg.Incremental.MaxFiles > 0 {
+		add("incremental: at most %d modified files before committing", cfg.Incremental.MaxFiles)
+	}
+	if cfg.Rules.Invariants {
+		add("invariants: %d structural checks apply to written files", countInvariants(&cfg.Invariants))
+	}
+	if len(cfg.Commands.Block) > 0 {
+		add("blocked commands: %s", strings.Join(cfg.Commands.Block, ", "))
+	}
+	if len(cfg.Tools.Block) > 0 {
+		add("blocked tools: %s", strings.Join(cfg.Tools.Block, ", "))
+	}
+
+	if len(lines) == 0 {
+		return "watchman is active with no rules enabled."
+	}
+	return "watchman policy for this session:\n" + strings.Join(lines, "\n")
+}
+
+// protectedEntries lists the protected paths, filenames and globs the
+// config adds to the built-in ones.
+func protectedEntries(cfg *config.ProtectedConfig) []string {
+	var entries []string
+	entries = append(entries, cfg.Paths...)
+	entries = append(entries, cfg.Filenames...)
+	return append(entries, cfg.Globs...)
+}
+
+func countInvariants(cfg *config.InvariantsConfig) int {
+	return len(cfg.Coexistence) + len(cfg.Content) + len(cfg.Imports) + len(cfg.Naming) + len(cfg.Required)
+}
@@ -0,0 +1,91 @@
+package hook
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestSummary(t *testing.T) {
+	cwd := t.TempDir()
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want []string
+		not  []string
+	}{
+		{
+			name: "no rules",
+			cfg:  &config.Config{},
+			want: []string{"no rules enabled"},
+		},
+		{
+			name: "workspace at cwd",
+			cfg:  &config.Config{Rules: config.RulesConfig{Workspace: true}},
+			want: []string{"workspace: stay inside " + cwd + ";"},
+			not:  []string{"exceptions"},
+		},
+		{
+			name: "workspace roots and exceptions",
+			cfg: &config.Config{
+				Rules: config.RulesConfig{Workspace: true},
+				Workspace: config.WorkspaceConfig{
+					Root:            config.Roots{config.RootCwd, "../shared"},
+					Allow:           []string{"/tmp/"},
+					ReadAllow:       []string{"/usr/share/doc/"},
+					WriteAllow:      []string{"~/scratch/"},
+					Block:           []string{"vendor/"},
+					ToolchainCaches: true,
+				},
+			},
+			want: []string{
+				"workspace: stay inside " + cwd + ", " + filepath.Join(filepath.Dir(cwd), "shared") + ";",
+				"workspace exceptions: /tmp/",
+				"workspace read-only exceptions: /usr/share/doc/, the Go module and build caches",
+				"workspace writable exceptions: ~/scratch/",
+				"workspace: never access vendor/",
+			},
+		},
+		{
+			name: "protected entries",
+			cfg: &config.Config{
+				Protected: config.ProtectedConfig{
+					Paths:     []string{"secrets/"},
+					Filenames: []string{".env"},
+					Globs:     []string{"**/*.pem"},
+				},
+			},
+			want: []string{"protected: never access secrets/, .env, **/*.pem"},
+			not:  []string{"no rules enabled"},
+		},
+		{
+			name: "rules without workspace",
+			cfg: &config.Config{
+				Rules:       config.RulesConfig{Incremental: true},
+				Incremental: config.IncrementalConfig{MaxFiles: 5},
+				Commands:    config.CommandsConfig{Block: []string{"sudo"}},
+			},
+			want: []string{"at most 5 modified files", "blocked commands: sudo"},
+			not:  []string{"workspace"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summary(tt.cfg, cwd)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("summary missing %q:\n%s", want, got)
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(got, not) {
+					t.Errorf("summary should not contain %q:\n%s", not, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+#!/bin/bash
+echo '{"decision":"ask","reason":"needs a human"}'
@@ -0,0 +1,102 @@
+package hook
+
+import "strings"
+
+// Verdicts recorded for each rule in a decision trace.
+const (
+	VerdictAllow = "allow"
+	VerdictDeny  = "deny"
+	VerdictAsk   = "ask"
+	VerdictWarn  = "warn"
+	VerdictSkip  = "skip"
+
+	// VerdictAudit records a violation allowed in audit mode, with the
+	// reason it would have had. A rule can record several.
+	VerdictAudit = "audit"
+)
+
+// Step records the outcome of a single rule during an evaluation.
+type Step struct {
+	Rule    string
+	Verdict string
+	Reason  string
+}
+
+// DecidingRule returns the rule that denied the call, the first rule that
+// asked the user, the rules whose violations were audited or the rules that
+// attached warnings to an allowed call. Empty when every rule allowed it.
+func (r Result) DecidingRule() string {
+	var asked string
+	var audited, warned []string
+	for _, step := range r.Trace {
+		switch step.Verdict {
+		case VerdictDeny:
+			return step.Rule
+		case VerdictAsk:
+			if asked == "" {
+				asked = step.Rule
+			}
+		case VerdictAudit:
+			if len(audited) == 0 || audited[len(audited)-1] != step.Rule {
+				audited = append(audited, step.Rule)
+			}
+		case VerdictWarn:
+			warned = append(warned, step.Rule)
+		}
+	}
+	switch {
+	case asked != "":
+		return asked
+	case len(audited) > 0:
+		return strings.Join(audited, ",")
+	}
+	return strings.Join(warned, ",")
+}
+
+// Audited returns every violation audit mode allowed during the evaluation,
+// as the rule followed by the reason it would have given.
+func (r Result) Audited() []string {
+	var audited []string
+	for _, step := range r.Trace {
+		if step.Verdict == VerdictAudit {
+			audited = append(audited, step.Rule+": "+step.Reason)
+		}
+	}
+	return audited
+}
+
+// trace collects the steps of a single evaluation in the order rules ran.
+type trace struct {
+	steps []Step
+}
+
+// record appends a step derived from the rule result and returns the result
+// unchanged. Audited violations come first, one step each; an allowed result
+// with nothing else to show adds no step of its own.
+func (t *trace) record(rule string, result Result) Result {
+	for _, reason := range result.Audit {
+		t.steps = append(t.steps, Step{Rule: rule, Verdict: VerdictAudit, Reason: reason})
+	}
+
+	step := Step{Rule: rule, Verdict: VerdictAllow}
+	switch {
+	case result.Ask:
+		step.Verdict = VerdictAsk
+		step.Reason = result.Reason
+	case !result.Allowed:
+		step.Verdict = VerdictDeny
+		step.Reason = result.Reason
+	case result.Warning != "":
+		step.Verdict = VerdictWarn
+		step.Reason = result.Warning
+	case len(result.Audit) > 0:
+		return result
+	}
+	t.steps = append(t.steps, step)
+	return result
+}
+
+// skip appends a step for a rule that did not run.
+func (t *trace) skip(rule, reason string) {
+	t.steps = append(t.steps, Step{Rule: rule, Verdict: VerdictSkip, Reason: reason})
+}
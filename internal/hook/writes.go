@@ -0,0 +1,104 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/adrianpk/watchman/internal/parser"
+)
+
+// bashWrites returns the files a Bash command writes, creates or deletes.
+// Copies into existing directories, copied content and the files named in
+// patches are resolved against the filesystem.
+func bashWrites(input Input) []parser.FileWrite {
+	if input.ToolName != "Bash" {
+		return nil
+	}
+	cmd, ok := input.ToolInput["command"].(string)
+	if !ok {
+		return nil
+	}
+
+	var writes []parser.FileWrite
+	for _, w := range parser.Writes(bashCommands(cmd, input.CWD)) {
+		switch {
+		case w.PatchFile != "":
+			data, err := os.ReadFile(resolve(w.PatchFile, input.CWD))
+			if err != nil {
+				continue
+			}
+			writes = append(writes, patchTargets(string(data), w.PatchDir)...)
+		case w.Patch != "":
+			writes = append(writes, patchTargets(w.Patch, w.PatchDir)...)
+		case w.CopyOf != "":
+			writes = append(writes, resolveCopy(w, input.CWD))
+		case w.LinkTo != "":
+			writes = append(writes, resolveLink(w, input.CWD))
+		default:
+			writes = append(writes, w)
+		}
+	}
+	return writes
+}
+
+// resolveCopy points a copy into an existing directory at the file it
+// creates there and fills in the copied content when the source is a file.
+func resolveCopy(w parser.FileWrite, cwd string) parser.FileWrite {
+	if info, err := os.Stat(resolve(w.Path, cwd)); err == nil && info.IsDir() {
+		w.Path = filepath.Join(w.Path, filepath.Base(w.CopyOf))
+	}
+
+	src := resolve(w.CopyOf, cwd)
+	if info, err := os.Stat(src); err == nil && info.Mode().IsRegular() {
+		if data, err := os.ReadFile(src); err == nil {
+			w.Content = string(data)
+			w.HasContent = true
+		}
+	}
+	return w
+}
+
+// resolveLink points a link into an existing directory at the link it
+// creates there, and makes a relative symbolic target relative to the
+// working directory, like every other path.
+func resolveLink(w parser.FileWrite, cwd string) parser.FileWrite {
+	if info, err := os.Stat(resolve(w.Path, cwd)); err == nil && info.IsDir() {
+		w.Path = filepath.Join(w.Path, filepath.Base(w.LinkTo))
+	}
+	if w.Symbolic && !filepath.IsAbs(w.LinkTo) {
+		w.LinkTo = filepath.Join(filepath.Dir(w.Path), w.LinkTo)
+	}
+	return w
+}
+
+// patchTargets returns the files named in a patch, relative to dir when
+// the patch is applied there.
+func patchTargets(patch, dir string) []parser.FileWrite {
+	targets := parser.PatchTargets(patch)
+	if dir == "" {
+		return targets
+	}
+	for i := range targets {
+		targets[i].Path = resolve(targets[i].Path, dir)
+	}
+	return targets
+}
+
+// writtenPaths returns the paths of writes that leave a file behind.
+func writtenPaths(writes []parser.FileWrite) []string {
+	var paths []string
+	for _, w := range writes {
+		if w.Op != parser.OpDelete {
+			paths = append(paths, w.Path)
+		}
+	}
+	return paths
+}
+
+// resolve joins a relative path to the working directory of the tool call.
+func resolve(p, cwd string) string {
+	if filepath.IsAbs(p) || cwd == "" {
+		return p
+	}
+	return filepath.Join(cwd, p)
+}
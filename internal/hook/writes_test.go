@@ -0,0 +1,149 @@
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/parser"
+)
+
+func bashInput(command, cwd string) Input {
+	return Input{ToolName: "Bash", ToolInput: map[string]interface{}{"command": command}, CWD: cwd}
+}
+
+func TestEvaluateScopeBashWrites(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Scope: true},
+		Scope: config.ScopeConfig{
+			Allow: []string{"src/**"},
+			Block: []string{"src/gen/**"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"read outside scope", "cat internal/foo.go", true},
+		{"redirect outside scope", "echo x > internal/foo.go", false},
+		{"redirect in scope", "echo x > src/foo.go", true},
+		{"sed in place", "sed -i 's/a/b/' internal/foo.go", false},
+		{"tee", "go test | tee internal/out.txt", false},
+		{"cp destination", "cp src/a.go internal/a.go", false},
+		{"mv source is deleted", "mv internal/a.go src/a.go", false},
+		{"rm blocked", "rm src/gen/x.go", false},
+		{"truncate", "truncate -s 0 internal/foo.go", false},
+		{"discarded output", "go build ./... > /dev/null", true},
+	}
+
+	e := NewEvaluator(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(bashInput(tt.command, ""))
+			if result.Allowed != tt.allowed {
+				t.Errorf("Evaluate(%q).Allowed = %v, want %v (%s)", tt.command, result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluateScopeBashPatch(t *testing.T) {
+	dir := t.TempDir()
+	patch := "--- a/internal/foo.go\n+++ b/internal/foo.go\n@@ -1 +1 @@\n-x\n+y\n"
+	os.WriteFile(filepath.Join(dir, "fix.diff"), []byte(patch), 0644)
+
+	cfg := &config.Config{
+		Rules: config.RulesConfig{Scope: true},
+		Scope: config.ScopeConfig{Allow: []string{"src/**"}},
+	}
+	result := NewEvaluator(cfg).Evaluate(bashInput("git apply fix.diff", dir))
+	if result.Allowed || !strings.Contains(result.Reason, "internal/foo.go") {
+		t.Errorf("expected patched file outside scope to be blocked, got %+v", result)
+	}
+}
+
+func TestBashWritesGitDir(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "fix.diff"), []byte("--- a/x.go\n+++ b/x.go\n"), 0644)
+
+	got := bashWrites(bashInput("git -C sub apply fix.diff && git -C sub restore y.go", dir))
+	want := []parser.FileWrite{
+		{Path: "sub/x.go", Op: parser.OpWrite},
+		{Path: "sub/y.go", Op: parser.OpWrite},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bashWrites() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEvaluateInvariantsBashWrites(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "todo.go"), []byte("package a // TODO"), 0644)
+	os.WriteFile(filepath.Join(dir, "clean.go"), []byte("package a"), 0644)
+	os.Mkdir(filepath.Join(dir, "pkg"), 0755)
+
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"echo literal", "echo '// TODO' > main.go", false},
+		{"echo clean", "echo 'package main' > main.go", true},
+		{"heredoc", "cat > main.go <<'EOF'\n// TODO\nEOF", false},
+		{"append is not checked", "echo '// TODO' >> main.go", true},
+		{"unknown content", "go run gen.go > main.go", true},
+		{"copy", "cp todo.go main.go", false},
+		{"copy clean", "cp clean.go main.go", true},
+		{"copy into directory", "cp todo.go pkg", false},
+		{"copy non-matching path", "cp todo.go notes.txt", true},
+	}
+
+	e := NewEvaluator(noTODOConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(bashInput(tt.command, dir))
+			if result.Allowed != tt.allowed {
+				t.Errorf("Evaluate(%q).Allowed = %v, want %v (%s)", tt.command, result.Allowed, tt.allowed, result.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluatePostToolUseBashWrites(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // TODO"), 0644)
+
+	input := bashInput("sed -i 's/x/y/' main.go", dir)
+	input.HookType = config.EventPostToolUse
+
+	result := NewEvaluator(noTODOConfig(config.EventPostToolUse)).Evaluate(input)
+	if result.Allowed || !strings.Contains(result.Reason, "main.go") {
+		t.Errorf("expected edited file on disk to be checked, got %+v", result)
+	}
+}
+
+func TestBashWrites(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644)
+	os.Mkdir(filepath.Join(dir, "dst"), 0755)
+	os.WriteFile(filepath.Join(dir, "fix.diff"), []byte("--- a/old.go\n+++ /dev/null\n"), 0644)
+
+	got := bashWrites(bashInput("cp a.go dst && git apply fix.diff && ls", dir))
+	want := []parser.FileWrite{
+		{Path: "dst/a.go", Op: parser.OpWrite, Content: "package a", HasContent: true, CopyOf: "a.go"},
+		{Path: "old.go", Op: parser.OpDelete},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bashWrites() = %+v, want %+v", got, want)
+	}
+
+	if got := bashWrites(Input{ToolName: "Write", ToolInput: map[string]interface{}{"file_path": "x"}}); got != nil {
+		t.Errorf("non-Bash tools have no Bash writes, got %+v", got)
+	}
+}
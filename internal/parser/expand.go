@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// declarations are builtins whose assignments last for the rest of the script.
+var declarations = map[string]bool{
+	"export": true, "declare": true, "typeset": true, "local": true, "readonly": true,
+}
+
+// Expander resolves variables and tildes in command words the way the shell
+// would before running the command, as far as that can be done without
+// running anything. Variables come from the command's leading assignments,
+// from assignments earlier in the script and from the environment.
+type Expander struct {
+	lookup func(string) (string, bool)
+	dir    string
+	vars   map[string]binding
+}
+
+// binding is a variable assigned by the script. Its value is unknown when
+// the assignment itself could not be expanded.
+type binding struct {
+	value string
+	known bool
+}
+
+// NewExpander creates an expander that reads the environment through lookup,
+// such as os.LookupEnv. dir is the working directory, used for $PWD and ~+.
+func NewExpander(lookup func(string) (string, bool), dir string) *Expander {
+	return &Expander{lookup: lookup, dir: dir, vars: make(map[string]binding)}
+}
+
+// ExpandAll expands the words, environment values and redirect targets of
+// each command, in script order. Words that depend on something only known
+// at run time, such as $(pwd)/x or $1, are kept as written and listed in
+// Unresolved. An argument made of a single command substitution is kept
+// as written but not listed.
+func (x *Expander) ExpandAll(cmds []Command) []Command {
+	out := make([]Command, 0, len(cmds))
+	for _, c := range cmds {
+		out = append(out, x.expand(c))
+	}
+	return out
+}
+
+func (x *Expander) expand(c Command) Command {
+	var unresolved []string
+
+	names := make([]string, 0, len(c.Env))
+	for name := range c.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make(map[string]string, len(c.Env))
+	known := make(map[string]bool, len(c.Env))
+	for _, name := range names {
+		value, ok := x.word(c.Env[name], c.envNodes[name], nil)
+		if !ok {
+			unresolved = append(unresolved, c.Env[name])
+			value = c.Env[name]
+		}
+		env[name] = value
+		known[name] = ok
+	}
+
+	words := make([]string, len(c.Words))
+	for i, w := range c.Words {
+		var node *syntax.Word
+		if len(c.nodes) == len(c.Words) {
+			node = c.nodes[i]
+		}
+		value, ok := x.word(w, node, env)
+		if !ok {
+			// The output of an argument such as "$(cat <<EOF ...)" is not a path
+			// the command names; the commands inside are analyzed on their own.
+			if node == nil || !isSubstitution(node) {
+				unresolved = append(unresolved, w)
+			}
+			value = w
+		}
+		words[i] = value
+	}
+
+	out := newCommand(c.Raw, env, words)
+	out.nodes = c.nodes
+	out.envNodes = c.envNodes
+	out.redirectNodes = c.redirectNodes
+	for i, r := range c.Redirects {
+		if r.IsFile() && i < len(c.redirectNodes) {
+			if value, ok := x.word(r.Target, c.redirectNodes[i], env); ok {
+				r.Target = value
+			} else {
+				unresolved = append(unresolved, r.Target)
+			}
+		}
+		out.Redirects = append(out.Redirects, r)
+	}
+	out.Unresolved = unresolved
+
+	// Plain assignments and declarations set variables for later commands;
+	// leading assignments only apply to their own command.
+	if c.Program == "" || declarations[c.Program] {
+		for name, value := range env {
+			x.vars[name] = binding{value: value, known: known[name]}
+		}
+	}
+	return out
+}
+
+// word expands a single word. Words without syntax, such as those built
+// by launchers from other words, are parsed again when they contain
+// anything to expand.
+func (x *Expander) word(s string, node *syntax.Word, env map[string]string) (string, bool) {
+	if node == nil {
+		if !strings.ContainsAny(s, "$`~") {
+			return s, true
+		}
+		if node = parseWord(s); node == nil {
+			return s, false
+		}
+	}
+
+	vars := &scriptEnv{x: x, cmd: env, refs: make(map[string]bool)}
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if pe, ok := n.(*syntax.ParamExp); ok && pe.Param != nil {
+			vars.refs[pe.Param.Value] = true
+		}
+		return true
+	})
+	cfg := &expand.Config{
+		Env: vars,
+		ProcSubst: func(*syntax.ProcSubst) (string, error) {
+			return "/dev/fd/63", nil
+		},
+	}
+	fields, err := expand.Fields(cfg, node)
+	if err != nil || vars.missed {
+		return s, false
+	}
+	value := strings.Join(fields, " ")
+
+	// A tilde prefix naming an unknown user is left in place by the shell.
+	if lit, ok := firstLit(node); ok && strings.HasPrefix(lit, "~") && strings.HasPrefix(value, "~") {
+		return s, false
+	}
+	return value, true
+}
+
+// isSubstitution reports whether a word consists of a single command
+// substitution, quoted or not.
+func isSubstitution(w *syntax.Word) bool {
+	parts := w.Parts
+	if len(parts) == 1 {
+		if dq, ok := parts[0].(*syntax.DblQuoted); ok {
+			parts = dq.Parts
+		}
+	}
+	if len(parts) != 1 {
+		return false
+	}
+	_, ok := parts[0].(*syntax.CmdSubst)
+	return ok
+}
+
+// parseWord parses s as a single shell word.
+func parseWord(s string) *syntax.Word {
+	file, err := syntax.NewParser().Parse(strings.NewReader(s), "")
+	if err != nil || len(file.Stmts) != 1 {
+		return nil
+	}
+	call, ok := file.Stmts[0].Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Assigns) > 0 || len(call.Args) != 1 {
+		return nil
+	}
+	return call.Args[0]
+}
+
+func firstLit(w *syntax.Word) (string, bool) {
+	if len(w.Parts) == 0 {
+		return "", false
+	}
+	lit, ok := w.Parts[0].(*syntax.Lit)
+	if !ok {
+		return "", false
+	}
+	return lit.Value, true
+}
+
+// scriptEnv resolves variables for a single expansion and notes whether
+// any variable the word refers to had no known value.
+type scriptEnv struct {
+	x      *Expander
+	cmd    map[string]string
+	refs   map[string]bool
+	missed bool
+}
+
+func (e *scriptEnv) Get(name string) expand.Variable {
+	if value, ok := e.value(name); ok {
+		return expand.Variable{Set: true, Kind: expand.String, Str: value}
+	}
+	// The expander also asks for variables of its own, such as IFS.
+	if e.refs[name] {
+		e.missed = true
+	}
+	return expand.Variable{}
+}
+
+func (e *scriptEnv) Each(func(name string, vr expand.Variable) bool) {}
+
+func (e *scriptEnv) value(name string) (string, bool) {
+	if value, ok := e.cmd[name]; ok {
+		return value, true
+	}
+	if b, ok := e.x.vars[name]; ok {
+		return b.value, b.known
+	}
+	switch name {
+	case "PWD", "HOME +":
+		if e.x.dir != "" {
+			return e.x.dir, true
+		}
+		name = "PWD"
+	case "HOME -":
+		name = "OLDPWD"
+	}
+	if e.x.lookup == nil || strings.Contains(name, " ") {
+		return "", false
+	}
+	return e.x.lookup(name)
+}
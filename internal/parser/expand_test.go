@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"os/user"
+	"reflect"
+	"testing"
+)
+
+func testLookup(name string) (string, bool) {
+	value, ok := map[string]string{
+		"HOME":   "/home/me",
+		"OLDPWD": "/tmp/prev",
+		"GOPATH": "/home/me/go",
+	}[name]
+	return value, ok
+}
+
+func TestExpanderExpandAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		args       []string // args of the last cat, or of the last command
+		unresolved []string
+	}{
+		{"env variable", "cat $HOME/.ssh/id_rsa", []string{"/home/me/.ssh/id_rsa"}, nil},
+		{"braced", "ls ${HOME}/.aws", []string{"/home/me/.aws"}, nil},
+		{"quoted", `cat "$PWD/../x"`, []string{"/work/project/../x"}, nil},
+		{"default value", "cat ${MISSING:-/etc}/passwd", nil, []string{"${MISSING:-/etc}/passwd"}},
+		{"single quotes", `awk '{print $1}' f`, []string{"{print $1}", "f"}, nil},
+		{"escaped", `echo \$HOME`, []string{"$HOME"}, nil},
+		{"tilde", "cat ~/.netrc", []string{"/home/me/.netrc"}, nil},
+		{"bare tilde", "ls ~", []string{"/home/me"}, nil},
+		{"tilde plus", "ls ~+/x", []string{"/work/project/x"}, nil},
+		{"tilde minus", "ls ~-", []string{"/tmp/prev"}, nil},
+		{"quoted tilde", `ls "~/x"`, []string{"~/x"}, nil},
+		{"unknown user", "ls ~nosuchuser1234/x", []string{"~nosuchuser1234/x"}, []string{"~nosuchuser1234/x"}},
+		{"leading assignment", "D=/etc cat $D/passwd", []string{"/etc/passwd"}, nil},
+		{"earlier assignment", "D=/etc; cat $D/passwd", []string{"/etc/passwd"}, nil},
+		{"export", "export D=$HOME/x && cat $D", []string{"/home/me/x"}, nil},
+		{"assignment from environment", "G=$GOPATH/bin; ls $G", []string{"/home/me/go/bin"}, nil},
+		{"unknown assignment", "D=$(pwd); cat $D/x", []string{"$D/x"}, []string{"$D/x"}},
+		{"command substitution", "cat $(git rev-parse --show-toplevel)/x", []string{"$(git rev-parse --show-toplevel)/x"}, []string{"$(git rev-parse --show-toplevel)/x"}},
+		{"bare substitution", `git commit -m "$(cat msg.txt)"`, nil, nil},
+		{"positional", "cat $1", []string{"$1"}, []string{"$1"}},
+		{"unset", "cat $NOPE/x", []string{"$NOPE/x"}, []string{"$NOPE/x"}},
+		{"redirect", "echo x > $HOME/.bashrc", []string{"x"}, nil},
+		{"launcher", "sudo cat $HOME/.aws/credentials", []string{"/home/me/.aws/credentials"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := NewExpander(testLookup, "/work/project").ExpandAll(ParseAll(tt.script))
+			last := cmds[len(cmds)-1]
+			for _, c := range cmds {
+				if c.Program == "cat" {
+					last = c
+				}
+			}
+			if len(tt.args) > 0 && !reflect.DeepEqual(last.Args, tt.args) {
+				t.Errorf("Args = %q, want %q", last.Args, tt.args)
+			}
+			if !reflect.DeepEqual(last.Unresolved, tt.unresolved) {
+				t.Errorf("Unresolved = %q, want %q", last.Unresolved, tt.unresolved)
+			}
+		})
+	}
+}
+
+func TestExpanderRedirectTarget(t *testing.T) {
+	cmds := NewExpander(testLookup, "").ExpandAll(ParseAll("echo x > $HOME/.bashrc 2>$(mktemp)"))
+	if got := cmds[0].Redirects[0].Target; got != "/home/me/.bashrc" {
+		t.Errorf("Target = %q", got)
+	}
+	if !reflect.DeepEqual(cmds[0].Unresolved, []string{"$(mktemp)"}) {
+		t.Errorf("Unresolved = %q", cmds[0].Unresolved)
+	}
+}
+
+func TestExpanderOtherUser(t *testing.T) {
+	root, err := user.Lookup("root")
+	if err != nil {
+		t.Skip("no root user")
+	}
+	cmds := NewExpander(testLookup, "").ExpandAll(ParseAll("ls ~root/.ssh"))
+	if want := root.HomeDir + "/.ssh"; cmds[0].Args[0] != want {
+		t.Errorf("Args[0] = %q, want %q", cmds[0].Args[0], want)
+	}
+}
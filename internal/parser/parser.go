@@ -4,6 +4,8 @@ package parser
 import (
 	"regexp"
 	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
 )
 
 // Command represents a parsed shell command.
@@ -14,63 +16,82 @@ type Command struct {
 	Subcommand string
 	Args       []string
 	Flags      map[string]string
+	Words      []string // program and arguments in order, quotes removed
+	Redirects  []Redirect
+	Unresolved []string // words whose value is only known at run time, see Expander
+
+	// Syntax behind Words, Env and Redirects, when the command was parsed as
+	// shell; used to expand them. Entries may be nil.
+	nodes         []*syntax.Word
+	envNodes      map[string]*syntax.Word
+	redirectNodes []*syntax.Word
 }
 
 var envVarPattern = regexp.MustCompile(`^([A-Z_][A-Z0-9_]*)=(.*)$`)
 
 // Parse parses a shell command string into its components.
+// For scripts with several commands it returns the first one; use ParseAll to see them all.
 func Parse(cmd string) Command {
-	result := Command{
-		Raw:   cmd,
-		Env:   make(map[string]string),
-		Args:  make([]string, 0),
-		Flags: make(map[string]string),
-	}
-
-	cmd = strings.TrimSpace(cmd)
-	if cmd == "" {
+	if cmds := ParseAll(cmd); len(cmds) > 0 {
+		result := cmds[0]
+		result.Raw = cmd
 		return result
 	}
+	return newCommand(cmd, make(map[string]string), nil)
+}
 
-	tokens := tokenize(stripHeredocs(cmd))
-	if len(tokens) == 0 {
-		return result
-	}
+// parseTokens splits a command on whitespace, respecting quotes.
+// Used when the command is not valid shell syntax.
+func parseTokens(cmd string) Command {
+	env := make(map[string]string)
+	tokens := tokenize(stripHeredocs(strings.TrimSpace(cmd)))
 
 	idx := 0
-
-	// Extract leading environment variables
 	for idx < len(tokens) {
-		if match := envVarPattern.FindStringSubmatch(tokens[idx]); match != nil {
-			result.Env[match[1]] = match[2]
-			idx++
-		} else {
+		match := envVarPattern.FindStringSubmatch(tokens[idx])
+		if match == nil {
 			break
 		}
+		env[match[1]] = match[2]
+		idx++
 	}
 
-	if idx >= len(tokens) {
+	return newCommand(cmd, env, tokens[idx:])
+}
+
+// newCommand classifies the words of a command into program, subcommand, flags and arguments.
+func newCommand(raw string, env map[string]string, words []string) Command {
+	result := Command{
+		Raw:   raw,
+		Env:   env,
+		Args:  make([]string, 0),
+		Flags: make(map[string]string),
+		Words: words,
+	}
+
+	if len(words) == 0 {
 		return result
 	}
 
-	result.Program = tokens[idx]
+	idx := 0
+	result.Program = words[idx]
 	idx++
 
 	// Check for subcommand (non-flag argument immediately after program)
-	if idx < len(tokens) && !strings.HasPrefix(tokens[idx], "-") {
+	if idx < len(words) && !strings.HasPrefix(words[idx], "-") {
 		if hasSubcommand(result.Program) {
-			result.Subcommand = tokens[idx]
+			result.Subcommand = words[idx]
 			idx++
 		}
 	}
 
-	for idx < len(tokens) {
-		token := tokens[idx]
+	for idx < len(words) {
+		token := words[idx]
 		if strings.HasPrefix(token, "-") {
 			key, value := parseFlag(token)
 			// Check for value in next token if flag has no embedded value
-			if value == "" && idx+1 < len(tokens) && !strings.HasPrefix(tokens[idx+1], "-") {
-				next := tokens[idx+1]
+			if value == "" && idx+1 < len(words) && !strings.HasPrefix(words[idx+1], "-") {
+				next := words[idx+1]
 				if !strings.HasPrefix(next, ".") && !strings.HasPrefix(next, "/") && !strings.Contains(next, "/") {
 					value = next
 					idx++
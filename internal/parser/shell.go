@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Redirect is a file redirection attached to a command, such as > out.txt.
+type Redirect struct {
+	Op     string // >, >>, <, &>, >&, <<, <<<, ...
+	Fd     string // explicit descriptor, e.g. 2 in 2>err.log
+	Target string // file, descriptor or heredoc delimiter
+	Body   string // heredoc content
+}
+
+// IsFile reports whether the redirect target names a file,
+// as opposed to a descriptor (2>&1), a heredoc or a here-string.
+func (r Redirect) IsFile() bool {
+	switch r.Op {
+	case "<<", "<<-", "<<<":
+		return false
+	case ">&", "<&":
+		return !isDescriptor(r.Target)
+	}
+	return r.Target != ""
+}
+
+// ParseAll parses a shell script into every simple command it runs,
+// including commands in pipelines, lists, subshells, command and process
+// substitutions, and the bodies of compound commands. Outer commands come
+// before the commands nested in their words.
+// Commands run through launchers such as sudo, xargs or bash -c follow the
+// launcher, as if they had been typed directly.
+// A script that does not parse falls back to a single tokenized command.
+func ParseAll(script string) []Command {
+	return parseAll(script, 0)
+}
+
+func parseAll(script string, depth int) []Command {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		cmd := parseTokens(script)
+		return append([]Command{cmd}, unwrap(cmd, depth)...)
+	}
+
+	var cmds []Command
+	syntax.Walk(file, func(node syntax.Node) bool {
+		stmt, ok := node.(*syntax.Stmt)
+		if !ok {
+			return true
+		}
+		if cmd, ok := fromStmt(script, stmt); ok {
+			cmds = append(cmds, cmd)
+			cmds = append(cmds, unwrap(cmd, depth)...)
+		}
+		return true
+	})
+	return cmds
+}
+
+// fromStmt builds a command from a statement running a simple command or a
+// declaration. Compound statements only yield a command when they redirect,
+// so that `(cd x; make) > log` still reports the log file.
+func fromStmt(src string, stmt *syntax.Stmt) (Command, bool) {
+	env := make(map[string]string)
+	envNodes := make(map[string]*syntax.Word)
+	var words []string
+	var nodes []*syntax.Word
+
+	switch c := stmt.Cmd.(type) {
+	case *syntax.CallExpr:
+		for _, a := range c.Assigns {
+			env[a.Name.Value] = assignValue(src, a)
+			envNodes[a.Name.Value] = a.Value
+		}
+		for _, w := range c.Args {
+			words = append(words, wordValue(src, w))
+			nodes = append(nodes, w)
+		}
+	case *syntax.DeclClause:
+		words = append(words, c.Variant.Value)
+		nodes = append(nodes, nil)
+		for _, a := range c.Args {
+			if a.Name == nil {
+				words = append(words, wordValue(src, a.Value))
+				nodes = append(nodes, a.Value)
+				continue
+			}
+			if a.Naked {
+				words = append(words, a.Name.Value)
+				nodes = append(nodes, nil)
+				continue
+			}
+			env[a.Name.Value] = assignValue(src, a)
+			envNodes[a.Name.Value] = a.Value
+		}
+	default:
+		if len(stmt.Redirs) == 0 {
+			return Command{}, false
+		}
+	}
+
+	cmd := newCommand(stmtSource(src, stmt), env, words)
+	cmd.nodes = nodes
+	cmd.envNodes = envNodes
+	for _, r := range stmt.Redirs {
+		redirect := Redirect{Op: r.Op.String()}
+		if r.N != nil {
+			redirect.Fd = r.N.Value
+		}
+		if r.Word != nil {
+			redirect.Target = wordValue(src, r.Word)
+		}
+		if r.Hdoc != nil {
+			redirect.Body = heredocBody(src, r.Hdoc)
+		}
+		cmd.Redirects = append(cmd.Redirects, redirect)
+		cmd.redirectNodes = append(cmd.redirectNodes, r.Word)
+	}
+	return cmd, true
+}
+
+// stmtSource returns the text of a statement, including any heredoc bodies.
+func stmtSource(src string, stmt *syntax.Stmt) string {
+	end := stmt.End().Offset()
+	for _, r := range stmt.Redirs {
+		if r.Hdoc != nil && r.Hdoc.End().Offset() > end {
+			end = r.Hdoc.End().Offset()
+		}
+	}
+	return source(src, stmt.Pos().Offset(), end)
+}
+
+// heredocBody returns the text of a heredoc as written, without the closing
+// delimiter. Expansions in unquoted heredocs are kept as source text.
+func heredocBody(src string, w *syntax.Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		if lit, ok := part.(*syntax.Lit); ok {
+			b.WriteString(lit.Value)
+			continue
+		}
+		b.WriteString(source(src, part.Pos().Offset(), part.End().Offset()))
+	}
+	return b.String()
+}
+
+func assignValue(src string, a *syntax.Assign) string {
+	switch {
+	case a.Value != nil:
+		return wordValue(src, a.Value)
+	case a.Array != nil:
+		return source(src, a.Array.Pos().Offset(), a.Array.End().Offset())
+	}
+	return ""
+}
+
+// wordValue returns the literal value of a word with quotes and escapes removed.
+// Expansions and substitutions are kept as they appear in the source, e.g. $HOME.
+func wordValue(src string, w *syntax.Word) string {
+	var b strings.Builder
+	writeParts(&b, src, w.Parts, false)
+	return b.String()
+}
+
+func writeParts(b *strings.Builder, src string, parts []syntax.WordPart, quoted bool) {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(p.Value, quoted))
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			writeParts(b, src, p.Parts, true)
+		default:
+			b.WriteString(source(src, part.Pos().Offset(), part.End().Offset()))
+		}
+	}
+}
+
+// unescape removes backslashes the shell would drop. Inside double quotes
+// a backslash only escapes $, `, ", \ and newline.
+func unescape(s string, quoted bool) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if !quoted || strings.IndexByte("$`\"\\\n", next) >= 0 {
+				if next != '\n' {
+					b.WriteByte(next)
+				}
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func source(src string, start, end uint) string {
+	if int(end) > len(src) || start > end {
+		return ""
+	}
+	return src[start:end]
+}
+
+func isDescriptor(s string) bool {
+	if s == "-" {
+		return true
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
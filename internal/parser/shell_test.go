@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		programs []string
+	}{
+		{"single", "ls -la", []string{"ls"}},
+		{"and list", "cat a && cat /etc/shadow", []string{"cat", "cat"}},
+		{"pipeline", "ps aux | grep go | wc -l", []string{"ps", "grep", "wc"}},
+		{"semicolon and newline", "cd x; make\ngo test", []string{"cd", "make", "go"}},
+		{"command substitution", "echo $(cat ~/.ssh/id_rsa)", []string{"echo", "cat"}},
+		{"backticks", "echo `whoami`", []string{"echo", "whoami"}},
+		{"process substitution", "diff <(ls a) <(ls b)", []string{"diff", "ls", "ls"}},
+		{"subshell", "(cd /; rm x)", []string{"cd", "rm"}},
+		{"compound", "if test -f x; then rm x; fi", []string{"test", "rm"}},
+		{"loop", "for f in *.go; do gofmt -l $f; done", []string{"gofmt"}},
+		{"declaration", "export GOBIN=/opt/bin", []string{"export"}},
+		{"assignment only", "FOO=bar", []string{""}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range ParseAll(tt.script) {
+				got = append(got, c.Program)
+			}
+			if !reflect.DeepEqual(got, tt.programs) {
+				t.Errorf("programs = %q, want %q", got, tt.programs)
+			}
+		})
+	}
+}
+
+func TestParseAllCommandFields(t *testing.T) {
+	cmds := ParseAll(`FOO=1 BAR="a b" go test -run 'TestX' ./... > out.log 2>&1 && git commit -m "$(cat msg.txt)"`)
+	if len(cmds) != 3 {
+		t.Fatalf("expected 3 commands, got %d: %+v", len(cmds), cmds)
+	}
+
+	goCmd := cmds[0]
+	if goCmd.Program != "go" || goCmd.Subcommand != "test" {
+		t.Errorf("unexpected program: %+v", goCmd)
+	}
+	if !reflect.DeepEqual(goCmd.Env, map[string]string{"FOO": "1", "BAR": "a b"}) {
+		t.Errorf("Env = %v", goCmd.Env)
+	}
+	if v, _ := goCmd.FlagValue("run"); v != "TestX" {
+		t.Errorf("run flag = %q", v)
+	}
+	if !reflect.DeepEqual(goCmd.Args, []string{"./..."}) {
+		t.Errorf("Args = %v", goCmd.Args)
+	}
+	wantRedirects := []Redirect{{Op: ">", Target: "out.log"}, {Op: ">&", Fd: "2", Target: "1"}}
+	if !reflect.DeepEqual(goCmd.Redirects, wantRedirects) {
+		t.Errorf("Redirects = %+v, want %+v", goCmd.Redirects, wantRedirects)
+	}
+	if goCmd.Raw != `FOO=1 BAR="a b" go test -run 'TestX' ./... > out.log 2>&1` {
+		t.Errorf("Raw = %q", goCmd.Raw)
+	}
+
+	gitCmd := cmds[1]
+	if gitCmd.Raw != `git commit -m "$(cat msg.txt)"` {
+		t.Errorf("Raw = %q", gitCmd.Raw)
+	}
+	if cmds[2].Program != "cat" || !reflect.DeepEqual(cmds[2].Args, []string{"msg.txt"}) {
+		t.Errorf("nested command = %+v", cmds[2])
+	}
+}
+
+func TestParseAllInvalidSyntaxFallsBack(t *testing.T) {
+	cmds := ParseAll("cat /etc/passwd )")
+	if len(cmds) != 1 || cmds[0].Program != "cat" {
+		t.Fatalf("expected tokenized fallback, got %+v", cmds)
+	}
+	if !reflect.DeepEqual(cmds[0].Args, []string{"/etc/passwd", ")"}) {
+		t.Errorf("Args = %v", cmds[0].Args)
+	}
+}
+
+func TestRedirectIsFile(t *testing.T) {
+	tests := []struct {
+		redirect Redirect
+		want     bool
+	}{
+		{Redirect{Op: ">", Target: "out"}, true},
+		{Redirect{Op: ">>", Target: "out"}, true},
+		{Redirect{Op: "<", Target: "in"}, true},
+		{Redirect{Op: "&>", Target: "all.log"}, true},
+		{Redirect{Op: ">&", Target: "1"}, false},
+		{Redirect{Op: ">&", Target: "-"}, false},
+		{Redirect{Op: ">&", Target: "file"}, true},
+		{Redirect{Op: "<<", Target: "EOF"}, false},
+		{Redirect{Op: "<<<", Target: "text"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.redirect.IsFile(); got != tt.want {
+			t.Errorf("%+v.IsFile() = %v, want %v", tt.redirect, got, tt.want)
+		}
+	}
+}
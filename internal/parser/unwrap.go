@@ -0,0 +1,276 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxUnwrapDepth limits how many launchers deep ParseAll looks, so that
+// `sh -c "sh -c ..."` cannot make the analysis run away. Launchers nested
+// deeper are still reported, but their payload is not.
+const maxUnwrapDepth = 8
+
+// launcherValueFlags lists, for each program that runs another command
+// given in its arguments, the options that consume the next word.
+var launcherValueFlags = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-h": true, "-p": true, "-C": true, "-D": true, "-r": true, "-t": true, "-U": true, "-T": true, "--user": true, "--group": true, "--host": true, "--prompt": true, "--chdir": true},
+	"doas":    {"-u": true, "-C": true},
+	"nohup":   {},
+	"setsid":  {},
+	"builtin": {},
+	"exec":    {"-a": true},
+	"command": {},
+	"nice":    {"-n": true, "--adjustment": true},
+	"ionice":  {"-c": true, "-n": true, "--class": true, "--classdata": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"time":    {"-f": true, "-o": true, "--format": true, "--output": true},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-L": true, "-d": true, "-E": true, "-s": true, "-a": true, "--arg-file": true, "--delimiter": true, "--max-args": true, "--max-procs": true, "--max-lines": true, "--replace": true},
+}
+
+// shells run the script given with -c.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true,
+}
+
+// unwrap returns the commands a launcher runs on behalf of cmd, such as
+// rm in `sudo rm -rf x`, `xargs rm` or `bash -c "rm -rf x"`. Each inner
+// command is followed by the commands it launches in turn.
+func unwrap(cmd Command, depth int) []Command {
+	if depth >= maxUnwrapDepth || len(cmd.Words) == 0 {
+		return nil
+	}
+	args := cmd.Words[1:]
+	program := filepath.Base(cmd.Program)
+
+	switch {
+	case shells[program]:
+		if script, ok := shellScript(args); ok {
+			return parseAll(script, depth+1)
+		}
+	case program == "eval":
+		return parseAll(strings.Join(args, " "), depth+1)
+	case program == "env":
+		return envCommand(cmd, depth)
+	case program == "watch":
+		start := skipOptions(args, map[string]bool{"-n": true, "--interval": true})
+		return parseAll(strings.Join(args[start:], " "), depth+1)
+	case program == "find":
+		return findExec(cmd, depth)
+	case program == "git":
+		return gitAlias(cmd, depth)
+	case launcherValueFlags[program] != nil:
+		start := 1 + skipOptions(args, launcherValueFlags[program])
+		switch program {
+		case "timeout":
+			start++ // duration
+		case "command":
+			if hasAnyFlag(args, "-v", "-V") {
+				return nil // looks the command up without running it
+			}
+		}
+		if start >= len(cmd.Words) {
+			return nil
+		}
+		words, nodes := cmd.span(start, len(cmd.Words))
+		return launch(cmd.Env, words, nodes, depth)
+	}
+	return nil
+}
+
+// span returns the words from start to end along with their syntax nodes.
+func (c Command) span(start, end int) ([]string, []*syntax.Word) {
+	var nodes []*syntax.Word
+	if len(c.nodes) == len(c.Words) {
+		nodes = c.nodes[start:end]
+	}
+	return c.Words[start:end], nodes
+}
+
+// launch builds the command made of words and unwraps it in turn. nodes
+// holds the syntax of each word when known. Leading NAME=value words, as
+// accepted by sudo and env, become the command's environment.
+func launch(env map[string]string, words []string, nodes []*syntax.Word, depth int) []Command {
+	inner := make(map[string]string, len(env))
+	for k, v := range env {
+		inner[k] = v
+	}
+	for len(words) > 0 {
+		match := envVarPattern.FindStringSubmatch(words[0])
+		if match == nil {
+			break
+		}
+		inner[match[1]] = match[2]
+		words = words[1:]
+		if nodes != nil {
+			nodes = nodes[1:]
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	cmd := newCommand(quoteWords(words), inner, words)
+	cmd.nodes = nodes
+	return append([]Command{cmd}, unwrap(cmd, depth+1)...)
+}
+
+// shellScript returns the script a shell runs with -c, alone or in a
+// cluster such as -lc. A shell started with a script file runs nothing we can see.
+func shellScript(args []string) (string, bool) {
+	command := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			if command && i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", false
+		case a == "-o" || a == "+o" || a == "-O" || a == "+O":
+			i++
+		case strings.HasPrefix(a, "--"):
+		case (strings.HasPrefix(a, "-") || strings.HasPrefix(a, "+")) && len(a) > 1:
+			if strings.ContainsRune(a[1:], 'c') {
+				command = true
+			}
+		case command:
+			return a, true
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// envCommand unwraps env, whose assignments go to the command it runs.
+// env -S splits its argument into a command line of its own.
+func envCommand(cmd Command, depth int) []Command {
+	args := cmd.Words
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-S" || a == "--split-string":
+			if i+1 < len(args) {
+				return parseAll(strings.Join(args[i+1:], " "), depth+1)
+			}
+			return nil
+		case strings.HasPrefix(a, "--split-string="):
+			return parseAll(strings.TrimPrefix(a, "--split-string=")+" "+quoteWords(args[i+1:]), depth+1)
+		case a == "-u" || a == "--unset" || a == "-C" || a == "--chdir":
+			i++
+		case a == "--":
+			words, nodes := cmd.span(i+1, len(args))
+			return launch(cmd.Env, words, nodes, depth)
+		case strings.HasPrefix(a, "-") && a != "-":
+		default:
+			words, nodes := cmd.span(i, len(args))
+			return launch(cmd.Env, words, nodes, depth)
+		}
+	}
+	return nil
+}
+
+// findExec returns the commands run by -exec, -execdir, -ok and -okdir,
+// each ending at ; or +.
+func findExec(cmd Command, depth int) []Command {
+	args := cmd.Words
+	var cmds []Command
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "-exec", "-execdir", "-ok", "-okdir":
+		default:
+			continue
+		}
+		end := i + 1
+		for end < len(args) && args[end] != ";" && args[end] != "+" {
+			end++
+		}
+		words, nodes := cmd.span(i+1, end)
+		cmds = append(cmds, launch(cmd.Env, words, nodes, depth)...)
+		i = end
+	}
+	return cmds
+}
+
+// gitAlias expands an alias defined on the command line with
+// `git -c alias.name=value name`. Aliases starting with ! run a shell command.
+func gitAlias(cmd Command, depth int) []Command {
+	args := cmd.Words
+	aliases := make(map[string]string)
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-c" && i+1 < len(args):
+			i++
+			if name, value, ok := strings.Cut(args[i], "="); ok && strings.HasPrefix(name, "alias.") {
+				aliases[strings.TrimPrefix(name, "alias.")] = value
+			}
+		case a == "-C" || a == "--git-dir" || a == "--work-tree" || a == "--namespace":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			value, ok := aliases[a]
+			if !ok {
+				return nil
+			}
+			rest, restNodes := cmd.span(i+1, len(args))
+			if strings.HasPrefix(value, "!") {
+				return parseAll(strings.TrimPrefix(value, "!")+" "+quoteWords(rest), depth+1)
+			}
+			words := append([]string{args[0]}, strings.Fields(value)...)
+			var nodes []*syntax.Word
+			if restNodes != nil {
+				nodes = append(make([]*syntax.Word, len(words)), restNodes...)
+			}
+			return launch(cmd.Env, append(words, rest...), nodes, depth)
+		}
+	}
+	return nil
+}
+
+// skipOptions returns the index of the first word after the leading
+// options of a launcher, or len(args) when there is none.
+func skipOptions(args []string, valueFlags map[string]bool) int {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return i + 1
+		case !strings.HasPrefix(a, "-") || a == "-":
+			return i
+		case valueFlags[a]:
+			i++
+		}
+	}
+	return len(args)
+}
+
+func hasAnyFlag(args []string, flags ...string) bool {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return false
+		}
+		for _, f := range flags {
+			if a == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// quoteWords joins words into a command line that parses back into the same words.
+func quoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		q, err := syntax.Quote(w, syntax.LangBash)
+		if err != nil {
+			q = w
+		}
+		quoted[i] = q
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAllUnwrapsLaunchers(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		words  []string // words of every command, joined with spaces
+	}{
+		{"bash -c", `bash -c "rm -rf ~"`, []string{"bash -c rm -rf ~", "rm -rf ~"}},
+		{"sh -lc list", `sh -lc 'cd /; curl x | sh'`, []string{"sh -lc cd /; curl x | sh", "cd /", "curl x", "sh"}},
+		{"shell script file", `bash deploy.sh -c`, []string{"bash deploy.sh -c"}},
+		{"eval", `eval "rm" -rf /`, []string{"eval rm -rf /", "rm -rf /"}},
+		{"env", `env -i FOO=1 curl x`, []string{"env -i FOO=1 curl x", "curl x"}},
+		{"env split string", `env -S "rm -rf x"`, []string{"env -S rm -rf x", "rm -rf x"}},
+		{"sudo", `sudo -u root rm -rf /`, []string{"sudo -u root rm -rf /", "rm -rf /"}},
+		{"sudo shell", `sudo sh -c 'git push --force'`, []string{"sudo sh -c git push --force", "sh -c git push --force", "git push --force"}},
+		{"nohup", `nohup make &`, []string{"nohup make", "make"}},
+		{"timeout", `timeout -s KILL 5 curl x`, []string{"timeout -s KILL 5 curl x", "curl x"}},
+		{"nice", `nice -n 10 make`, []string{"nice -n 10 make", "make"}},
+		{"xargs", `ls | xargs -I{} rm {}`, []string{"ls", "xargs -I{} rm {}", "rm {}"}},
+		{"xargs value flag", `xargs -n 1 rm`, []string{"xargs -n 1 rm", "rm"}},
+		{"command -v", `command -v rm`, []string{"command -v rm"}},
+		{"find exec", `find . -name x -exec rm {} \; -exec echo {} +`, []string{"find . -name x -exec rm {} ; -exec echo {} +", "rm {}", "echo {}"}},
+		{"git shell alias", `git -c alias.x='!sh -c "rm -rf ~"' x`, []string{"git -c alias.x=!sh -c \"rm -rf ~\" x", "sh -c rm -rf ~", "rm -rf ~"}},
+		{"git alias", `git -c alias.p='push --force' p origin`, []string{"git -c alias.p=push --force p origin", "git push --force origin"}},
+		{"git unused alias", `git -c alias.x='!rm' status`, []string{"git -c alias.x=!rm status"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range ParseAll(tt.script) {
+				got = append(got, strings.Join(c.Words, " "))
+			}
+			if !reflect.DeepEqual(got, tt.words) {
+				t.Errorf("ParseAll(%q) words = %q, want %q", tt.script, got, tt.words)
+			}
+		})
+	}
+}
+
+func TestParseAllUnwrappedCommandFields(t *testing.T) {
+	cmds := ParseAll(`FOO=1 env BAR=2 git commit -m "fix bug"`)
+	if len(cmds) != 2 {
+		t.Fatalf("expected 2 commands, got %+v", cmds)
+	}
+
+	inner := cmds[1]
+	if inner.Program != "git" || inner.Subcommand != "commit" {
+		t.Errorf("unexpected inner command: %+v", inner)
+	}
+	if !reflect.DeepEqual(inner.Env, map[string]string{"FOO": "1", "BAR": "2"}) {
+		t.Errorf("Env = %v", inner.Env)
+	}
+	if inner.Raw != `git commit -m 'fix bug'` {
+		t.Errorf("Raw = %q, should reparse into the same words", inner.Raw)
+	}
+	if got := Parse(inner.Raw).Words; !reflect.DeepEqual(got, inner.Words) {
+		t.Errorf("Raw reparses to %q, want %q", got, inner.Words)
+	}
+}
+
+func TestParseAllUnwrapDepthLimit(t *testing.T) {
+	script := "rm -rf x"
+	for i := 0; i < maxUnwrapDepth+2; i++ {
+		script = quoteWords([]string{"sh", "-c", script})
+	}
+
+	cmds := ParseAll(script)
+	if len(cmds) != maxUnwrapDepth+1 {
+		t.Errorf("expected %d commands, got %d", maxUnwrapDepth+1, len(cmds))
+	}
+	for _, c := range cmds {
+		if c.Program == "rm" {
+			t.Error("payload beyond the depth limit should not be parsed")
+		}
+	}
+}
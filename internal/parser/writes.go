@@ -0,0 +1,665 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// File operations a command can perform.
+const (
+	OpWrite  = "write"  // content replaced or edited in place
+	OpAppend = "append" // content added at the end
+	OpCreate = "create" // file or directory created without content, e.g. touch, mkdir
+	OpDelete = "delete"
+)
+
+// FileWrite is a filesystem change a command makes.
+type FileWrite struct {
+	Path       string
+	Op         string
+	Content    string // complete new content, when HasContent
+	HasContent bool
+	CopyOf     string // the file receives the content of this path (cp, mv, install)
+	PatchFile  string // paths are listed in this patch (patch, git apply); Path is empty
+	Patch      string // inline patch text from a heredoc; Path is empty
+	PatchDir   string // the paths in the patch are relative to this directory (git -C)
+	LinkTo     string // the file is a link to this path (ln)
+	Symbolic   bool   // LinkTo is a symbolic link target, relative to the link's directory
+}
+
+// Writes returns the filesystem changes made by the commands: redirections
+// and a catalog of programs known to mutate files.
+func Writes(cmds []Command) []FileWrite {
+	var writes []FileWrite
+	for _, cmd := range cmds {
+		writes = append(writes, CommandWrites(cmd)...)
+	}
+	return writes
+}
+
+// IsDevice reports whether a path names a device rather than a file,
+// such as the /dev/null target of a discarded output.
+func IsDevice(p string) bool {
+	return strings.HasPrefix(p, "/dev/") && !strings.HasPrefix(p, "/dev/shm/")
+}
+
+// CommandWrites returns the filesystem changes made by a single command.
+func CommandWrites(cmd Command) []FileWrite {
+	var files []FileWrite
+	for _, w := range commandWrites(cmd) {
+		if !IsDevice(w.Path) {
+			files = append(files, w)
+		}
+	}
+	return files
+}
+
+func commandWrites(cmd Command) []FileWrite {
+	writes := redirectWrites(cmd)
+
+	if len(cmd.Words) == 0 {
+		return writes
+	}
+	args := cmd.Words[1:]
+
+	switch filepath.Base(cmd.Program) {
+	case "tee":
+		pos, flags := splitArgs(args, nil)
+		op := OpWrite
+		if flags["-a"] || flags["--append"] {
+			op = OpAppend
+		}
+		content, ok := stdinContent(cmd)
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Path: p, Op: op, Content: content, HasContent: ok && op == OpWrite})
+		}
+	case "sed":
+		writes = append(writes, inPlaceWrites(sedArgs(args), sedValueFlags, hasSedInPlace, "-e", "--expression", "-f", "--file")...)
+	case "perl":
+		writes = append(writes, inPlaceWrites(args, perlValueFlags, hasPerlInPlace, "-e", "-E")...)
+	case "install":
+		writes = append(writes, installWrites(args)...)
+	case "cp", "rsync":
+		writes = append(writes, copyWrites(args, false)...)
+	case "mv":
+		writes = append(writes, copyWrites(args, true)...)
+	case "ln":
+		writes = append(writes, linkWrites(args)...)
+	case "rm", "rmdir", "unlink", "shred":
+		pos, _ := splitArgs(args, nil)
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Path: p, Op: OpDelete})
+		}
+	case "touch", "mkdir", "mkfifo":
+		pos, _ := splitArgs(args, map[string]bool{"-r": true, "-t": true, "-d": true, "-m": true, "--mode": true, "--reference": true, "--date": true})
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Path: p, Op: OpCreate})
+		}
+	case "truncate":
+		writes = append(writes, truncateWrites(args)...)
+	case "dd":
+		for _, a := range args {
+			if strings.HasPrefix(a, "of=") {
+				writes = append(writes, FileWrite{Path: strings.TrimPrefix(a, "of="), Op: OpWrite})
+			}
+		}
+	case "patch":
+		writes = append(writes, patchWrites(cmd, args)...)
+	case "git":
+		writes = append(writes, gitWrites(cmd)...)
+	case "sort":
+		writes = append(writes, outputFlagWrites(args, "-o", "--output")...)
+	case "curl":
+		writes = append(writes, outputFlagWrites(args, "-o", "--output")...)
+	case "wget":
+		writes = append(writes, outputFlagWrites(args, "-O", "--output-document")...)
+	case "go":
+		if cmd.Subcommand == "build" || cmd.Subcommand == "test" {
+			writes = append(writes, outputFlagWrites(args[1:], "-o")...)
+		}
+	case "gcc", "cc", "clang", "g++", "clang++":
+		writes = append(writes, outputFlagWrites(args, "-o")...)
+	}
+
+	return writes
+}
+
+// redirectWrites returns the files written by output redirections.
+func redirectWrites(cmd Command) []FileWrite {
+	var writes []FileWrite
+	for _, r := range cmd.Redirects {
+		if !r.IsFile() {
+			continue
+		}
+		switch r.Op {
+		case ">", ">|", "&>", ">&", "<>":
+			w := FileWrite{Path: r.Target, Op: OpWrite}
+			if r.Op != "<>" && (r.Fd == "" || r.Fd == "1") {
+				w.Content, w.HasContent = outputContent(cmd)
+			}
+			writes = append(writes, w)
+		case ">>", "&>>":
+			writes = append(writes, FileWrite{Path: r.Target, Op: OpAppend})
+		}
+	}
+	return writes
+}
+
+// outputContent computes what a command prints, for the few commands where
+// that follows from the command line alone.
+func outputContent(cmd Command) (string, bool) {
+	if len(cmd.Words) == 0 {
+		return "", false
+	}
+	args := cmd.Words[1:]
+	for _, a := range args {
+		if !isLiteral(a) {
+			return "", false
+		}
+	}
+
+	switch filepath.Base(cmd.Program) {
+	case "echo":
+		newline := "\n"
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			switch args[0] {
+			case "-n":
+				newline = ""
+			case "-E":
+			default:
+				return "", false // -e interprets escapes
+			}
+			args = args[1:]
+		}
+		return strings.Join(args, " ") + newline, true
+	case "printf":
+		if len(args) == 1 && !strings.ContainsAny(args[0], `%\`) {
+			return args[0], true
+		}
+	case "cat":
+		if len(args) == 0 {
+			return stdinContent(cmd)
+		}
+	case "true", ":":
+		return "", true
+	}
+	return "", false
+}
+
+// stdinContent returns the text fed to the command by a heredoc or here-string.
+func stdinContent(cmd Command) (string, bool) {
+	for _, r := range cmd.Redirects {
+		if r.Fd != "" && r.Fd != "0" {
+			continue
+		}
+		switch r.Op {
+		case "<<", "<<-":
+			if isLiteral(r.Body) || quotedDelimiter(cmd.Raw, r.Target) {
+				return r.Body, true
+			}
+		case "<<<":
+			if isLiteral(r.Target) {
+				return r.Target + "\n", true
+			}
+		}
+	}
+	return "", false
+}
+
+// quotedDelimiter reports whether a heredoc delimiter was quoted, which turns off expansion in the body.
+func quotedDelimiter(raw, delimiter string) bool {
+	return strings.Contains(raw, "'"+delimiter+"'") || strings.Contains(raw, `"`+delimiter+`"`)
+}
+
+func isLiteral(s string) bool {
+	return !strings.ContainsAny(s, "$`")
+}
+
+var (
+	sedValueFlags  = map[string]bool{"-e": true, "--expression": true, "-f": true, "--file": true, "-l": true, "--line-length": true}
+	perlValueFlags = map[string]bool{"-e": true, "-E": true, "-I": true, "-M": true, "-m": true}
+)
+
+// inPlaceWrites returns the files a stream editor edits in place. Without
+// a script flag, the first positional argument is the script.
+func inPlaceWrites(args []string, valueFlags map[string]bool, inPlace func(string) bool, scriptFlags ...string) []FileWrite {
+	edits := false
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if inPlace(a) {
+			edits = true
+		}
+	}
+	if !edits {
+		return nil
+	}
+
+	pos, flags := splitArgs(args, valueFlags)
+	hasScript := false
+	for _, f := range scriptFlags {
+		if flags[f] {
+			hasScript = true
+		}
+	}
+	if !hasScript && len(pos) > 0 {
+		pos = pos[1:]
+	}
+
+	var writes []FileWrite
+	for _, p := range pos {
+		writes = append(writes, FileWrite{Path: p, Op: OpWrite})
+	}
+	return writes
+}
+
+// sedArgs drops the empty argument after -i, the backup suffix of BSD sed
+// when it keeps no backup, so that it is not taken for the script.
+func sedArgs(args []string) []string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if a == "-i" && i+1 < len(args) && args[i+1] == "" {
+			return append(append([]string{}, args[:i+1]...), args[i+2:]...)
+		}
+	}
+	return args
+}
+
+func hasSedInPlace(arg string) bool {
+	return arg == "--in-place" || strings.HasPrefix(arg, "--in-place=") || strings.HasPrefix(arg, "-i") || hasShortFlag('i')(arg)
+}
+
+// hasPerlInPlace reports whether a perl switch cluster, such as -pi or
+// -i.bak, edits in place. -i takes the rest of the cluster as its backup
+// extension, and so do the switches with a value: in -Mstrict or -Idir the
+// i is part of the value.
+func hasPerlInPlace(arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
+		return false
+	}
+	for i := 1; i < len(arg); i++ {
+		switch c := arg[i]; {
+		case c == 'i':
+			return true
+		case c == '0' || c == 'l' || c == 'C':
+			// An optional number follows.
+			for i+1 < len(arg) && arg[i+1] >= '0' && arg[i+1] <= '9' {
+				i++
+			}
+		case strings.IndexByte("eEMmIxdDF", c) >= 0:
+			return false
+		case !isLetter(c):
+			return false
+		}
+	}
+	return false
+}
+
+// hasShortFlag returns a matcher for a short flag, alone or in a cluster such as -pi.
+func hasShortFlag(flag byte) func(string) bool {
+	return func(arg string) bool {
+		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") || len(arg) < 2 {
+			return false
+		}
+		for i := 1; i < len(arg); i++ {
+			c := arg[i]
+			if c == flag {
+				return true
+			}
+			if !isLetter(c) {
+				return false
+			}
+		}
+		return false
+	}
+}
+
+var copyValueFlags = map[string]bool{
+	"-t": true, "--target-directory": true, "-S": true, "--suffix": true,
+	"-m": true, "--mode": true, "-o": true, "--owner": true, "-g": true, "--group": true,
+	"-e": true, "--rsh": true, "--exclude": true, "--include": true,
+}
+
+// copyWrites returns the destinations of cp, install, rsync and mv.
+// A destination ending in / or receiving several sources is a directory.
+func copyWrites(args []string, move bool) []FileWrite {
+	pos, _ := splitArgs(args, copyValueFlags)
+	target, targetDir := flagValue(args, "-t", "--target-directory")
+	var sources []string
+	switch {
+	case targetDir:
+		sources = pos
+	case len(pos) >= 2:
+		sources = pos[:len(pos)-1]
+		target = pos[len(pos)-1]
+		targetDir = len(sources) > 1 || strings.HasSuffix(target, "/")
+	default:
+		return nil
+	}
+
+	var writes []FileWrite
+	for _, src := range sources {
+		if isRemote(src) || isRemote(target) {
+			continue
+		}
+		dest := target
+		if targetDir {
+			dest = filepath.Join(target, filepath.Base(src))
+		}
+		writes = append(writes, FileWrite{Path: dest, Op: OpWrite, CopyOf: src})
+		if move {
+			writes = append(writes, FileWrite{Path: src, Op: OpDelete})
+		}
+	}
+	return writes
+}
+
+// installWrites returns the destinations of install, or the directories
+// install -d creates.
+func installWrites(args []string) []FileWrite {
+	pos, flags := splitArgs(args, copyValueFlags)
+	if !flags["-d"] {
+		return copyWrites(args, false)
+	}
+	var writes []FileWrite
+	for _, p := range pos {
+		writes = append(writes, FileWrite{Path: p, Op: OpCreate})
+	}
+	return writes
+}
+
+// isRemote reports whether an rsync or scp operand names another host.
+func isRemote(p string) bool {
+	i := strings.Index(p, ":")
+	return i > 0 && !strings.Contains(p[:i], "/")
+}
+
+func truncateWrites(args []string) []FileWrite {
+	pos, _ := splitArgs(args, map[string]bool{"-s": true, "--size": true, "-r": true, "--reference": true})
+	size, _ := flagValue(args, "-s", "--size")
+
+	var writes []FileWrite
+	for _, p := range pos {
+		writes = append(writes, FileWrite{Path: p, Op: OpWrite, HasContent: size == "0"})
+	}
+	return writes
+}
+
+var linkValueFlags = map[string]bool{
+	"-t": true, "--target-directory": true, "-S": true, "--suffix": true,
+}
+
+// linkWrites returns the links ln creates. A single target is linked into
+// the working directory under its own name; a destination ending in / or
+// receiving several targets is a directory.
+func linkWrites(args []string) []FileWrite {
+	pos, flags := splitArgs(args, linkValueFlags)
+	symbolic := flags["--symbolic"]
+	for f := range flags {
+		if hasShortFlag('s')(f) {
+			symbolic = true
+		}
+	}
+
+	dir, targetDir := flagValue(args, "-t", "--target-directory")
+	var targets []string
+	switch {
+	case targetDir:
+		targets = pos
+	case len(pos) >= 2:
+		targets = pos[:len(pos)-1]
+		dir = pos[len(pos)-1]
+		targetDir = len(targets) > 1 || strings.HasSuffix(dir, "/")
+	case len(pos) == 1:
+		targets = pos
+		dir, targetDir = ".", true
+	default:
+		return nil
+	}
+
+	var writes []FileWrite
+	for _, target := range targets {
+		link := dir
+		if targetDir {
+			link = filepath.Join(dir, filepath.Base(target))
+		}
+		writes = append(writes, FileWrite{Path: link, Op: OpCreate, LinkTo: target, Symbolic: symbolic})
+	}
+	return writes
+}
+
+var patchValueFlags = map[string]bool{
+	"-i": true, "--input": true, "-o": true, "--output": true, "-p": true, "--strip": true,
+	"-d": true, "--directory": true, "-r": true, "--reject-file": true, "-B": true, "-z": true, "-D": true,
+}
+
+// patchWrites returns the files changed by patch: the file named on the
+// command line, or the files listed in the patch itself.
+func patchWrites(cmd Command, args []string) []FileWrite {
+	pos, _ := splitArgs(args, patchValueFlags)
+
+	var writes []FileWrite
+	if out, ok := flagValue(args, "-o", "--output"); ok && out != "-" {
+		writes = append(writes, FileWrite{Path: out, Op: OpWrite})
+	}
+	if len(pos) > 0 {
+		return append(writes, FileWrite{Path: pos[0], Op: OpWrite})
+	}
+
+	if in, ok := flagValue(args, "-i", "--input"); ok {
+		return append(writes, FileWrite{Op: OpWrite, PatchFile: in})
+	}
+	return append(writes, stdinPatch(cmd)...)
+}
+
+// gitWrites returns the working tree files changed by git subcommands.
+func gitWrites(cmd Command) []FileWrite {
+	dir, sub, args := gitSubcommand(cmd.Words)
+	writes := gitSubcommandWrites(cmd, sub, args)
+	if dir == "" {
+		return writes
+	}
+	for i := range writes {
+		writes[i].Path = inDir(dir, writes[i].Path)
+		writes[i].PatchFile = inDir(dir, writes[i].PatchFile)
+		if writes[i].PatchFile != "" || writes[i].Patch != "" {
+			writes[i].PatchDir = dir
+		}
+	}
+	return writes
+}
+
+// gitSubcommand skips the global options before a git subcommand and
+// returns the directory set with -C, the subcommand and its arguments.
+func gitSubcommand(words []string) (dir, sub string, args []string) {
+	for i := 1; i < len(words); i++ {
+		a := words[i]
+		switch {
+		case a == "-C" && i+1 < len(words):
+			i++
+			dir = inDir(dir, words[i])
+		case a == "-c" || a == "--git-dir" || a == "--work-tree" || a == "--namespace" || a == "--config-env":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return dir, a, words[i+1:]
+		}
+	}
+	return dir, "", nil
+}
+
+// inDir joins a relative path to dir.
+func inDir(dir, p string) string {
+	if dir == "" || p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+func gitSubcommandWrites(cmd Command, sub string, args []string) []FileWrite {
+	switch sub {
+	case "apply":
+		_, flags := splitArgs(args, map[string]bool{"--directory": true, "--exclude": true, "--include": true, "-C": true})
+		for _, f := range []string{"--check", "--stat", "--numstat", "--summary"} {
+			if flags[f] && !flags["--apply"] {
+				return nil
+			}
+		}
+		pos, _ := splitArgs(args, map[string]bool{"--directory": true, "--exclude": true, "--include": true, "-C": true})
+		if len(pos) == 0 {
+			return stdinPatch(cmd)
+		}
+		var writes []FileWrite
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Op: OpWrite, PatchFile: p})
+		}
+		return writes
+	case "rm":
+		pos, flags := splitArgs(args, nil)
+		if flags["--cached"] {
+			return nil
+		}
+		var writes []FileWrite
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Path: p, Op: OpDelete})
+		}
+		return writes
+	case "mv":
+		return copyWrites(args, true)
+	case "restore":
+		pos, _ := splitArgs(args, map[string]bool{"-s": true, "--source": true})
+		var writes []FileWrite
+		for _, p := range pos {
+			writes = append(writes, FileWrite{Path: p, Op: OpWrite})
+		}
+		return writes
+	case "checkout":
+		// Only paths after -- are unambiguous; anything else may be a branch.
+		for i, a := range args {
+			if a == "--" {
+				var writes []FileWrite
+				for _, p := range args[i+1:] {
+					writes = append(writes, FileWrite{Path: p, Op: OpWrite})
+				}
+				return writes
+			}
+		}
+	}
+	return nil
+}
+
+// stdinPatch returns a patch fed through a redirect or heredoc.
+func stdinPatch(cmd Command) []FileWrite {
+	for _, r := range cmd.Redirects {
+		if r.Fd != "" && r.Fd != "0" {
+			continue
+		}
+		switch r.Op {
+		case "<":
+			return []FileWrite{{Op: OpWrite, PatchFile: r.Target}}
+		case "<<", "<<-":
+			return []FileWrite{{Op: OpWrite, Patch: r.Body}}
+		}
+	}
+	return nil
+}
+
+// outputFlagWrites returns the file named by an output flag such as -o.
+func outputFlagWrites(args []string, flags ...string) []FileWrite {
+	if out, ok := flagValue(args, flags...); ok && out != "-" && out != "" {
+		return []FileWrite{{Path: out, Op: OpWrite}}
+	}
+	return nil
+}
+
+// PatchTargets lists the files a unified or git diff writes and deletes.
+func PatchTargets(patch string) []FileWrite {
+	var writes []FileWrite
+	var from string
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			from = patchPath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			to := patchPath(line[4:])
+			switch {
+			case to == "/dev/null" && from != "":
+				writes = append(writes, FileWrite{Path: from, Op: OpDelete})
+			case to != "/dev/null" && to != "":
+				writes = append(writes, FileWrite{Path: to, Op: OpWrite})
+			}
+			from = ""
+		}
+	}
+	return writes
+}
+
+// patchPath strips the timestamp and the a/ or b/ prefix from a diff header path.
+func patchPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// splitArgs separates positional arguments from flags. Flags in valueFlags
+// consume the next argument unless their value is attached with =.
+// Everything after -- is positional.
+func splitArgs(args []string, valueFlags map[string]bool) ([]string, map[string]bool) {
+	var pos []string
+	flags := make(map[string]bool)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			pos = append(pos, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			pos = append(pos, a)
+			continue
+		}
+		name := a
+		if j := strings.IndexByte(a, '='); j > 0 {
+			name = a[:j]
+			flags[name] = true
+			continue
+		}
+		flags[name] = true
+		if valueFlags[name] {
+			i++
+		}
+	}
+	return pos, flags
+}
+
+// flagValue returns the value of the first of the given flags,
+// written as -o value, -ovalue, --output value or --output=value.
+func flagValue(args []string, names ...string) (string, bool) {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		for _, name := range names {
+			switch {
+			case a == name && i+1 < len(args):
+				return args[i+1], true
+			case strings.HasPrefix(name, "--") && strings.HasPrefix(a, name+"="):
+				return a[len(name)+1:], true
+			case !strings.HasPrefix(name, "--") && len(name) == 2 && strings.HasPrefix(a, name) && len(a) > 2:
+				return a[2:], true
+			}
+		}
+	}
+	return "", false
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrites(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []FileWrite
+	}{
+		{"read only", "cat internal/foo.go | grep x", nil},
+		{"redirect echo", "echo x > internal/foo.go", []FileWrite{{Path: "internal/foo.go", Op: OpWrite, Content: "x\n", HasContent: true}}},
+		{"redirect echo -n", "echo -n 'a b' > f", []FileWrite{{Path: "f", Op: OpWrite, Content: "a b", HasContent: true}}},
+		{"redirect echo -e", `echo -e 'a\tb' > f`, []FileWrite{{Path: "f", Op: OpWrite}}},
+		{"redirect expansion", "echo $HOME > f", []FileWrite{{Path: "f", Op: OpWrite}}},
+		{"redirect printf", "printf 'package x' > x.go", []FileWrite{{Path: "x.go", Op: OpWrite, Content: "package x", HasContent: true}}},
+		{"redirect printf format", "printf '%s' x > x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"append", "echo x >> log.txt", []FileWrite{{Path: "log.txt", Op: OpAppend}}},
+		{"stderr", "make 2> err.log", []FileWrite{{Path: "err.log", Op: OpWrite}}},
+		{"dev null", "make > /dev/null 2>&1", nil},
+		{"heredoc", "cat > a.go <<'EOF'\npackage a\nEOF", []FileWrite{{Path: "a.go", Op: OpWrite, Content: "package a\n", HasContent: true}}},
+		{"heredoc expansion", "cat > a.go <<EOF\n$X\nEOF", []FileWrite{{Path: "a.go", Op: OpWrite}}},
+		{"here-string", "cat > a <<< hi", []FileWrite{{Path: "a", Op: OpWrite, Content: "hi\n", HasContent: true}}},
+		{"tee", "go test | tee out.txt", []FileWrite{{Path: "out.txt", Op: OpWrite}}},
+		{"tee append", "tee -a a b < in", []FileWrite{{Path: "a", Op: OpAppend}, {Path: "b", Op: OpAppend}}},
+		{"sed in place", "sed -i 's/a/b/' x.go y.go", []FileWrite{{Path: "x.go", Op: OpWrite}, {Path: "y.go", Op: OpWrite}}},
+		{"sed backup suffix", "sed -i.bak -e 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"sed stdout", "sed 's/a/b/' x.go", nil},
+		{"perl in place", "perl -pi -e 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"perl backup extension", "perl -i.bak -pe 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"perl separate switches", "perl -l -p -i -e 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"perl module with i", "perl -Mstrict -ne 'print' x.go", nil},
+		{"perl include dir with i", "perl -Ilib/ci -ne 'print' x.go", nil},
+		{"perl script with i", "perl -ne 'print if /-i/' x.go", nil},
+		{"sed empty suffix", "sed -i '' 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"sed empty suffix with script flag", "sed -i '' -e 's/a/b/' x.go", []FileWrite{{Path: "x.go", Op: OpWrite}}},
+		{"cp", "cp a.go b.go", []FileWrite{{Path: "b.go", Op: OpWrite, CopyOf: "a.go"}}},
+		{"cp into dir", "cp -r a.go b.go dst", []FileWrite{{Path: "dst/a.go", Op: OpWrite, CopyOf: "a.go"}, {Path: "dst/b.go", Op: OpWrite, CopyOf: "b.go"}}},
+		{"cp target dir", "cp -t dst a.go", []FileWrite{{Path: "dst/a.go", Op: OpWrite, CopyOf: "a.go"}}},
+		{"cp -d", "cp -d src dst", []FileWrite{{Path: "dst", Op: OpWrite, CopyOf: "src"}}},
+		{"rsync -d", "rsync -d src dst", []FileWrite{{Path: "dst", Op: OpWrite, CopyOf: "src"}}},
+		{"install -d", "install -d bin lib", []FileWrite{{Path: "bin", Op: OpCreate}, {Path: "lib", Op: OpCreate}}},
+		{"mv", "mv a.go b.go", []FileWrite{{Path: "b.go", Op: OpWrite, CopyOf: "a.go"}, {Path: "a.go", Op: OpDelete}}},
+		{"rsync remote", "rsync -a host:/src dst", nil},
+		{"ln symbolic", "ln -sf ../shared/config.yml config.yml", []FileWrite{{Path: "config.yml", Op: OpCreate, LinkTo: "../shared/config.yml", Symbolic: true}}},
+		{"ln into cwd", "ln --symbolic /etc", []FileWrite{{Path: "etc", Op: OpCreate, LinkTo: "/etc", Symbolic: true}}},
+		{"ln target dir", "ln -t links a b", []FileWrite{{Path: "links/a", Op: OpCreate, LinkTo: "a"}, {Path: "links/b", Op: OpCreate, LinkTo: "b"}}},
+		{"rm", "rm -rf build -- -x", []FileWrite{{Path: "build", Op: OpDelete}, {Path: "-x", Op: OpDelete}}},
+		{"touch", "touch -d now a", []FileWrite{{Path: "a", Op: OpCreate}}},
+		{"mkdir", "mkdir -p -m 755 a/b", []FileWrite{{Path: "a/b", Op: OpCreate}}},
+		{"truncate empty", "truncate -s 0 f", []FileWrite{{Path: "f", Op: OpWrite, HasContent: true}}},
+		{"truncate size", "truncate --size=10 f", []FileWrite{{Path: "f", Op: OpWrite}}},
+		{"dd", "dd if=/dev/zero of=disk.img bs=1M", []FileWrite{{Path: "disk.img", Op: OpWrite}}},
+		{"patch file", "patch -p1 main.go fix.diff", []FileWrite{{Path: "main.go", Op: OpWrite}}},
+		{"patch stdin", "patch -p1 < fix.diff", []FileWrite{{Op: OpWrite, PatchFile: "fix.diff"}}},
+		{"patch input", "patch -p1 -i fix.diff", []FileWrite{{Op: OpWrite, PatchFile: "fix.diff"}}},
+		{"git apply", "git apply fix.diff", []FileWrite{{Op: OpWrite, PatchFile: "fix.diff"}}},
+		{"git apply check", "git apply --check fix.diff", nil},
+		{"git apply heredoc", "git apply <<'EOF'\n+++ b/x\nEOF", []FileWrite{{Op: OpWrite, Patch: "+++ b/x\n"}}},
+		{"git rm", "git rm -r old", []FileWrite{{Path: "old", Op: OpDelete}}},
+		{"git rm cached", "git rm --cached old", nil},
+		{"git checkout paths", "git checkout HEAD -- a.go", []FileWrite{{Path: "a.go", Op: OpWrite}}},
+		{"git checkout branch", "git checkout main", nil},
+		{"git -C apply", "git -C . apply p.diff", []FileWrite{{Op: OpWrite, PatchFile: "p.diff", PatchDir: "."}}},
+		{"git -C sub apply", "git -C sub apply p.diff", []FileWrite{{Op: OpWrite, PatchFile: "sub/p.diff", PatchDir: "sub"}}},
+		{"git -c restore", "git -c core.quotepath=off restore f", []FileWrite{{Path: "f", Op: OpWrite}}},
+		{"git --git-dir checkout", "git --git-dir=.git checkout -- f", []FileWrite{{Path: "f", Op: OpWrite}}},
+		{"git --work-tree rm", "git --work-tree . --no-pager rm old", []FileWrite{{Path: "old", Op: OpDelete}}},
+		{"git -C restore", "git -C sub restore f /abs/g", []FileWrite{{Path: "sub/f", Op: OpWrite}, {Path: "/abs/g", Op: OpWrite}}},
+		{"git nested -C", "git -C a -C b rm f", []FileWrite{{Path: "a/b/f", Op: OpDelete}}},
+		{"go build output", "go build -o bin/app ./cmd/app", []FileWrite{{Path: "bin/app", Op: OpWrite}}},
+		{"curl output", "curl -o out.html https://example.com", []FileWrite{{Path: "out.html", Op: OpWrite}}},
+		{"sort output", "sort -o sorted.txt in.txt", []FileWrite{{Path: "sorted.txt", Op: OpWrite}}},
+		{"list", "echo a > a && rm b", []FileWrite{{Path: "a", Op: OpWrite, Content: "a\n", HasContent: true}, {Path: "b", Op: OpDelete}}},
+		{"compound redirect", "(cd x; make) > build.log", []FileWrite{{Path: "build.log", Op: OpWrite}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Writes(ParseAll(tt.script))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Writes(%q) = %+v, want %+v", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchTargets(t *testing.T) {
+	patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-x
++y
+--- a/old.go
++++ /dev/null
+--- /dev/null
++++ b/new.go	2024-01-01 00:00:00
+`
+	want := []FileWrite{
+		{Path: "a.go", Op: OpWrite},
+		{Path: "old.go", Op: OpDelete},
+		{Path: "new.go", Op: OpWrite},
+	}
+	if got := PatchTargets(patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("PatchTargets() = %+v, want %+v", got, want)
+	}
+}
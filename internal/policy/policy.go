@@ -4,10 +4,15 @@ package policy
 import "github.com/adrianpk/watchman/internal/parser"
 
 // Decision represents the result of evaluating a command against rules.
+// A decision that asks is not allowed: the user decides, prompted with Reason.
+// Audit holds the reasons of violations found by checks in audit mode,
+// which do not change the decision.
 type Decision struct {
 	Allowed bool
+	Ask     bool
 	Reason  string
 	Warning string
+	Audit   []string
 }
 
 // Rule evaluates a command and returns a decision.
@@ -20,13 +25,23 @@ type Policy struct {
 	Rules []Rule
 }
 
-// Evaluate runs all rules against the command. First rule that denies wins.
+// Evaluate runs all rules against the command. First rule that denies wins;
+// otherwise the first rule that asks does.
 func (p *Policy) Evaluate(cmd parser.Command) Decision {
+	var ask *Decision
 	for _, rule := range p.Rules {
 		decision := rule.Evaluate(cmd)
-		if !decision.Allowed {
+		switch {
+		case decision.Ask:
+			if ask == nil {
+				ask = &decision
+			}
+		case !decision.Allowed:
 			return decision
 		}
 	}
+	if ask != nil {
+		return *ask
+	}
 	return Decision{Allowed: true}
 }
@@ -20,12 +20,21 @@ func (r denyAllRule) Evaluate(cmd parser.Command) Decision {
 	return Decision{Allowed: false, Reason: r.reason}
 }
 
+type askAllRule struct {
+	reason string
+}
+
+func (r askAllRule) Evaluate(cmd parser.Command) Decision {
+	return Decision{Ask: true, Reason: r.reason}
+}
+
 func TestPolicyEvaluate(t *testing.T) {
 	tests := []struct {
 		name        string
 		rules       []Rule
 		cmd         string
 		wantAllowed bool
+		wantAsk     bool
 		wantReason  string
 	}{
 		{
@@ -68,6 +77,26 @@ func TestPolicyEvaluate(t *testing.T) {
 			wantAllowed: false,
 			wantReason:  "denied",
 		},
+		{
+			name:       "ask then allow",
+			rules:      []Rule{askAllRule{reason: "confirm"}, allowAllRule{}},
+			cmd:        "git push",
+			wantAsk:    true,
+			wantReason: "confirm",
+		},
+		{
+			name:       "deny wins over an earlier ask",
+			rules:      []Rule{askAllRule{reason: "confirm"}, denyAllRule{reason: "denied"}},
+			cmd:        "git push",
+			wantReason: "denied",
+		},
+		{
+			name:       "first ask wins",
+			rules:      []Rule{askAllRule{reason: "first"}, askAllRule{reason: "second"}},
+			cmd:        "git push",
+			wantAsk:    true,
+			wantReason: "first",
+		},
 	}
 
 	for _, tt := range tests {
@@ -79,6 +108,9 @@ func TestPolicyEvaluate(t *testing.T) {
 			if got.Allowed != tt.wantAllowed {
 				t.Errorf("Evaluate() Allowed = %v, want %v", got.Allowed, tt.wantAllowed)
 			}
+			if got.Ask != tt.wantAsk {
+				t.Errorf("Evaluate() Ask = %v, want %v", got.Ask, tt.wantAsk)
+			}
 			if got.Reason != tt.wantReason {
 				t.Errorf("Evaluate() Reason = %q, want %q", got.Reason, tt.wantReason)
 			}
@@ -2,8 +2,12 @@ package policy
 
 import (
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
+
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/glob"
 )
 
 // alwaysProtected contains paths that are NEVER accessible, regardless of config.
@@ -37,87 +41,266 @@ func IsAlwaysProtected(p string) bool {
 		return false
 	}
 
+	// A link is protected when the file it leads to is, and a protected
+	// directory that is itself a link is protected under both names.
 	absPath := resolvePath(p)
+	for _, candidate := range []string{absPath, resolveSymlinks(absPath)} {
+		filename := filepath.Base(candidate)
+		for _, protected := range protectedFilenames {
+			if filename == protected {
+				return true
+			}
+		}
 
-	filename := filepath.Base(absPath)
-	for _, protected := range protectedFilenames {
-		if filename == protected {
-			return true
+		for _, pattern := range alwaysProtected {
+			isDir := strings.HasSuffix(pattern, "/")
+			expandedPattern := expandTilde(strings.TrimSuffix(pattern, "/"))
+			for _, target := range []string{expandedPattern, resolveSymlinks(expandedPattern)} {
+				if candidate == target {
+					return true
+				}
+				if isDir && strings.HasPrefix(candidate, target+string(filepath.Separator)) {
+					return true
+				}
+			}
 		}
 	}
 
-	for _, pattern := range alwaysProtected {
-		isDir := strings.HasSuffix(pattern, "/")
+	return false
+}
 
-		expandedPattern := strings.TrimSuffix(pattern, "/")
-		if strings.HasPrefix(expandedPattern, "~/") {
-			if home, err := os.UserHomeDir(); err == nil {
-				expandedPattern = filepath.Join(home, expandedPattern[2:])
-			}
-		}
+// Protected holds the protected paths added by config on top of the
+// hardcoded ones, which always apply.
+type Protected struct {
+	Paths     []string
+	Filenames []string
+	Globs     []string
+}
+
+// NewProtected creates the protected set from config.
+func NewProtected(cfg *config.ProtectedConfig) *Protected {
+	if cfg == nil {
+		return &Protected{}
+	}
+	return &Protected{
+		Paths:     cfg.Paths,
+		Filenames: cfg.Filenames,
+		Globs:     cfg.Globs,
+	}
+}
 
-		if isDir {
-			if absPath == expandedPattern || strings.HasPrefix(absPath, expandedPattern+string(filepath.Separator)) {
+// Match reports whether a path is protected by config. Like the hardcoded
+// paths, a link is protected when the file it leads to is. Paths are taken
+// literally. Relative entries start at the top of the repository around
+// cwd, or at cwd outside a repository; a relative path starts at cwd.
+func (p *Protected) Match(path, cwd string) bool {
+	if p == nil || path == "" {
+		return false
+	}
+
+	patterns := append([]string{}, p.Globs...)
+	for _, entry := range p.Paths {
+		patterns = append(patterns, literalPattern(entry))
+	}
+
+	absPath := absolutePath(path, cwd)
+	roots := projectRoots(cwd)
+	for _, candidate := range []string{absPath, resolveSymlinks(absPath)} {
+		filename := filepath.Base(candidate)
+		for _, protected := range p.Filenames {
+			if filename == protected {
 				return true
 			}
-		} else if absPath == expandedPattern {
+		}
+		if matchPatterns(candidate, roots, patterns) {
 			return true
 		}
 	}
-
 	return false
 }
 
-// resolvePath converts a path to absolute form.
-func resolvePath(p string) string {
-	if strings.HasPrefix(p, "~/") {
-		if home, err := os.UserHomeDir(); err == nil {
-			p = filepath.Join(home, p[2:])
+// literalPattern turns a path into a pattern that matches only that path
+// and, for a directory, what is inside it.
+func literalPattern(p string) string {
+	var b strings.Builder
+	for i, r := range p {
+		if strings.ContainsRune(`*?[]{}\`, r) || (i == 0 && r == '!') {
+			b.WriteByte('\\')
 		}
+		b.WriteRune(r)
+	}
+	pattern := b.String()
+	if !filepath.IsAbs(p) && !strings.HasPrefix(p, "~") {
+		pattern = "./" + strings.TrimPrefix(pattern, "./")
 	}
+	return pattern
+}
+
+// resolvePath converts a path to absolute form.
+func resolvePath(p string) string {
+	return absolutePath(p, "")
+}
+
+// absolutePath makes a path absolute against cwd, or against the process
+// directory when cwd is empty.
+func absolutePath(p, cwd string) string {
+	p = expandTilde(p)
 	if filepath.IsAbs(p) {
 		return filepath.Clean(p)
 	}
-	if cwd, err := os.Getwd(); err == nil {
-		return filepath.Clean(filepath.Join(cwd, p))
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return filepath.Clean(p)
+		}
+		cwd = wd
 	}
-	return filepath.Clean(p)
+	return filepath.Clean(filepath.Join(cwd, p))
 }
 
-// MatchProtectedPath checks if a path matches a protected pattern.
-// Supports ~/ expansion and directory patterns (ending with /).
-func MatchProtectedPath(path, pattern string) bool {
-	absPath := resolvePath(path)
+// maxLinkDepth bounds the dangling links followed by resolveSymlinks.
+const maxLinkDepth = 40
 
-	// Check if pattern is a filename (no path separators)
-	if !strings.Contains(pattern, "/") {
-		filename := filepath.Base(absPath)
-		return filename == pattern
+// resolveSymlinks evaluates the links in the longest existing prefix of an
+// absolute path and appends the remainder, which does not exist yet. A
+// dangling link is followed to where writing through it would create the
+// file. The path is returned unchanged when nothing can be resolved.
+func resolveSymlinks(absPath string) string {
+	return followLinks(absPath, 0)
+}
+
+func followLinks(absPath string, depth int) string {
+	var rest []string
+	for p := absPath; ; {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		if target, err := os.Readlink(p); err == nil && depth < maxLinkDepth {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			return followLinks(filepath.Join(append([]string{target}, rest...)...), depth+1)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return absPath
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
+	}
+}
+
+// resolvePattern resolves the links in an absolute path pattern, keeping
+// the trailing slash of directory patterns. Relative patterns are returned
+// unchanged.
+func resolvePattern(pattern string) string {
+	expanded := expandTilde(pattern)
+	if !filepath.IsAbs(expanded) {
+		return pattern
+	}
+	resolved := resolveSymlinks(filepath.Clean(expanded))
+	if strings.HasSuffix(pattern, "/") && resolved != "/" {
+		resolved += "/"
+	}
+	return resolved
+}
+
+// expandTilde resolves the ~, ~/path and ~user/path forms.
+// A path naming an unknown user is returned unchanged.
+func expandTilde(p string) string {
+	if !strings.HasPrefix(p, "~") {
+		return p
 	}
+	name, rest, _ := strings.Cut(p[1:], "/")
 
-	return matchPath(absPath, pattern)
+	var home string
+	if name == "" {
+		h, err := os.UserHomeDir()
+		if err != nil {
+			return p
+		}
+		home = h
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return p
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, rest)
 }
 
-// matchPath checks if a path matches a pattern.
-// Supports exact match and prefix match (pattern ending with /).
-func matchPath(path, pattern string) bool {
-	if strings.HasPrefix(pattern, "~/") {
-		if home, err := os.UserHomeDir(); err == nil {
-			pattern = filepath.Join(home, pattern[2:])
+// MatchProtectedPath checks if a path matches a protected pattern.
+// Patterns are gitignore-style, see matchPatterns, relative to the
+// repository around cwd, or to cwd outside a repository. Links are matched both as written and by the file they lead to.
+func MatchProtectedPath(path, pattern, cwd string) bool {
+	absPath := absolutePath(path, cwd)
+	roots := projectRoots(cwd)
+	for _, candidate := range []string{absPath, resolveSymlinks(absPath)} {
+		if matchPatterns(candidate, roots, []string{pattern}) {
+			return true
 		}
 	}
+	return false
+}
 
-	if path == pattern {
-		return true
+// projectRoots returns the root relative protected entries start at: the
+// top of the repository around cwd, where the repository config lives, or
+// cwd outside a repository. It returns none when cwd is empty, so
+// matchPatterns falls back to the process directory.
+func projectRoots(cwd string) []string {
+	if cwd == "" {
+		return nil
 	}
+	cwd = filepath.Clean(cwd)
+	if top := config.RepositoryRoot(cwd); top != "" {
+		return []string{top}
+	}
+	return []string{cwd}
+}
 
-	if strings.HasSuffix(pattern, "/") {
-		return strings.HasPrefix(path, pattern) || path == strings.TrimSuffix(pattern, "/")
+// matchPatterns matches an absolute path against gitignore-style patterns
+// from the config, see glob.List. A path inside a workspace root is matched
+// relative to it, so .env, ./.env and /project/.env are the same file, and
+// any other path is matched as an absolute path. Without roots, the process
+// directory is the root. In patterns, ~ is expanded, ../ starts at the first
+// root, and absolute patterns also match where their links lead. A path that
+// does not exist counts as a directory for patterns ending in /.
+func matchPatterns(absPath string, roots []string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	if len(roots) == 0 {
+		if cwd, err := os.Getwd(); err == nil {
+			roots = []string{cwd}
+		}
 	}
 
-	if strings.HasPrefix(path, pattern+"/") || strings.HasPrefix(path, pattern+string(filepath.Separator)) {
-		return true
+	var expanded []string
+	for _, pattern := range patterns {
+		negate := ""
+		if strings.HasPrefix(pattern, "!") {
+			negate, pattern = "!", pattern[1:]
+		}
+		pattern = expandTilde(pattern)
+		if strings.HasPrefix(pattern, "../") && len(roots) > 0 {
+			dir := strings.HasSuffix(pattern, "/")
+			pattern = filepath.Join(roots[0], pattern)
+			if dir {
+				pattern += "/"
+			}
+		}
+		expanded = append(expanded, negate+pattern)
+		if filepath.IsAbs(pattern) {
+			if resolved := resolvePattern(pattern); resolved != pattern {
+				expanded = append(expanded, negate+resolved)
+			}
+		}
 	}
 
-	return false
+	isDir := true
+	if info, err := os.Stat(absPath); err == nil {
+		isDir = info.IsDir()
+	}
+	return glob.NewList(expanded).Match(toRelativePath(absPath, "", roots), isDir)
 }
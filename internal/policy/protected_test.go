@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
 )
 
 func TestIsAlwaysProtected(t *testing.T) {
@@ -146,6 +148,10 @@ func TestResolvePath(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	tests := []struct {
 		name string
@@ -172,6 +178,21 @@ func TestResolvePath(t *testing.T) {
 			path: "./src/../src/main.go",
 			want: filepath.Join(cwd, "src/main.go"),
 		},
+		{
+			name: "home",
+			path: "~",
+			want: home,
+		},
+		{
+			name: "home subpath",
+			path: "~/.ssh/id_rsa",
+			want: filepath.Join(home, ".ssh/id_rsa"),
+		},
+		{
+			name: "unknown user",
+			path: "~nosuchuser1234/x",
+			want: filepath.Join(cwd, "~nosuchuser1234/x"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -184,7 +205,72 @@ func TestResolvePath(t *testing.T) {
 	}
 }
 
-func TestMatchPath(t *testing.T) {
+func TestResolveSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	real := t.TempDir()
+	if err := os.Symlink(real, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("link/missing/file", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("loop", filepath.Join(dir, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"no links", filepath.Join(dir, "a/b"), filepath.Join(dir, "a/b")},
+		{"link", filepath.Join(dir, "link"), real},
+		{"missing under link", filepath.Join(dir, "link/new/file.txt"), filepath.Join(real, "new/file.txt")},
+		{"dangling link", filepath.Join(dir, "dangling"), filepath.Join(real, "missing/file")},
+		{"link loop", filepath.Join(dir, "loop/x"), filepath.Join(dir, "loop/x")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveSymlinks(tt.path); got != tt.want {
+				t.Errorf("resolveSymlinks(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAlwaysProtectedSymlink(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dotfiles := filepath.Join(home, "dotfiles", "ssh")
+	if err := os.MkdirAll(dotfiles, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dotfiles, filepath.Join(home, ".ssh")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Symlink(filepath.Join(home, ".ssh"), filepath.Join(dir, "keys")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, ".watchman.yml"), filepath.Join(dir, "notes")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{
+		filepath.Join(home, ".ssh/id_rsa"),
+		filepath.Join(dotfiles, "id_rsa"),
+		filepath.Join(dir, "keys/id_rsa"),
+		filepath.Join(dir, "notes"),
+	} {
+		if !IsAlwaysProtected(p) {
+			t.Errorf("IsAlwaysProtected(%q) = false, want true", p)
+		}
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		t.Fatal(err)
@@ -248,10 +334,58 @@ func TestMatchPath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := matchPath(tt.path, tt.pattern)
+			got := matchPatterns(tt.path, nil, []string{tt.pattern})
 			if got != tt.want {
-				t.Errorf("matchPath(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+				t.Errorf("matchPatterns(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectedMatch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected := NewProtected(&config.ProtectedConfig{
+		Paths:     []string{"~/.kube/config", "~/.docker/", "/opt/corp/[creds]", "deploy/key"},
+		Filenames: []string{".npmrc"},
+		Globs:     []string{"*.kdbx", "!public.kdbx"},
+	})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(home, ".kube", "config"), true},
+		{"~/.kube/config", true},
+		{filepath.Join(home, ".kube", "cache"), false},
+		{filepath.Join(home, ".docker", "config.json"), true},
+		{"/opt/corp/[creds]", true},
+		{"/opt/corp/c", false},
+		{"deploy/key", true},
+		{filepath.Join(cwd, "deploy", "key"), true},
+		{"other/deploy/key", false},
+		{"/srv/app/.npmrc", true},
+		{"vault.kdbx", true},
+		{"backup/vault.kdbx", true},
+		{"public.kdbx", false},
+		{"main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := protected.Match(tt.path, ""); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
 			}
 		})
 	}
+
+	var none *Protected
+	if none.Match("main.go", "") {
+		t.Error("nil Protected should match nothing")
+	}
 }
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+// ResolveRoots turns the workspace.root setting into absolute directories.
+// cwd is the working directory of the tool call, and an empty setting means
+// cwd. git is the top level of the repository containing cwd: a linked
+// worktree is its own top level, and inside a submodule it is the top level
+// of the outermost superproject. Outside a repository git falls back to cwd.
+// Other entries are paths; ~ is expanded and relative paths start at cwd.
+func ResolveRoots(spec []string, cwd string) []string {
+	if cwd == "" {
+		if wd, err := os.Getwd(); err == nil {
+			cwd = wd
+		}
+	}
+	if len(spec) == 0 {
+		return []string{filepath.Clean(cwd)}
+	}
+
+	var roots []string
+	for _, s := range spec {
+		var root string
+		switch s {
+		case config.RootCwd:
+			root = cwd
+		case config.RootGit:
+			root = gitRoot(cwd)
+		default:
+			root = expandTilde(s)
+			if !filepath.IsAbs(root) {
+				root = filepath.Join(cwd, root)
+			}
+		}
+		roots = appendRoot(roots, filepath.Clean(root))
+	}
+	return roots
+}
+
+// gitRoot returns the top level of the working tree containing dir,
+// climbing out of submodules into their superprojects.
+func gitRoot(dir string) string {
+	root := dir
+	for {
+		out, err := exec.Command("git", "-C", root, "rev-parse", "--show-superproject-working-tree", "--show-toplevel").Output()
+		if err != nil {
+			return root
+		}
+		// The superproject, when there is one, comes before the top level.
+		lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+		if lines[0] == "" {
+			return root
+		}
+		if len(lines) < 2 {
+			return lines[0]
+		}
+		if lines[0] == root {
+			return root
+		}
+		root = lines[0]
+	}
+}
+
+func appendRoot(roots []string, root string) []string {
+	for _, r := range roots {
+		if r == root {
+			return roots
+		}
+	}
+	return append(roots, root)
+}
+
+// rootOf returns the root that contains the absolute path p, if any.
+// Links in both are resolved before comparing.
+func rootOf(p string, roots []string) (string, bool) {
+	real := resolveSymlinks(p)
+	for _, root := range roots {
+		realRoot := resolveSymlinks(root)
+		if real == realRoot || strings.HasPrefix(real, realRoot+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return "", false
+}
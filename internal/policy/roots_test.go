@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveRoots(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cwd := t.TempDir()
+
+	tests := []struct {
+		name string
+		spec []string
+		want []string
+	}{
+		{"default", nil, []string{cwd}},
+		{"cwd", []string{"cwd"}, []string{cwd}},
+		{"git outside a repository", []string{"git"}, []string{cwd}},
+		{"relative path", []string{"../shared"}, []string{filepath.Join(filepath.Dir(cwd), "shared")}},
+		{"tilde", []string{"~/work"}, []string{filepath.Join(home, "work")}},
+		{"list without duplicates", []string{"cwd", "/srv/a", "/srv/a/"}, []string{cwd, "/srv/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveRoots(tt.spec, cwd); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveRoots(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRootsGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := resolveSymlinks(t.TempDir())
+	worktree := filepath.Join(resolveSymlinks(t.TempDir()), "wt")
+	sub := filepath.Join(repo, "pkg", "a")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(repo, "init", "-q")
+	git(repo, "commit", "-q", "--allow-empty", "-m", "init")
+	git(repo, "worktree", "add", "-q", worktree)
+	if err := os.MkdirAll(filepath.Join(worktree, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ResolveRoots([]string{"git"}, sub); !reflect.DeepEqual(got, []string{repo}) {
+		t.Errorf("ResolveRoots(git) from subdirectory = %q, want %q", got, repo)
+	}
+	if got := ResolveRoots([]string{"git"}, filepath.Join(worktree, "pkg")); !reflect.DeepEqual(got, []string{worktree}) {
+		t.Errorf("ResolveRoots(git) from worktree = %q, want %q", got, worktree)
+	}
+}
+
+func TestViolatesWorkspaceBoundaryRoots(t *testing.T) {
+	root := t.TempDir()
+	shared := t.TempDir()
+	cwd := filepath.Join(root, "pkg", "a")
+
+	rule := &ConfineToWorkspace{Roots: []string{root, shared}}
+	tests := []struct {
+		path     string
+		violates bool
+	}{
+		{"../b/main.go", false},
+		{"../../go.mod", false},
+		{filepath.Join(shared, "lib.go"), false},
+		{"../../../outside", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rule.violatesBoundary(tt.path, cwd, AccessRead); got != tt.violates {
+				t.Errorf("violatesBoundary(%q) = %v, want %v", tt.path, got, tt.violates)
+			}
+		})
+	}
+}
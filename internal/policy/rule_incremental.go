@@ -11,19 +11,20 @@ import (
 type IncrementalRule struct {
 	MaxFiles  int
 	WarnRatio float64
+	Roots     []string   // resolved workspace roots, see ResolveRoots; empty means Dir
+	Dir       string     // working directory of the request; empty means the process directory
 	countFunc func() int // injectable for testing
 }
 
 // NewIncrementalRule creates a new incremental change rule.
 func NewIncrementalRule(cfg *config.IncrementalConfig) *IncrementalRule {
-	if cfg == nil {
-		return &IncrementalRule{countFunc: countGitModifiedFiles}
-	}
-	return &IncrementalRule{
-		MaxFiles:  cfg.MaxFiles,
-		WarnRatio: cfg.WarnRatio,
-		countFunc: countGitModifiedFiles,
+	r := &IncrementalRule{}
+	if cfg != nil {
+		r.MaxFiles = cfg.MaxFiles
+		r.WarnRatio = cfg.WarnRatio
 	}
+	r.countFunc = func() int { return countGitModifiedFiles(r.Dir, r.Roots...) }
+	return r
 }
 
 // Evaluate checks if the current number of modified files exceeds limits.
@@ -72,12 +73,36 @@ func (r *IncrementalRule) countModifiedFiles() int {
 	if r.countFunc != nil {
 		return r.countFunc()
 	}
-	return countGitModifiedFiles()
+	return countGitModifiedFiles(r.Dir, r.Roots...)
+}
+
+// countGitModifiedFiles runs git status and counts modified files under
+// each root, or in the repository around dir when no roots are given.
+func countGitModifiedFiles(dir string, roots ...string) int {
+	if len(roots) == 0 {
+		if dir == "" {
+			return countGitStatus(exec.Command("git", "status", "--porcelain"))
+		}
+		return countGitStatus(exec.Command("git", "-C", dir, "status", "--porcelain"))
+	}
+
+	// Roots that are not in a repository have nothing to count.
+	total := -1
+	for _, root := range roots {
+		count := countGitStatus(exec.Command("git", "-C", root, "status", "--porcelain", "--", "."))
+		if count < 0 {
+			continue
+		}
+		if total < 0 {
+			total = 0
+		}
+		total += count
+	}
+	return total
 }
 
-// countGitModifiedFiles runs git status and counts modified files.
-func countGitModifiedFiles() int {
-	cmd := exec.Command("git", "status", "--porcelain")
+// countGitStatus counts the modified files listed by a git status command.
+func countGitStatus(cmd *exec.Cmd) int {
 	output, err := cmd.Output()
 	if err != nil {
 		return -1
@@ -1,6 +1,9 @@
 package policy
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/adrianpk/watchman/internal/config"
@@ -207,7 +210,7 @@ func TestIncrementalRuleCountModifiedFiles(t *testing.T) {
 
 func TestCountGitModifiedFiles(t *testing.T) {
 	// This test actually runs git status, so it's more of an integration test.
-	count := countGitModifiedFiles()
+	count := countGitModifiedFiles("")
 	// Just verify it doesn't return an unexpected error
 	if count < -1 {
 		t.Errorf("countGitModifiedFiles() = %d, want >= -1", count)
@@ -215,10 +218,40 @@ func TestCountGitModifiedFiles(t *testing.T) {
 	t.Logf("Current modified files: %d", count)
 }
 
+func TestCountGitModifiedFilesInDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for _, name := range []string{"a.go", "b.go"} {
+		os.WriteFile(filepath.Join(repo, name), []byte("package a"), 0644)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	os.WriteFile(filepath.Join(repo, "a.go"), []byte("package b"), 0644)
+	os.WriteFile(filepath.Join(repo, "b.go"), []byte("package b"), 0644)
+
+	if got := countGitModifiedFiles(repo); got != 2 {
+		t.Errorf("countGitModifiedFiles(%q) = %d, want 2", repo, got)
+	}
+	if got := countGitModifiedFiles(t.TempDir()); got != -1 {
+		t.Errorf("countGitModifiedFiles() outside a repository = %d, want -1", got)
+	}
+}
+
 func TestParseGitStatusOutput(t *testing.T) {
 	// Test the parsing logic by testing countGitModifiedFiles indirectly
 	// Since we can't easily mock exec.Command, we test what we can
-	count := countGitModifiedFiles()
+	count := countGitModifiedFiles("")
 	if count < 0 {
 		t.Skip("git status failed, skipping")
 	}
@@ -13,6 +13,14 @@ import (
 // InvariantsRule enforces declarative structural checks.
 type InvariantsRule struct {
 	cfg *config.InvariantsConfig
+
+	// Dir limits the rule to files under it, see config.DirectoryConfig.
+	// Patterns then match paths relative to Dir. Empty means every file,
+	// matched as given.
+	Dir string
+
+	// Audit puts every check in audit mode, whatever its own mode.
+	Audit bool
 }
 
 // NewInvariantsRule creates an invariants rule from config.
@@ -29,56 +37,89 @@ func (r *InvariantsRule) Evaluate(toolName, filePath, content string) Decision {
 	if !writeTools[toolName] {
 		return Decision{Allowed: true}
 	}
+	return r.CheckFile(filePath, content)
+}
+
+// CheckFile checks the complete new content of a file against every invariant.
+// A failing check in audit mode adds its reason to the decision's Audit and
+// the checks go on; the first failing check in enforce mode decides.
+func (r *InvariantsRule) CheckFile(filePath, content string) Decision {
+	if r.Dir != "" {
+		rel, ok := relativeTo(r.Dir, filePath)
+		if !ok {
+			return Decision{Allowed: true}
+		}
+		filePath = rel
+	}
+
+	var audit []string
 
 	// Check coexistence rules
-	if decision := r.checkCoexistence(filePath); !decision.Allowed {
-		return decision
+	if decision := r.checkCoexistence(filePath, &audit); !decision.Allowed {
+		return withAudit(decision, audit)
 	}
 
 	// Check content rules
-	if decision := r.checkContent(filePath, content); !decision.Allowed {
-		return decision
+	if decision := r.checkContent(filePath, content, &audit); !decision.Allowed {
+		return withAudit(decision, audit)
 	}
 
 	// Check import rules
-	if decision := r.checkImports(filePath, content); !decision.Allowed {
-		return decision
+	if decision := r.checkImports(filePath, content, &audit); !decision.Allowed {
+		return withAudit(decision, audit)
 	}
 
 	// Check naming rules
-	if decision := r.checkNaming(filePath); !decision.Allowed {
-		return decision
+	if decision := r.checkNaming(filePath, &audit); !decision.Allowed {
+		return withAudit(decision, audit)
 	}
 
 	// Check required files rules
-	if decision := r.checkRequired(filePath); !decision.Allowed {
-		return decision
+	if decision := r.checkRequired(filePath, &audit); !decision.Allowed {
+		return withAudit(decision, audit)
 	}
 
-	return Decision{Allowed: true}
+	return Decision{Allowed: true, Audit: audit}
+}
+
+// violation returns the decision for a failing check. A check in audit mode
+// only adds its reason to audit, and the call stays allowed.
+func (r *InvariantsRule) violation(mode, reason string, audit *[]string) Decision {
+	if r.Audit || mode == config.ModeAudit {
+		*audit = append(*audit, reason)
+		return Decision{Allowed: true}
+	}
+	return Decision{Allowed: false, Reason: reason}
+}
+
+func withAudit(decision Decision, audit []string) Decision {
+	decision.Audit = audit
+	return decision
 }
 
 // checkCoexistence ensures related files exist together.
-func (r *InvariantsRule) checkCoexistence(filePath string) Decision {
+func (r *InvariantsRule) checkCoexistence(filePath string, audit *[]string) Decision {
 	for _, check := range r.cfg.Coexistence {
 		if !glob.Match(filePath, check.If) {
 			continue
 		}
 
 		requiredPath := expandPlaceholders(check.Require, filePath)
-		if _, err := os.Stat(requiredPath); os.IsNotExist(err) {
+		if _, err := os.Stat(r.onDisk(requiredPath)); os.IsNotExist(err) {
 			msg := check.Message
 			if msg == "" {
 				msg = "coexistence check failed: " + check.Name + " requires " + requiredPath
 			}
-			return Decision{Allowed: false, Reason: msg}
+			if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+				return decision
+			}
 		}
 	}
 	return Decision{Allowed: true}
 }
 
 // checkContent validates file content against patterns.
-func (r *InvariantsRule) checkContent(filePath, content string) Decision {
+func (r *InvariantsRule) checkContent(filePath, content string, audit *[]string) Decision {
 	for _, check := range r.cfg.Content {
 		if !matchesPathPatterns(filePath, check.Paths) {
 			continue
@@ -95,7 +136,9 @@ func (r *InvariantsRule) checkContent(filePath, content string) Decision {
 				if msg == "" {
 					msg = "content check failed: " + check.Name + " forbids pattern: " + check.Forbid
 				}
-				return Decision{Allowed: false, Reason: msg}
+				if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+					return decision
+				}
 			}
 		}
 
@@ -110,7 +153,9 @@ func (r *InvariantsRule) checkContent(filePath, content string) Decision {
 				if msg == "" {
 					msg = "content check failed: " + check.Name + " requires pattern: " + check.Require
 				}
-				return Decision{Allowed: false, Reason: msg}
+				if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+					return decision
+				}
 			}
 		}
 	}
@@ -118,7 +163,7 @@ func (r *InvariantsRule) checkContent(filePath, content string) Decision {
 }
 
 // checkImports validates import statements (regex-based).
-func (r *InvariantsRule) checkImports(filePath, content string) Decision {
+func (r *InvariantsRule) checkImports(filePath, content string, audit *[]string) Decision {
 	for _, check := range r.cfg.Imports {
 		if !matchesPathPatterns(filePath, check.Paths) {
 			continue
@@ -133,14 +178,16 @@ func (r *InvariantsRule) checkImports(filePath, content string) Decision {
 			if msg == "" {
 				msg = "import check failed: " + check.Name + " forbids import matching: " + check.Forbid
 			}
-			return Decision{Allowed: false, Reason: msg}
+			if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+				return decision
+			}
 		}
 	}
 	return Decision{Allowed: true}
 }
 
 // checkNaming validates file naming conventions.
-func (r *InvariantsRule) checkNaming(filePath string) Decision {
+func (r *InvariantsRule) checkNaming(filePath string, audit *[]string) Decision {
 	for _, check := range r.cfg.Naming {
 		if !matchesPathPatterns(filePath, check.Paths) {
 			continue
@@ -156,14 +203,16 @@ func (r *InvariantsRule) checkNaming(filePath string) Decision {
 			if msg == "" {
 				msg = "naming check failed: " + check.Name + " requires pattern: " + check.Pattern
 			}
-			return Decision{Allowed: false, Reason: msg}
+			if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+				return decision
+			}
 		}
 	}
 	return Decision{Allowed: true}
 }
 
 // checkRequired ensures certain files exist in directories.
-func (r *InvariantsRule) checkRequired(filePath string) Decision {
+func (r *InvariantsRule) checkRequired(filePath string, audit *[]string) Decision {
 	dir := filepath.Dir(filePath)
 
 	for _, check := range r.cfg.Required {
@@ -173,7 +222,7 @@ func (r *InvariantsRule) checkRequired(filePath string) Decision {
 
 		// Check "when" condition if specified
 		if check.When != "" {
-			entries, err := os.ReadDir(dir)
+			entries, err := os.ReadDir(r.onDisk(dir))
 			if err != nil {
 				continue
 			}
@@ -190,17 +239,41 @@ func (r *InvariantsRule) checkRequired(filePath string) Decision {
 		}
 
 		requiredFile := filepath.Join(dir, check.Require)
-		if _, err := os.Stat(requiredFile); os.IsNotExist(err) {
+		if _, err := os.Stat(r.onDisk(requiredFile)); os.IsNotExist(err) {
 			msg := check.Message
 			if msg == "" {
 				msg = "required check failed: " + check.Name + " requires " + check.Require + " in " + dir
 			}
-			return Decision{Allowed: false, Reason: msg}
+			if decision := r.violation(check.Mode, msg, audit); !decision.Allowed {
+				return decision
+			}
 		}
 	}
 	return Decision{Allowed: true}
 }
 
+// onDisk returns where a path the rule matched lies on disk.
+func (r *InvariantsRule) onDisk(p string) string {
+	if r.Dir == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(r.Dir, p)
+}
+
+// relativeTo returns p relative to dir, if p lies under dir. Relative
+// paths are taken from the working directory.
+func relativeTo(dir, p string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
 // expandPlaceholders replaces ${name}, ${base}, ${ext} in a pattern.
 func expandPlaceholders(pattern, filePath string) string {
 	dir := filepath.Dir(filePath)
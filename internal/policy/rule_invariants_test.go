@@ -3,6 +3,7 @@ package policy
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/adrianpk/watchman/internal/config"
@@ -228,6 +229,85 @@ func TestInvariantsCoexistence(t *testing.T) {
 	}
 }
 
+func TestInvariantsDir(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "internal")
+	if err := os.MkdirAll(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "user.go"), []byte("package internal"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.InvariantsConfig{
+		Coexistence: []config.CoexistenceCheck{
+			{Name: "test-requires-impl", If: "internal/*_test.go", Require: "${base}.go"},
+		},
+	}
+	rule := NewInvariantsRule(cfg)
+	rule.Dir = dir
+
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{"impl exists", filepath.Join(pkg, "user_test.go"), true},
+		{"impl missing", filepath.Join(pkg, "order_test.go"), false},
+		{"pattern relative to dir", filepath.Join(dir, "other", "internal", "order_test.go"), true},
+		{"outside dir", filepath.Join(t.TempDir(), "internal", "order_test.go"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := rule.CheckFile(tt.path, "package internal")
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.allowed, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestInvariantsAudit(t *testing.T) {
+	cfg := &config.InvariantsConfig{
+		Content: []config.ContentCheck{
+			{Name: "no-todo", Paths: []string{"*.go"}, Forbid: "TODO", Mode: config.ModeAudit},
+		},
+		Naming: []config.NamingCheck{
+			{Name: "snake-case", Paths: []string{"*.go"}, Pattern: "^[a-z_]+\\.go$"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		audit   bool
+		allowed bool
+		audited []string
+	}{
+		{"audited check goes on", "MyFile.go", false, false, []string{"content check failed: no-todo forbids pattern: TODO"}},
+		{"audited check only", "my_file.go", false, true, []string{"content check failed: no-todo forbids pattern: TODO"}},
+		{"rule in audit mode", "MyFile.go", true, true, []string{
+			"content check failed: no-todo forbids pattern: TODO",
+			"naming check failed: snake-case requires pattern: ^[a-z_]+\\.go$",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewInvariantsRule(cfg)
+			rule.Audit = tt.audit
+			decision := rule.CheckFile(tt.path, "// TODO")
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.allowed, decision.Reason)
+			}
+			if strings.Join(decision.Audit, "\n") != strings.Join(tt.audited, "\n") {
+				t.Errorf("Audit = %q, want %q", decision.Audit, tt.audited)
+			}
+		})
+	}
+}
+
 func TestInvariantsRequired(t *testing.T) {
 	// Create temp directory structure
 	tmpDir := t.TempDir()
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/adrianpk/watchman/internal/config"
-	"github.com/adrianpk/watchman/internal/glob"
 	"github.com/adrianpk/watchman/internal/parser"
 )
 
@@ -21,6 +20,7 @@ var writeTools = map[string]bool{
 type ScopeToFiles struct {
 	Allow []string
 	Block []string
+	Roots []string // resolved workspace roots, see ResolveRoots; empty means cwd
 }
 
 // NewScopeToFiles creates a scope rule from config.
@@ -40,25 +40,32 @@ func (r *ScopeToFiles) Evaluate(toolName string, cmd parser.Command, cwd string)
 		return Decision{Allowed: true}
 	}
 
-	paths := collectPathCandidates(cmd)
-	for _, p := range paths {
-		if r.isBlocked(p) {
-			return Decision{
-				Allowed: false,
-				Reason:  "scope.block: " + p + " matches blocked pattern",
-			}
-		}
-		if !r.isInScope(p, cwd) {
-			return Decision{
-				Allowed: false,
-				Reason:  "scope.allow: " + p + " does not match any allowed pattern " + r.summarizeAllow(),
-			}
+	for _, p := range collectPathCandidates(cmd) {
+		if decision := r.CheckPath(p, cwd); !decision.Allowed {
+			return decision
 		}
 	}
 
 	return Decision{Allowed: true}
 }
 
+// CheckPath checks a single path the agent is about to modify.
+func (r *ScopeToFiles) CheckPath(p string, cwd string) Decision {
+	if r.isBlocked(p, cwd) {
+		return Decision{
+			Allowed: false,
+			Reason:  "scope.block: " + p + " matches blocked pattern",
+		}
+	}
+	if !r.isInScope(p, cwd) {
+		return Decision{
+			Allowed: false,
+			Reason:  "scope.allow: " + p + " does not match any allowed pattern " + r.summarizeAllow(),
+		}
+	}
+	return Decision{Allowed: true}
+}
+
 // summarizeAllow returns a short summary of allowed patterns for error messages.
 func (r *ScopeToFiles) summarizeAllow() string {
 	if len(r.Allow) == 0 {
@@ -70,9 +77,9 @@ func (r *ScopeToFiles) summarizeAllow() string {
 	return "(" + strings.Join(r.Allow[:5], ", ") + ", ...)"
 }
 
-// isBlocked checks if a path matches any block pattern.
-func (r *ScopeToFiles) isBlocked(p string) bool {
-	return glob.MatchAny(p, r.Block)
+// isBlocked checks if a path matches the block patterns.
+func (r *ScopeToFiles) isBlocked(p string, cwd string) bool {
+	return matchPatterns(absolutePath(p, cwd), r.roots(cwd), r.Block)
 }
 
 // isInScope checks if a path is within the allowed scope.
@@ -82,17 +89,25 @@ func (r *ScopeToFiles) isInScope(p string, cwd string) bool {
 		return true
 	}
 
-	// Normalize path to relative for glob matching
-	// This allows patterns like "src/**/*.go" to match absolute paths
-	relPath := toRelativePath(p, cwd)
+	// Patterns match the path relative to the workspace root, so "src/**/*.go"
+	// matches src/main.go however the agent writes it.
+	return matchPatterns(absolutePath(p, cwd), r.roots(cwd), r.Allow)
+}
 
-	// Try both the original path and the relative version
-	return glob.MatchAny(p, r.Allow) || glob.MatchAny(relPath, r.Allow)
+// roots returns the workspace roots, cwd unless set.
+func (r *ScopeToFiles) roots(cwd string) []string {
+	if len(r.Roots) > 0 || cwd == "" {
+		return r.Roots
+	}
+	return []string{filepath.Clean(cwd)}
 }
 
-// toRelativePath converts an absolute path to relative (if within cwd).
-func toRelativePath(p string, cwd string) string {
-	if !filepath.IsAbs(p) {
+// toRelativePath converts a path to relative form for glob matching: paths
+// within a workspace root are made relative to it. Relative paths start at
+// cwd and are returned unchanged when no roots are given, since cwd is then
+// the root.
+func toRelativePath(p string, cwd string, roots []string) string {
+	if !filepath.IsAbs(p) && len(roots) == 0 {
 		return p
 	}
 
@@ -104,11 +119,26 @@ func toRelativePath(p string, cwd string) string {
 			return p
 		}
 	}
+	if len(roots) == 0 {
+		roots = []string{cwd}
+	}
 
-	// Check if path is within cwd
-	if strings.HasPrefix(p, cwd+string(filepath.Separator)) {
-		rel, err := filepath.Rel(cwd, p)
-		if err == nil {
+	abs := p
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(cwd, abs)
+	}
+	abs = filepath.Clean(abs)
+
+	// Check if path is within a root, as written or through links
+	for _, root := range roots {
+		if strings.HasPrefix(abs, root+string(filepath.Separator)) {
+			if rel, err := filepath.Rel(root, abs); err == nil {
+				return rel
+			}
+		}
+	}
+	if root, ok := rootOf(abs, roots); ok {
+		if rel, err := filepath.Rel(resolveSymlinks(root), resolveSymlinks(abs)); err == nil {
 			return rel
 		}
 	}
@@ -149,6 +149,8 @@ func TestScopeIsBlocked(t *testing.T) {
 		{"src/types_generated.go", true},
 		{"internal/api_generated.go", true},
 		{".env", true},
+		{"./.env", true},
+		{"config/.env", true},
 		{"src/main.go", false},
 		{"internal/api.go", false},
 		{"README.md", false},
@@ -156,7 +158,7 @@ func TestScopeIsBlocked(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.path, func(t *testing.T) {
-			got := rule.isBlocked(tt.path)
+			got := rule.isBlocked(tt.path, "")
 			if got != tt.blocked {
 				t.Errorf("isBlocked(%q) = %v, want %v", tt.path, got, tt.blocked)
 			}
@@ -260,7 +262,7 @@ func TestToRelativePath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := toRelativePath(tt.path, "")
+			got := toRelativePath(tt.path, "", nil)
 			want := tt.want
 			if want == "" {
 				want = tt.path
@@ -272,4 +274,33 @@ func TestToRelativePath(t *testing.T) {
 	}
 }
 
+func TestToRelativePathRoots(t *testing.T) {
+	root := t.TempDir()
+	cwd := filepath.Join(root, "pkg", "a")
+	roots := []string{root}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", "pkg/a/main.go"},
+		{"../b/b.go", "pkg/b/b.go"},
+		{filepath.Join(root, "go.mod"), "go.mod"},
+		{"/etc/passwd", "/etc/passwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := toRelativePath(tt.path, cwd, roots); got != tt.want {
+				t.Errorf("toRelativePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	rule := &ScopeToFiles{Allow: []string{"pkg/a/**"}, Roots: roots}
+	if !rule.isInScope("main.go", cwd) || rule.isInScope("../b/b.go", cwd) {
+		t.Error("scope patterns should be relative to the workspace root")
+	}
+}
+
 // Note: matchGlob and matchDoublestar tests are now in internal/glob/glob_test.go
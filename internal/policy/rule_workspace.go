@@ -11,72 +11,151 @@ import (
 
 // ConfineToWorkspace blocks commands that attempt to access paths outside the project.
 type ConfineToWorkspace struct {
-	Allow []string
-	Block []string
+	Allow      []string
+	ReadAllow  []string
+	WriteAllow []string
+	Block      []string
+	Roots      []string   // resolved workspace roots, see ResolveRoots; empty means cwd
+	Protected  *Protected // protected paths from config, on top of the hardcoded ones
 }
 
+// Access is what a tool call does with a path.
+type Access int
+
+const (
+	AccessRead Access = iota
+	AccessWrite
+)
+
 // NewConfineToWorkspace creates a workspace rule from config.
 func NewConfineToWorkspace(cfg *config.WorkspaceConfig) *ConfineToWorkspace {
 	if cfg == nil {
 		return &ConfineToWorkspace{}
 	}
+	readAllow := cfg.ReadAllow
+	if cfg.ToolchainCaches {
+		readAllow = append(append([]string{}, readAllow...), ToolchainCaches()...)
+	}
 	return &ConfineToWorkspace{
-		Allow: cfg.Allow,
-		Block: cfg.Block,
+		Allow:      cfg.Allow,
+		ReadAllow:  readAllow,
+		WriteAllow: cfg.WriteAllow,
+		Block:      cfg.Block,
 	}
 }
 
 // Evaluate checks if the command attempts to access paths outside the workspace.
+// Paths the command writes need write access; the rest need read access.
 func (r *ConfineToWorkspace) Evaluate(cmd parser.Command, cwd string) Decision {
-	candidates := collectPathCandidates(cmd)
+	writes := make(map[string]bool)
+	for _, w := range parser.CommandWrites(cmd) {
+		writes[w.Path] = true
+	}
+	for _, p := range collectPathCandidates(cmd) {
+		access := AccessRead
+		if writes[p] {
+			access = AccessWrite
+		}
+		if decision := r.CheckPath(p, cwd, access); !decision.Allowed {
+			return decision
+		}
+	}
+	return Decision{Allowed: true}
+}
 
-	for _, p := range candidates {
-		if IsAlwaysProtected(p) {
-			return Decision{
-				Allowed: false,
-				Reason:  "protected path: " + p + " (hardcoded security boundary)",
-			}
+// CheckPath checks a single path against the protected paths, the block
+// list and the workspace boundary.
+func (r *ConfineToWorkspace) CheckPath(p, cwd string, access Access) Decision {
+	if by := r.protectedBy(p, cwd); by != "" {
+		return Decision{
+			Allowed: false,
+			Reason:  "protected path: " + p + " (" + by + ")",
 		}
-		if r.isBlocked(p) {
-			return Decision{
-				Allowed: false,
-				Reason:  "workspace.block: " + p + " matches blocked pattern",
-			}
+	}
+	if r.isBlocked(p, cwd) {
+		return Decision{
+			Allowed: false,
+			Reason:  "workspace.block: " + p + " matches blocked pattern",
 		}
-		if r.violatesBoundary(p, cwd) {
+	}
+	if r.violatesBoundary(p, cwd, access) {
+		if access == AccessWrite && !r.violatesBoundary(p, cwd, AccessRead) {
 			return Decision{
 				Allowed: false,
-				Reason:  "workspace boundary: " + p + " is outside project directory",
+				Reason:  "workspace.read_allow: " + p + " is outside project directory and read-only",
 			}
 		}
+		return Decision{
+			Allowed: false,
+			Reason:  "workspace boundary: " + p + " is outside project directory",
+		}
 	}
-
 	return Decision{Allowed: true}
 }
 
-// isBlocked checks if a path matches any block pattern.
-func (r *ConfineToWorkspace) isBlocked(p string) bool {
-	for _, pattern := range r.Block {
-		if matchPath(p, pattern) {
-			return true
+// CheckLink checks a link about to be created. Reads and writes through a
+// link reach its target, so the target must pass the same checks as a path.
+// Creating the link only needs read access to the target: a write through
+// it later is checked against where it leads. A relative target is taken
+// relative to cwd.
+func (r *ConfineToWorkspace) CheckLink(link, target, cwd string) Decision {
+	if by := r.protectedBy(target, cwd); by != "" {
+		return Decision{
+			Allowed: false,
+			Reason:  "protected path: link " + link + " points to " + target + " (" + by + ")",
+		}
+	}
+	if r.violatesBoundary(target, cwd, AccessRead) {
+		return Decision{
+			Allowed: false,
+			Reason:  "workspace boundary: link " + link + " points to " + target + " outside project directory",
 		}
 	}
-	return false
+	return Decision{Allowed: true}
 }
 
-// isAllowed checks if a path matches any allow pattern.
-func (r *ConfineToWorkspace) isAllowed(p string) bool {
-	for _, pattern := range r.Allow {
-		if matchPath(p, pattern) {
-			return true
-		}
+// protectedBy tells what protects a path, or returns empty if nothing does.
+func (r *ConfineToWorkspace) protectedBy(p, cwd string) string {
+	if IsAlwaysProtected(absolutePath(p, cwd)) {
+		return "hardcoded security boundary"
+	}
+	if r.Protected.Match(p, cwd) {
+		return "protected by config"
+	}
+	return ""
+}
+
+// isBlocked checks if a path, or the file it leads to, matches the block
+// patterns.
+func (r *ConfineToWorkspace) isBlocked(p, cwd string) bool {
+	absPath := absolutePath(p, cwd)
+	roots := r.roots(cwd)
+	return matchPatterns(absPath, roots, r.Block) || matchPatterns(resolveSymlinks(absPath), roots, r.Block)
+}
+
+// isAllowed checks if an absolute path matches the allow patterns for the
+// access: allow and write_allow grant reading and writing, read_allow only
+// reading.
+func (r *ConfineToWorkspace) isAllowed(absPath, cwd string, access Access) bool {
+	patterns := append(append([]string{}, r.Allow...), r.WriteAllow...)
+	if access == AccessRead {
+		patterns = append(patterns, r.ReadAllow...)
+	}
+	return matchPatterns(absPath, r.roots(cwd), patterns)
+}
+
+// roots returns the workspace roots, cwd unless set. An empty cwd leaves
+// the choice to matchPatterns.
+func (r *ConfineToWorkspace) roots(cwd string) []string {
+	if len(r.Roots) > 0 || cwd == "" {
+		return r.Roots
 	}
-	return false
+	return []string{filepath.Clean(cwd)}
 }
 
 // violatesBoundary checks if a path escapes the workspace,
-// considering allow list exceptions.
-func (r *ConfineToWorkspace) violatesBoundary(p string, cwd string) bool {
+// considering the allow list exceptions for the access.
+func (r *ConfineToWorkspace) violatesBoundary(p string, cwd string, access Access) bool {
 	if p == "" {
 		return false
 	}
@@ -91,26 +170,32 @@ func (r *ConfineToWorkspace) violatesBoundary(p string, cwd string) bool {
 	}
 
 	var absPath string
-	if filepath.IsAbs(p) {
-		absPath = filepath.Clean(p)
+	if expanded := expandTilde(p); filepath.IsAbs(expanded) {
+		absPath = filepath.Clean(expanded)
 	} else {
 		absPath = filepath.Clean(filepath.Join(cwd, p))
 	}
 
-	cwdClean := filepath.Clean(cwd)
-	isInside := absPath == cwdClean || strings.HasPrefix(absPath, cwdClean+string(filepath.Separator))
+	roots := r.Roots
+	if len(roots) == 0 {
+		roots = []string{filepath.Clean(cwd)}
+	}
 
-	if isInside {
+	// Links are followed, so a link inside the project that leads out of it
+	// is outside, and a path outside that leads into the project is inside.
+	if _, isInside := rootOf(absPath, roots); isInside {
 		return false
 	}
+	realPath := resolveSymlinks(absPath)
 
 	// Allow Claude Code operational directories (plans, todos, etc.)
 	// Note: sensitive files like .credentials.json are still blocked by IsAlwaysProtected
-	if isClaudeOperationalPath(absPath) {
+	if isClaudeOperationalPath(realPath) {
 		return false
 	}
 
-	if r.isAllowed(p) {
+	// Allow patterns match where the path leads, not the name of a link.
+	if r.isAllowed(realPath, cwd, access) {
 		return false
 	}
 
@@ -161,6 +246,12 @@ func collectPathCandidates(cmd parser.Command) []string {
 		out = append(out, v)
 	}
 
+	for _, r := range cmd.Redirects {
+		if r.IsFile() {
+			out = append(out, r.Target)
+		}
+	}
+
 	return out
 }
 
@@ -168,5 +259,5 @@ func collectPathCandidates(cmd parser.Command) []string {
 // This is the legacy function for backward compatibility.
 func ViolatesWorkspaceBoundary(p string) bool {
 	rule := &ConfineToWorkspace{}
-	return rule.violatesBoundary(p, "")
+	return rule.violatesBoundary(p, "", AccessRead)
 }
@@ -2,6 +2,8 @@ package policy
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/adrianpk/watchman/internal/config"
@@ -51,6 +53,18 @@ func TestConfineToWorkspaceEvaluate(t *testing.T) {
 			wantAllowed: true,
 		},
 
+		// Blocked cases - home directory
+		{
+			name:        "tilde path",
+			cmd:         "cat ~/notes.txt",
+			wantAllowed: false,
+		},
+		{
+			name:        "bare tilde",
+			cmd:         "ls ~",
+			wantAllowed: false,
+		},
+
 		// Blocked cases - absolute paths
 		{
 			name:        "rm absolute path",
@@ -106,6 +120,18 @@ func TestConfineToWorkspaceEvaluate(t *testing.T) {
 			cmd:         "FOO=bar GOBIN=/usr/local/bin go install ./...",
 			wantAllowed: false,
 		},
+
+		// Redirections
+		{
+			name:        "redirect to absolute path",
+			cmd:         "echo x > /tmp/out",
+			wantAllowed: false,
+		},
+		{
+			name:        "redirect descriptor",
+			cmd:         "go test ./... 2>&1",
+			wantAllowed: true,
+		},
 	}
 
 	rule := ConfineToWorkspace{}
@@ -222,7 +248,7 @@ func TestIsBlocked(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.path, func(t *testing.T) {
-			got := rule.isBlocked(tt.path)
+			got := rule.isBlocked(tt.path, "")
 			if got != tt.blocked {
 				t.Errorf("isBlocked(%q) = %v, want %v", tt.path, got, tt.blocked)
 			}
@@ -247,7 +273,7 @@ func TestIsAllowed(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.path, func(t *testing.T) {
-			got := rule.isAllowed(tt.path)
+			got := rule.isAllowed(tt.path, "", AccessRead)
 			if got != tt.allowed {
 				t.Errorf("isAllowed(%q) = %v, want %v", tt.path, got, tt.allowed)
 			}
@@ -255,6 +281,48 @@ func TestIsAllowed(t *testing.T) {
 	}
 }
 
+func TestIsBlockedPatterns(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "certs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "build"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := &ConfineToWorkspace{
+		Block: []string{".env", ".env.*", "!.env.example", "*.pem", "*.key", "build/", "config/local.yml"},
+		Roots: []string{root},
+	}
+
+	tests := []struct {
+		path    string
+		blocked bool
+	}{
+		{".env", true},
+		{"./.env", true},
+		{filepath.Join(root, ".env"), true},
+		{"api/.env", true},
+		{".env.local", true},
+		{".env.example", false},
+		{"certs/server.pem", true},
+		{"deploy/id.key", true},
+		{"keys.go", false},
+		{"build", false},
+		{"web/build/app.js", true},
+		{"config/local.yml", true},
+		{"api/config/local.yml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rule.isBlocked(tt.path, root); got != tt.blocked {
+				t.Errorf("isBlocked(%q) = %v, want %v", tt.path, got, tt.blocked)
+			}
+		})
+	}
+}
+
 func TestEvaluateWithBlockList(t *testing.T) {
 	rule := &ConfineToWorkspace{
 		Block: []string{".env"},
@@ -348,3 +416,117 @@ func TestWorkspaceAllowsClaudeOperationalPaths(t *testing.T) {
 		t.Error("should block Claude credentials path")
 	}
 }
+
+func TestViolatesWorkspaceBoundarySymlinks(t *testing.T) {
+	cwd := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(cwd, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"root":  "/",
+		"out":   outside,
+		"alias": filepath.Join(cwd, "sub"),
+	} {
+		if err := os.Symlink(target, filepath.Join(cwd, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(cwd, filepath.Join(outside, "back")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc", filepath.Join(outside, "esc")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		allow    []string
+		path     string
+		violates bool
+	}{
+		{"link to root", nil, "root/etc/passwd", true},
+		{"new file through link", nil, "out/new.txt", true},
+		{"link inside", nil, "alias/x.go", false},
+		{"outside path leading inside", nil, filepath.Join(outside, "back/x.go"), false},
+		{"allowed target", []string{outside + "/"}, "out/new.txt", false},
+		{"allowed name but not target", []string{outside + "/"}, filepath.Join(outside, "esc/passwd"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &ConfineToWorkspace{Allow: tt.allow}
+			if got := rule.violatesBoundary(tt.path, cwd, AccessRead); got != tt.violates {
+				t.Errorf("violatesBoundary(%q) = %v, want %v", tt.path, got, tt.violates)
+			}
+		})
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	cwd := t.TempDir()
+	rule := &ConfineToWorkspace{}
+
+	if d := rule.CheckLink("etc", "/etc", cwd); d.Allowed || !strings.Contains(d.Reason, "link etc points to /etc") {
+		t.Errorf("CheckLink(/etc) = %+v, want denied naming the link", d)
+	}
+	if d := rule.CheckLink("alias", filepath.Join(cwd, "src"), cwd); !d.Allowed {
+		t.Errorf("CheckLink(src) denied: %s", d.Reason)
+	}
+}
+
+func TestCheckPathAccess(t *testing.T) {
+	rule := &ConfineToWorkspace{
+		Allow:      []string{"/srv/both/"},
+		ReadAllow:  []string{"/opt/sdk/"},
+		WriteAllow: []string{"/var/out/"},
+	}
+	cwd := t.TempDir()
+
+	tests := []struct {
+		path    string
+		access  Access
+		allowed bool
+		reason  string
+	}{
+		{"/srv/both/x", AccessRead, true, ""},
+		{"/srv/both/x", AccessWrite, true, ""},
+		{"/opt/sdk/lib.go", AccessRead, true, ""},
+		{"/opt/sdk/lib.go", AccessWrite, false, "workspace.read_allow: /opt/sdk/lib.go is outside project directory and read-only"},
+		{"/var/out/log", AccessWrite, true, ""},
+		{"/var/out/log", AccessRead, true, ""},
+		{"/etc/hosts", AccessRead, false, "workspace boundary"},
+	}
+
+	for _, tt := range tests {
+		d := rule.CheckPath(tt.path, cwd, tt.access)
+		if d.Allowed != tt.allowed || !strings.Contains(d.Reason, tt.reason) {
+			t.Errorf("CheckPath(%q, %v) = %+v, want allowed %v with %q", tt.path, tt.access, d, tt.allowed, tt.reason)
+		}
+	}
+}
+
+func TestEvaluateAccess(t *testing.T) {
+	rule := &ConfineToWorkspace{ReadAllow: []string{"/opt/sdk/"}}
+
+	tests := []struct {
+		cmd     string
+		allowed bool
+	}{
+		{"cp /opt/sdk/lib.go lib.go", true},
+		{"grep -r TODO /opt/sdk", true},
+		{"cp lib.go /opt/sdk/lib.go", false},
+		{"echo x > /opt/sdk/new.go", false},
+		{"sed -i s/a/b/ /opt/sdk/lib.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			for _, cmd := range parser.ParseAll(tt.cmd) {
+				if got := rule.Evaluate(cmd, t.TempDir()); got.Allowed != tt.allowed {
+					t.Errorf("Evaluate(%q) Allowed = %v, want %v (%s)", tt.cmd, got.Allowed, tt.allowed, got.Reason)
+				}
+			}
+		})
+	}
+}
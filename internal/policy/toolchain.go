@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// toolchainVars are the go env variables naming directories the agent
+// reads while navigating code but should never write to.
+var toolchainVars = []string{"GOMODCACHE", "GOROOT", "GOCACHE"}
+
+var (
+	toolchainOnce sync.Once
+	toolchainDirs []string
+)
+
+// ToolchainCaches returns the Go module cache, GOROOT and the build cache
+// as directory patterns. go env is asked once per process; when go is not
+// installed the environment variables are used, if set.
+func ToolchainCaches() []string {
+	toolchainOnce.Do(func() {
+		toolchainDirs = detectToolchainCaches()
+	})
+	return toolchainDirs
+}
+
+func detectToolchainCaches() []string {
+	var values []string
+	if out, err := exec.Command("go", append([]string{"env"}, toolchainVars...)...).Output(); err == nil {
+		values = strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	}
+
+	var dirs []string
+	for i, name := range toolchainVars {
+		value := os.Getenv(name)
+		if i < len(values) && values[i] != "" {
+			value = values[i]
+		}
+		// GOCACHE=off disables the cache; relative values are invalid for go.
+		if !filepath.IsAbs(value) {
+			continue
+		}
+		dirs = append(dirs, filepath.Clean(value)+"/")
+	}
+	return dirs
+}
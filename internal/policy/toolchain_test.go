@@ -0,0 +1,40 @@
+package policy
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianpk/watchman/internal/config"
+)
+
+func TestToolchainCaches(t *testing.T) {
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		t.Skip("go not installed")
+	}
+	goroot := strings.TrimSpace(string(out))
+
+	rule := NewConfineToWorkspace(&config.WorkspaceConfig{ToolchainCaches: true})
+	found := false
+	for _, dir := range rule.ReadAllow {
+		if dir == goroot+"/" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("ReadAllow = %q, want it to contain %s/", rule.ReadAllow, goroot)
+	}
+
+	src := filepath.Join(goroot, "src", "fmt", "print.go")
+	if d := rule.CheckPath(src, t.TempDir(), AccessRead); !d.Allowed {
+		t.Errorf("reading GOROOT denied: %s", d.Reason)
+	}
+	if d := rule.CheckPath(src, t.TempDir(), AccessWrite); d.Allowed {
+		t.Error("writing GOROOT should be denied")
+	}
+	if NewConfineToWorkspace(&config.WorkspaceConfig{}).ReadAllow != nil {
+		t.Error("toolchain caches should only be added when enabled")
+	}
+}
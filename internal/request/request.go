@@ -0,0 +1,32 @@
+// Package request describes the tool call a hook invocation is about, as
+// Claude Code reports it in the hook payload.
+package request
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Context is where and in which session a tool call happens. Config
+// discovery, state, rules and external hooks take it from here rather than
+// from the process, which Claude Code may start in any directory.
+type Context struct {
+	CWD            string // working directory of the agent
+	SessionID      string
+	TranscriptPath string // the session transcript, JSON lines
+	Event          string // hook_event_name, such as PreToolUse
+	PermissionMode string // default, plan, acceptEdits or bypassPermissions
+}
+
+// Dir returns the working directory of the agent. Payloads without one,
+// such as those of older Claude Code versions, fall back to the process
+// directory.
+func (c Context) Dir() string {
+	if c.CWD != "" {
+		return filepath.Clean(c.CWD)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return "."
+}
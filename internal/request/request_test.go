@@ -0,0 +1,17 @@
+package request
+
+import (
+	"os"
+	"testing"
+)
+
+func TestContextDir(t *testing.T) {
+	if got := (Context{CWD: "/srv/app/"}).Dir(); got != "/srv/app" {
+		t.Errorf("Dir() = %q, want /srv/app", got)
+	}
+
+	wd, _ := os.Getwd()
+	if got := (Context{}).Dir(); got != wd {
+		t.Errorf("Dir() without cwd = %q, want %q", got, wd)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+const (
+	dialTimeout = 100 * time.Millisecond
+
+	// responseTimeout covers external hooks, which may run for several seconds.
+	responseTimeout = 60 * time.Second
+)
+
+// Query sends a payload to the daemon listening on socketPath.
+// An error means the daemon is not available and the caller should evaluate in-process.
+func Query(socketPath string, payload []byte) (hook.Input, hook.Result, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return hook.Input{}, hook.Result{}, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(responseTimeout))
+	if _, err := conn.Write(payload); err != nil {
+		return hook.Input{}, hook.Result{}, err
+	}
+	if err := conn.(*net.UnixConn).CloseWrite(); err != nil {
+		return hook.Input{}, hook.Result{}, err
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return hook.Input{}, hook.Result{}, err
+	}
+	return resp.Input, resp.Result, nil
+}
@@ -0,0 +1,145 @@
+// Package server keeps a compiled policy in memory and answers hook
+// evaluations over a Unix socket, so that tool calls do not pay for
+// config parsing and hook discovery on every invocation.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/audit"
+	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+// Policy is a loaded configuration together with its evaluator.
+// A policy whose config failed to load denies every call.
+type Policy struct {
+	dir         string
+	cfg         *config.Config
+	evaluator   *hook.Evaluator
+	err         error
+	fingerprint string
+}
+
+// LoadPolicy loads the configuration for a working directory, the current
+// one when dir is empty.
+func LoadPolicy(dir string) *Policy {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	p := &Policy{dir: dir, fingerprint: fingerprint(dir)}
+
+	cfg, err := config.LoadDir(dir)
+	if err != nil {
+		p.cfg = config.Default()
+		p.err = err
+		return p
+	}
+
+	p.cfg = cfg
+	p.evaluator = hook.NewEvaluator(cfg)
+	return p
+}
+
+// Stale reports whether a config file was created, changed or removed since the policy was loaded.
+func (p *Policy) Stale() bool {
+	return fingerprint(p.dir) != p.fingerprint
+}
+
+// PayloadDir returns the working directory a hook payload names, or empty
+// when it names none. The policy depends on it, since config files are
+// discovered from there.
+func PayloadDir(payload []byte) string {
+	var input struct {
+		CWD string `json:"cwd"`
+	}
+	if err := json.Unmarshal(payload, &input); err != nil || !filepath.IsAbs(input.CWD) {
+		return ""
+	}
+	return filepath.Clean(input.CWD)
+}
+
+// Decide parses a hook payload, evaluates it and writes the decision to the audit log.
+func (p *Policy) Decide(payload []byte) (hook.Input, hook.Result) {
+	start := time.Now()
+
+	if p.err != nil {
+		reason := "watchman config error: " + p.err.Error() + " (run 'watchman validate' for details)"
+		result := Failure("config", reason)
+		p.log(hook.Input{}, result, start)
+		return hook.Input{}, result
+	}
+
+	input, err := hook.ParseInput(payload)
+	if err != nil {
+		result := Failure("input", "watchman input error: "+err.Error())
+		p.log(hook.Input{}, result, start)
+		return hook.Input{}, result
+	}
+
+	result := p.evaluator.Evaluate(input)
+	p.log(input, result, start)
+	return input, result
+}
+
+// Failure builds a deny result for errors that happen before rules run.
+func Failure(rule, reason string) hook.Result {
+	return hook.Result{
+		Allowed: false,
+		Reason:  reason,
+		Trace:   []hook.Step{{Rule: rule, Verdict: hook.VerdictDeny, Reason: reason}},
+	}
+}
+
+// log appends the decision to the audit log.
+// Errors are ignored: logging must never change the outcome of a tool call.
+func (p *Policy) log(input hook.Input, result hook.Result, start time.Time) {
+	decision := "allow"
+	switch {
+	case result.Ask:
+		decision = "ask"
+	case !result.Allowed:
+		decision = "deny"
+	case len(result.Audited()) > 0:
+		decision = "audit"
+	case result.Warning != "":
+		decision = "advise"
+	}
+
+	_ = audit.NewLogger(&p.cfg.Audit).Write(audit.Record{
+		Time:       start,
+		SessionID:  input.SessionID,
+		Event:      input.HookType,
+		Tool:       input.ToolName,
+		CWD:        input.CWD,
+		Input:      input.ToolInput,
+		Paths:      hook.ExtractPaths(input.ToolName, input.ToolInput),
+		Decision:   decision,
+		Rule:       result.DecidingRule(),
+		Reason:     result.Reason,
+		Warning:    result.Warning,
+		Audited:    result.Audited(),
+		LatencyMS:  float64(time.Since(start).Microseconds()) / 1000,
+		ConfigHash: p.cfg.Hash(),
+	})
+}
+
+// fingerprint summarizes the existence, size and modification time of every
+// config file that applies to dir.
+func fingerprint(dir string) string {
+	var parts []string
+	for _, path := range config.WatchPaths(dir) {
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":-")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/hook"
+)
+
+// maxPayload bounds a request, so a misbehaving client cannot exhaust memory.
+const maxPayload = 16 << 20
+
+// Response is what the daemon returns for each payload.
+type Response struct {
+	Input  hook.Input  `json:"input"`
+	Result hook.Result `json:"result"`
+}
+
+// Server answers evaluations for the directory it was started in, loading
+// the policy of each working directory a payload names.
+// Requests are evaluated one at a time: rules and reminder state are not safe
+// for concurrent use, and a single agent rarely issues calls in parallel.
+type Server struct {
+	socketPath string
+	mu         sync.Mutex
+	dir        string
+	policies   map[string]*Policy // working directory -> policy
+	logf       func(format string, args ...interface{})
+}
+
+// New creates a server listening on socketPath.
+func New(socketPath string, logf func(format string, args ...interface{})) *Server {
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	return &Server{socketPath: socketPath, logf: logf}
+}
+
+// SocketPath returns the socket for the daemon serving dir:
+// $XDG_RUNTIME_DIR/watchman/<hash>.sock, falling back to the state directory.
+// Each project has its own daemon because the policy depends on the directory.
+func SocketPath(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	name := hex.EncodeToString(sum[:8]) + ".sock"
+
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		return filepath.Join(runtime, "watchman", name)
+	}
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "watchman", "sockets", name)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "watchman", "sockets", name)
+	}
+	return filepath.Join(os.TempDir(), "watchman-"+name)
+}
+
+// Serve listens until ctx is cancelled, then removes the socket.
+func (s *Server) Serve(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0700); err != nil {
+		return fmt.Errorf("cannot create socket directory: %w", err)
+	}
+
+	// A socket nobody answers on is left over from a daemon that did not shut down cleanly.
+	if conn, err := net.DialTimeout("unix", s.socketPath, dialTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("daemon already running on %s", s.socketPath)
+	}
+	os.Remove(s.socketPath)
+
+	ln, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", s.socketPath, err)
+	}
+	defer os.Remove(s.socketPath)
+
+	s.dir, _ = os.Getwd()
+	s.policies = make(map[string]*Policy)
+	if p := s.policyFor(s.dir); p.err != nil {
+		s.logf("config error, denying all calls until fixed: %v", p.err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			s.logf("accept: %v", err)
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+// policyFor returns the policy of a working directory, loading it the first
+// time and again whenever one of its config files changes.
+// Callers hold s.mu.
+func (s *Server) policyFor(dir string) *Policy {
+	p, ok := s.policies[dir]
+	switch {
+	case !ok:
+		p = LoadPolicy(dir)
+	case p.Stale():
+		p = LoadPolicy(dir)
+		if p.err != nil {
+			s.logf("config reload failed for %s: %v", dir, p.err)
+		} else {
+			s.logf("config reloaded for %s", dir)
+		}
+	default:
+		return p
+	}
+	s.policies[dir] = p
+	return p
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(conn, maxPayload))
+	if err != nil {
+		s.logf("read request: %v", err)
+		return
+	}
+
+	dir := PayloadDir(payload)
+	if dir == "" {
+		dir = s.dir
+	}
+
+	s.mu.Lock()
+	input, result := s.policyFor(dir).Decide(payload)
+	s.mu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := json.NewEncoder(conn).Encode(Response{Input: input, Result: result}); err != nil {
+		s.logf("write response: %v", err)
+	}
+}
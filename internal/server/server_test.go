@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer runs a daemon in a fresh project directory and returns its socket.
+func startServer(t *testing.T) (dir, socket string) {
+	t.Helper()
+
+	origHome := os.Getenv("HOME")
+	origState, hadState := os.LookupEnv("XDG_STATE_HOME")
+	origWd, _ := os.Getwd()
+	t.Cleanup(func() {
+		os.Setenv("HOME", origHome)
+		if hadState {
+			os.Setenv("XDG_STATE_HOME", origState)
+		} else {
+			os.Unsetenv("XDG_STATE_HOME")
+		}
+		os.Chdir(origWd)
+	})
+
+	os.Setenv("HOME", t.TempDir())
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir = t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	socket = filepath.Join(t.TempDir(), "w.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- New(socket, t.Logf).Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() = %v", err)
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(socket); err == nil {
+			return dir, socket
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("daemon did not start")
+	return "", ""
+}
+
+func bashPayload(command string) []byte {
+	return []byte(`{"hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"` + command + `"}}`)
+}
+
+func TestServerEvaluates(t *testing.T) {
+	_, socket := startServer(t)
+
+	input, result, err := Query(socket, bashPayload("ls"))
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if !result.Allowed || input.ToolName != "Bash" {
+		t.Errorf("expected ls to be allowed, got %+v", result)
+	}
+
+	_, result, err = Query(socket, bashPayload("cat /etc/passwd"))
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if result.Allowed || result.DecidingRule() != "workspace" {
+		t.Errorf("expected workspace deny, got %+v", result)
+	}
+}
+
+func TestServerReloadsConfig(t *testing.T) {
+	dir, socket := startServer(t)
+
+	if _, result, _ := Query(socket, bashPayload("make build")); !result.Allowed {
+		t.Fatalf("expected allow before config change: %s", result.Reason)
+	}
+
+	config := "commands:\n  block: [make]\n"
+	if err := os.WriteFile(filepath.Join(dir, ".watchman.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, result, err := Query(socket, bashPayload("make build"))
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if result.Allowed || !strings.Contains(result.Reason, "make") {
+		t.Errorf("expected reloaded config to block make, got %+v", result)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".watchman.yml"), []byte("scopes: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, result, _ = Query(socket, bashPayload("ls"))
+	if result.Allowed || !strings.Contains(result.Reason, "config error") {
+		t.Errorf("expected invalid config to deny, got %+v", result)
+	}
+}
+
+func TestServerLoadsPayloadDir(t *testing.T) {
+	dir, socket := startServer(t)
+
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	billing := filepath.Join(dir, "services", "billing")
+	if err := os.MkdirAll(billing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(billing, ".watchman.yml"), []byte("commands:\n  block: [psql]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := `{"hook_event_name":"PreToolUse","tool_name":"Bash","cwd":"` + billing + `","tool_input":{"command":"psql"}}`
+	if _, result, _ := Query(socket, []byte(payload)); result.Allowed {
+		t.Errorf("expected the billing config to block psql")
+	}
+	if _, result, _ := Query(socket, bashPayload("psql")); !result.Allowed {
+		t.Errorf("expected psql to be allowed at the repository top: %s", result.Reason)
+	}
+}
+
+func TestServerRefusesSecondInstance(t *testing.T) {
+	_, socket := startServer(t)
+
+	err := New(socket, nil).Serve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("expected already running error, got %v", err)
+	}
+}
+
+func TestQueryWithoutDaemon(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "none.sock")
+	if _, _, err := Query(socket, bashPayload("ls")); err == nil {
+		t.Error("expected error when no daemon is listening")
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	a := SocketPath("/home/me/project-a")
+	b := SocketPath("/home/me/project-b")
+	if a == b {
+		t.Error("different directories must use different sockets")
+	}
+	if !strings.HasPrefix(a, "/run/user/1000/watchman/") || !strings.HasSuffix(a, ".sock") {
+		t.Errorf("unexpected socket path: %s", a)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupKeepsLockedUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s.json")
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(path, old, old)
+
+	// The session is updated while Cleanup waits for its lock.
+	unlock, err := lockFile(filepath.Join(dir, "s.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan int)
+	go func() {
+		removed, _ := Cleanup(dir, time.Hour)
+		done <- removed
+	}()
+	time.Sleep(50 * time.Millisecond)
+	os.Chtimes(path, time.Now(), time.Now())
+	unlock()
+
+	if removed := <-done; removed != 0 {
+		t.Errorf("removed = %d, want 0", removed)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("updated session was removed: %v", err)
+	}
+}
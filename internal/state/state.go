@@ -2,45 +2,107 @@
 package state
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/adrianpk/watchman/internal/config"
+	"github.com/adrianpk/watchman/internal/glob"
+	"github.com/adrianpk/watchman/internal/request"
 )
 
-const stateFileName = ".watchman-state"
+// SessionTTL is how long the state of a session is kept after its last
+// update. Older sessions are removed when a new one starts.
+const SessionTTL = 7 * 24 * time.Hour
 
 // State represents the persistent state for reminders.
 type State struct {
 	TaskCount   int                  `json:"task_count"`
-	LastChecked map[string]time.Time `json:"last_checked"` // Per-reminder last trigger time
-	TaskCounts  map[string]int       `json:"task_counts"`  // Per-reminder task count since last trigger
+	LastChecked map[string]time.Time `json:"last_checked"`          // Per-reminder last trigger time
+	TaskCounts  map[string]int       `json:"task_counts"`           // Per-reminder occurrences of its condition since last trigger
+	Modified    map[string]int       `json:"modified,omitempty"`    // Per-file modifications in the session
+	LastDenied  string               `json:"last_denied,omitempty"` // Reason of the last denied call
+	Pending     []string             `json:"pending,omitempty"`     // Reminders triggered by a denied call, shown on the next allowed one
+}
+
+// Activity describes the hook call reminders are checked on.
+type Activity struct {
+	Event    string   // Hook event of the call
+	Tool     string   // Tool of the call, for tool events
+	Paths    []string // Paths the call touches
+	Modified []string // Paths the call modifies
+	Commit   bool     // The call runs git or jj commit
+	Denied   string   // Reason the call was denied, empty when allowed
+}
+
+// Trigger is a reminder due to be shown.
+type Trigger struct {
+	Reminder config.ReminderConfig
+	File     string // File whose modifications triggered a same_file reminder
 }
 
 // Manager handles state persistence and reminder checks.
+// Each session of an agent has its own state, kept outside the project in
+// $XDG_STATE_HOME/watchman/<repo-hash>/<session-hash>.json.
 type Manager struct {
 	state     *State
 	statePath string
 }
 
-// NewManager creates a new state manager for the current directory.
-func NewManager() *Manager {
-	cwd, err := os.Getwd()
+// NewManager creates a state manager for the session of a request, in the
+// repository around its working directory.
+func NewManager(ctx request.Context) *Manager {
+	return &Manager{
+		statePath: filepath.Join(RepoDir(ctx.Dir()), sessionFile(ctx.SessionID)),
+	}
+}
+
+// Root returns $XDG_STATE_HOME/watchman, falling back to
+// ~/.local/state/watchman.
+func Root() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "watchman")
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
-		cwd = "."
+		return filepath.Join(os.TempDir(), "watchman-state")
 	}
-	return &Manager{
-		statePath: filepath.Join(cwd, stateFileName),
+	return filepath.Join(home, ".local", "state", "watchman")
+}
+
+// RepoDir returns the state directory of the repository around dir, or of
+// dir itself outside a repository.
+func RepoDir(dir string) string {
+	top := config.RepositoryRoot(dir)
+	if top == "" {
+		top = dir
 	}
+	sum := sha256.Sum256([]byte(top))
+	return filepath.Join(Root(), hex.EncodeToString(sum[:8]))
+}
+
+// sessionFile names the state file of a session. Session ids come from
+// the payload, so the name is a digest of the id: distinct ids never share
+// a file, whatever characters they contain.
+func sessionFile(id string) string {
+	if id == "" {
+		id = "default"
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16]) + ".json"
 }
 
 // Load loads the state from disk, or initializes a new state if none exists.
+// Use Update to change the state safely while other sessions run.
 func (m *Manager) Load() error {
 	m.state = &State{
 		LastChecked: make(map[string]time.Time),
 		TaskCounts:  make(map[string]int),
+		Modified:    make(map[string]int),
 	}
 
 	data, err := os.ReadFile(m.statePath)
@@ -51,63 +113,273 @@ func (m *Manager) Load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, m.state)
+	if err := json.Unmarshal(data, m.state); err != nil {
+		return err
+	}
+	if m.state.LastChecked == nil {
+		m.state.LastChecked = make(map[string]time.Time)
+	}
+	if m.state.TaskCounts == nil {
+		m.state.TaskCounts = make(map[string]int)
+	}
+	if m.state.Modified == nil {
+		m.state.Modified = make(map[string]int)
+	}
+	return nil
 }
 
-// Save persists the state to disk.
+// Save persists the state to disk atomically: readers see either the old
+// or the new state, never a partial write.
 func (m *Manager) Save() error {
 	data, err := json.MarshalIndent(m.state, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.statePath, data, 0644)
+	dir := filepath.Dir(m.statePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(m.statePath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.statePath)
+}
+
+// Update loads the state, applies fn and saves it, holding an exclusive
+// lock on the session throughout so concurrent hook invocations do not
+// lose each other's changes. Creating the state of a new session also
+// removes sessions of the repository idle for longer than SessionTTL.
+func (m *Manager) Update(fn func()) error {
+	if err := os.MkdirAll(filepath.Dir(m.statePath), 0700); err != nil {
+		return err
+	}
+	unlock, err := lockFile(m.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, statErr := os.Stat(m.statePath)
+	if err := m.Load(); err != nil {
+		return err
+	}
+	fn()
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	if os.IsNotExist(statErr) {
+		_, _ = Cleanup(filepath.Dir(m.statePath), SessionTTL)
+	}
+	return nil
+}
+
+func (m *Manager) lockPath() string {
+	return strings.TrimSuffix(m.statePath, ".json") + ".lock"
+}
+
+// Cleanup removes the session states in dir, a directory returned by
+// RepoDir, that were not updated for longer than ttl. Each state is removed
+// while holding its session lock, so a session updated meanwhile is kept.
+// It returns how many it removed.
+func Cleanup(dir string, ttl time.Duration) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if info, err := entry.Info(); err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if removeStale(filepath.Join(dir, name), cutoff) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeStale removes a session state and its lock file if the state was
+// still not updated since cutoff once the lock is held.
+func removeStale(path string, cutoff time.Time) bool {
+	lock := strings.TrimSuffix(path, ".json") + ".lock"
+	unlock, err := lockFile(lock)
+	if err != nil {
+		return false
+	}
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil || !info.ModTime().Before(cutoff) || os.Remove(path) != nil {
+		return false
+	}
+	// Sessions waiting on the lock notice it was removed and take a new one.
+	os.Remove(lock)
+	return true
 }
 
-// IncrementTaskCount increments the global task counter and per-reminder counters.
-func (m *Manager) IncrementTaskCount() {
+// record counts an allowed tool call and the files it modifies, and keeps
+// the reason of a denied one.
+func (m *Manager) record(a Activity) {
+	if a.Denied != "" {
+		m.state.LastDenied = a.Denied
+		return
+	}
+	if a.Event != config.EventPreToolUse {
+		return
+	}
 	m.state.TaskCount++
-	for name := range m.state.TaskCounts {
-		m.state.TaskCounts[name]++
+	for _, p := range a.Modified {
+		m.state.Modified[p]++
 	}
 }
 
-// CheckReminders checks all configured reminders and returns any triggered messages.
-func (m *Manager) CheckReminders(reminders []config.ReminderConfig) []string {
-	var triggered []string
+// CheckReminders records the call and returns the reminders it triggers.
+// A denied call shows no reminders: those it triggers are kept and returned
+// by the next allowed call.
+func (m *Manager) CheckReminders(reminders []config.ReminderConfig, a Activity) []Trigger {
+	m.record(a)
+
+	var triggered []Trigger
 	now := time.Now()
 
+	if a.Denied == "" {
+		for _, name := range m.state.Pending {
+			for _, r := range reminders {
+				if r.Name == name {
+					triggered = append(triggered, Trigger{Reminder: r})
+				}
+			}
+		}
+		m.state.Pending = nil
+	}
+
 	for _, r := range reminders {
 		// Initialize tracking for new reminders
-		if _, ok := m.state.TaskCounts[r.Name]; !ok {
+		if _, ok := m.state.LastChecked[r.Name]; !ok {
 			m.state.TaskCounts[r.Name] = 0
 			m.state.LastChecked[r.Name] = now
 		}
 
-		shouldTrigger := false
+		if !occurs(r, a) {
+			continue
+		}
+		m.state.TaskCounts[r.Name]++
+
+		file, due := m.due(r, a, now)
+		if !due {
+			continue
+		}
+		// Reset counters for this reminder
+		m.state.TaskCounts[r.Name] = 0
+		m.state.LastChecked[r.Name] = now
 
-		// Check task count trigger
-		if r.EveryTasks > 0 && m.state.TaskCounts[r.Name] >= r.EveryTasks {
-			shouldTrigger = true
+		if a.Denied != "" {
+			m.state.Pending = append(m.state.Pending, r.Name)
+			continue
 		}
+		triggered = append(triggered, Trigger{Reminder: r, File: file})
+	}
+
+	return triggered
+}
+
+// occurs reports whether the call is an occurrence of the reminder's condition.
+// Reminders on tool calls count allowed calls of their tools touching their
+// paths, and never trigger when they set no condition at all.
+func occurs(r config.ReminderConfig, a Activity) bool {
+	switch r.On {
+	case config.ReminderOnSessionStart:
+		return a.Event == config.EventSessionStart
+	case config.ReminderOnCommit:
+		return a.Event == config.EventPostToolUse && a.Commit
+	case config.ReminderOnDeny:
+		return a.Denied != ""
+	}
 
-		// Check time trigger
-		if r.EveryMinutes > 0 {
-			lastCheck := m.state.LastChecked[r.Name]
-			elapsed := now.Sub(lastCheck)
-			if elapsed >= time.Duration(r.EveryMinutes)*time.Minute {
-				shouldTrigger = true
+	if a.Event != config.EventPreToolUse || a.Denied != "" {
+		return false
+	}
+	if !counts(r) && len(r.Tools) == 0 && len(r.Paths) == 0 {
+		return false
+	}
+	if len(r.Tools) > 0 && !hasTool(r.Tools, a.Tool) {
+		return false
+	}
+	if len(r.Paths) > 0 && !anyMatch(a.Paths, r.Paths) {
+		return false
+	}
+	return true
+}
+
+// due reports whether a reminder triggers on an occurrence of its condition,
+// and the file that made it trigger for same_file. Without counters it
+// triggers on every occurrence.
+func (m *Manager) due(r config.ReminderConfig, a Activity, now time.Time) (string, bool) {
+	if r.EveryTasks > 0 && m.state.TaskCounts[r.Name] >= r.EveryTasks {
+		return "", true
+	}
+	if r.EveryMinutes > 0 && now.Sub(m.state.LastChecked[r.Name]) >= time.Duration(r.EveryMinutes)*time.Minute {
+		return "", true
+	}
+	if r.SameFile > 0 {
+		for _, p := range a.Modified {
+			if len(r.Paths) > 0 && !glob.MatchAny(p, r.Paths) {
+				continue
+			}
+			if n := m.state.Modified[p]; n > 0 && n%r.SameFile == 0 {
+				return p, true
 			}
 		}
+	}
+	return "", !counts(r)
+}
 
-		if shouldTrigger {
-			triggered = append(triggered, r.Message)
-			// Reset counters for this reminder
-			m.state.TaskCounts[r.Name] = 0
-			m.state.LastChecked[r.Name] = now
+// counts reports whether a reminder sets any counter.
+func counts(r config.ReminderConfig) bool {
+	return r.EveryTasks > 0 || r.EveryMinutes > 0 || r.SameFile > 0
+}
+
+func hasTool(tools []string, tool string) bool {
+	for _, t := range tools {
+		if strings.EqualFold(t, tool) {
+			return true
 		}
 	}
+	return false
+}
 
-	return triggered
+func anyMatch(paths, patterns []string) bool {
+	for _, p := range paths {
+		if glob.MatchAny(p, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the loaded state.
+func (m *Manager) State() *State {
+	return m.state
 }
 
 // StatePath returns the path to the state file.
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adrianpk/watchman/internal/request"
+)
+
+func TestUpdateConcurrent(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	ctx := request.Context{CWD: t.TempDir(), SessionID: "s1"}
+
+	const calls = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := NewManager(ctx)
+			errs <- m.Update(func() { m.State().TaskCount++ })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	m := NewManager(ctx)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if m.State().TaskCount != calls {
+		t.Errorf("TaskCount = %d, want %d", m.State().TaskCount, calls)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"stale.json", "stale.lock", "fresh.json", "fresh.lock", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"stale.json", "notes.txt"} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := Cleanup(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("Cleanup() failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+	for name, kept := range map[string]bool{
+		"stale.json": false, "stale.lock": false, "fresh.json": true, "fresh.lock": true, "notes.txt": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != kept {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+
+	if removed, err := Cleanup(filepath.Join(dir, "missing"), time.Hour); err != nil || removed != 0 {
+		t.Errorf("Cleanup() of a missing directory = %d, %v", removed, err)
+	}
+}
+
+func TestSessionFile(t *testing.T) {
+	ids := []string{"", "a.b", "a_b", "a/b", "../b", "A-1"}
+	seen := make(map[string]string)
+	for _, id := range ids {
+		name := sessionFile(id)
+		if filepath.Base(name) != name || filepath.Ext(name) != ".json" {
+			t.Errorf("sessionFile(%q) = %q, want a plain .json file name", id, name)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("sessionFile(%q) = sessionFile(%q) = %q", id, other, name)
+		}
+		seen[name] = id
+	}
+}
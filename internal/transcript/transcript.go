@@ -0,0 +1,107 @@
+// Package transcript reads tool calls from recorded Claude Code session transcripts.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxLineSize bounds a single transcript entry; tool results can embed whole files.
+const maxLineSize = 64 * 1024 * 1024
+
+// ToolCall is a tool invocation requested by the agent.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Input     map[string]interface{}
+	CWD       string
+	SessionID string
+	Timestamp string
+	Line      int
+}
+
+// entry is a single line of a transcript. Only the fields needed to
+// recover tool calls are decoded.
+type entry struct {
+	Type      string  `json:"type"`
+	CWD       string  `json:"cwd"`
+	SessionID string  `json:"sessionId"`
+	Timestamp string  `json:"timestamp"`
+	Message   message `json:"message"`
+}
+
+type message struct {
+	Content json.RawMessage `json:"content"`
+}
+
+type contentBlock struct {
+	Type  string                 `json:"type"`
+	ID    string                 `json:"id"`
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input"`
+}
+
+// ReadFile extracts every tool call from a transcript file.
+func ReadFile(path string) ([]ToolCall, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read extracts every tool call from a JSONL transcript, in order.
+func Read(r io.Reader) ([]ToolCall, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var calls []ToolCall
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Type != "assistant" {
+			continue
+		}
+
+		// Content is either a plain string or a list of blocks
+		var blocks []contentBlock
+		if err := json.Unmarshal(e.Message.Content, &blocks); err != nil {
+			continue
+		}
+
+		for _, b := range blocks {
+			if b.Type != "tool_use" {
+				continue
+			}
+			calls = append(calls, ToolCall{
+				ID:        b.ID,
+				Name:      b.Name,
+				Input:     b.Input,
+				CWD:       e.CWD,
+				SessionID: e.SessionID,
+				Timestamp: e.Timestamp,
+				Line:      line,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return calls, nil
+}
@@ -0,0 +1,57 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `{"type":"user","message":{"role":"user","content":"read the config"},"cwd":"/p"}
+{"type":"assistant","cwd":"/p","sessionId":"s1","timestamp":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":[{"type":"text","text":"ok"},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/p/a.go"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"..."}]}}
+
+{"type":"assistant","cwd":"/p/sub","sessionId":"s1","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"ls"}},{"type":"tool_use","id":"t3","name":"Grep","input":{"pattern":"x"}}]}}
+{"type":"summary","summary":"done"}
+`
+
+func TestRead(t *testing.T) {
+	calls, err := Read(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	if len(calls) != 3 {
+		t.Fatalf("got %d calls, want 3", len(calls))
+	}
+
+	first := calls[0]
+	if first.ID != "t1" || first.Name != "Read" || first.CWD != "/p" || first.SessionID != "s1" || first.Line != 2 {
+		t.Errorf("unexpected first call: %+v", first)
+	}
+	if first.Input["file_path"] != "/p/a.go" {
+		t.Errorf("unexpected input: %v", first.Input)
+	}
+
+	if calls[1].Name != "Bash" || calls[1].CWD != "/p/sub" || calls[1].Line != 5 {
+		t.Errorf("unexpected second call: %+v", calls[1])
+	}
+	if calls[2].Name != "Grep" {
+		t.Errorf("unexpected third call: %+v", calls[2])
+	}
+}
+
+func TestReadInvalidLine(t *testing.T) {
+	_, err := Read(strings.NewReader("{\"type\":\"assistant\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	calls, err := Read(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("got %d calls, want 0", len(calls))
+	}
+}